@@ -0,0 +1,32 @@
+package api
+
+import (
+	// golang package
+	_ "embed"
+	"net/http"
+
+	// external package
+	"github.com/gin-gonic/gin"
+)
+
+// Spec is the OpenAPI 3 document of every route in routes.SetupRoutes, keep it in sync when a route changes.
+//
+//go:embed openapi.json
+var Spec []byte
+
+//go:embed swagger.html
+var swaggerUI []byte
+
+// OpenAPI openapi by given c pointer of gin.Context.
+//
+// GET /openapi.json
+func OpenAPI(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", Spec)
+}
+
+// SwaggerUI swagger ui by given c pointer of gin.Context.
+//
+// GET /docs, the page loads Spec from /openapi.json.
+func SwaggerUI(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", swaggerUI)
+}
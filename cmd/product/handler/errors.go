@@ -0,0 +1,36 @@
+package handler
+
+import (
+	// golang package
+	"encoding/json"
+	"errors"
+	"fmt"
+	"productfc/cmd/product/service"
+)
+
+// invalidField invalid field by given field, and message.
+//
+// It returns error of kind service.ErrValidation, detailing field.
+func invalidField(field string, message string) error {
+	return service.NewValidationError("invalid "+field, service.FieldError{
+		Field:   field,
+		Message: message,
+	})
+}
+
+// invalidBody invalid body by given err of binding the request body.
+//
+// A value of the wrong type is reported against its field.
+//
+// It returns error of kind service.ErrValidation.
+func invalidBody(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return service.NewValidationError("invalid request body", service.FieldError{
+			Field:   typeErr.Field,
+			Message: fmt.Sprintf("must be of type %s", typeErr.Type),
+		})
+	}
+
+	return service.NewValidationError("invalid request body: " + err.Error())
+}
@@ -0,0 +1,550 @@
+package handler
+
+import (
+	// golang package
+	"context"
+	"errors"
+	"net/http"
+	"productfc/cmd/product/service"
+	"productfc/cmd/product/usecase"
+	"productfc/config"
+	"productfc/infrastructure/log"
+	"productfc/models"
+	"strconv"
+
+	// external package
+	"github.com/gin-gonic/gin"
+	"github.com/graph-gophers/dataloader/v7"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/sirupsen/logrus"
+)
+
+// GraphQLHandler serves /graphql, a read API for storefront clients stitching products, categories and stock.
+type GraphQLHandler struct {
+	ProductUsecase usecase.ProductUsecase
+	SearchConfig   config.SearchConfig
+	GraphQLConfig  config.GraphQLConfig
+
+	schema graphql.Schema
+}
+
+// graphQLRequest is the body of POST /graphql.
+type graphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// categoryLoaderKey is the context key of the request's category loader.
+type categoryLoaderKey struct{}
+
+// NewGraphQLHandler new graphql handler by given ProductUsecase, searchConfig, and graphQLConfig.
+//
+// It returns pointer of GraphQLHandler, and nil error when successful.
+// Otherwise, nil pointer of GraphQLHandler, and error will be returned.
+func NewGraphQLHandler(productUsecase usecase.ProductUsecase, searchConfig config.SearchConfig, graphQLConfig config.GraphQLConfig) (*GraphQLHandler, error) {
+	h := &GraphQLHandler{
+		ProductUsecase: productUsecase,
+		SearchConfig:   searchConfig,
+		GraphQLConfig:  graphQLConfig,
+	}
+
+	schema, err := h.newSchema()
+	if err != nil {
+		return nil, err
+	}
+
+	h.schema = schema
+
+	return h, nil
+}
+
+// ServeGraphQL serve graphql by given c pointer of gin.Context.
+//
+// Queries that don't parse, don't validate or go over the depth or complexity limits are rejected with 400
+// before anything is resolved. Otherwise, the response is 200 with data, and errors of the fields that failed.
+func (h *GraphQLHandler) ServeGraphQL(c *gin.Context) {
+	var req graphQLRequest
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.Error(invalidBody(err))
+		return
+	}
+
+	if req.Query == "" {
+		c.Error(invalidField("query", "is required"))
+		return
+	}
+
+	document, err := parser.Parse(parser.ParseParams{Source: req.Query})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"errors": gqlerrors.FormatErrors(err),
+		})
+
+		return
+	}
+
+	validation := graphql.ValidateDocument(&h.schema, document, nil)
+	if !validation.IsValid {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"errors": validation.Errors,
+		})
+
+		return
+	}
+
+	err = h.checkQueryLimits(document, req.OperationName, req.Variables)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"errors": gqlerrors.FormatErrors(err),
+		})
+
+		return
+	}
+
+	ctx := context.WithValue(c.Request.Context(), categoryLoaderKey{}, h.newCategoryLoader())
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        h.schema,
+		AST:           document,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       ctx,
+	})
+
+	c.JSON(http.StatusOK, result)
+}
+
+// newCategoryLoader new category loader.
+//
+// It batches the category lookups of a request into one GetProductCategoriesByIDs call, so listing products
+// with their category doesn't query once per product.
+//
+// It returns pointer of dataloader.Loader of category ids.
+func (h *GraphQLHandler) newCategoryLoader() *dataloader.Loader[int, *models.ProductCategory] {
+	return dataloader.NewBatchedLoader(func(ctx context.Context, productCategoryIDs []int) []*dataloader.Result[*models.ProductCategory] {
+		results := make([]*dataloader.Result[*models.ProductCategory], len(productCategoryIDs))
+
+		productCategories, err := h.ProductUsecase.GetProductCategoriesByIDs(ctx, productCategoryIDs)
+		if err != nil {
+			log.Logger.WithFields(logrus.Fields{
+				"productCategoryIDs": productCategoryIDs,
+			}).Errorf("h.ProductUsecase.GetProductCategoriesByIDs() got error %v", err)
+		}
+
+		for i, productCategoryID := range productCategoryIDs {
+			results[i] = &dataloader.Result[*models.ProductCategory]{Error: err}
+			if productCategory, ok := productCategories[productCategoryID]; ok {
+				results[i].Data = &productCategory
+			}
+		}
+
+		return results
+	})
+}
+
+// newSchema new schema.
+//
+// It returns graphql.Schema, and nil error when successful.
+// Otherwise, empty graphql.Schema, and error will be returned.
+func (h *GraphQLHandler) newSchema() (graphql.Schema, error) {
+	pageInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:        "PageInput",
+		Description: "Page by number, or by cursor when cursor is set.",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"page":     &graphql.InputObjectFieldConfig{Type: graphql.Int},
+			"pageSize": &graphql.InputObjectFieldConfig{Type: graphql.Int},
+			"cursor":   &graphql.InputObjectFieldConfig{Type: graphql.String},
+		},
+	})
+
+	productFilter := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "ProductFilter",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"query":              &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"name":               &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"categoryIds":        &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.ID))},
+			"excludeCategoryIds": &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.ID))},
+			"ids":                &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.ID))},
+			"minPrice":           &graphql.InputObjectFieldConfig{Type: graphql.Float},
+			"maxPrice":           &graphql.InputObjectFieldConfig{Type: graphql.Float},
+			"inStock":            &graphql.InputObjectFieldConfig{Type: graphql.Boolean},
+		},
+	})
+
+	productsArgs := graphql.FieldConfigArgument{
+		"filter": &graphql.ArgumentConfig{Type: productFilter},
+		"page":   &graphql.ArgumentConfig{Type: pageInput},
+	}
+
+	// Product and Category refer to each other, so Category's fields are added once both exist
+	category := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Category",
+		Fields: graphql.Fields{
+			"id":        &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"name":      &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"createdAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime), Resolve: resolveCategoryCreatedAt},
+			"updatedAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime), Resolve: resolveCategoryUpdatedAt},
+		},
+	})
+
+	product := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Product",
+		Fields: graphql.Fields{
+			"id":          &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"name":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"description": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"price":       &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+			"stock":       &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"inStock":     &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean), Resolve: resolveProductInStock},
+			"popularity":  &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"categoryId":  &graphql.Field{Type: graphql.NewNonNull(graphql.ID), Resolve: resolveProductCategoryID},
+			"category":    &graphql.Field{Type: category, Resolve: resolveProductCategory},
+			"createdAt":   &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime), Resolve: resolveProductCreatedAt},
+			"updatedAt":   &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime), Resolve: resolveProductUpdatedAt},
+		},
+	})
+
+	productPage := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ProductPage",
+		Fields: graphql.Fields{
+			"items":      &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(product))), Resolve: resolveProductPageItems},
+			"totalCount": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"nextCursor": &graphql.Field{Type: graphql.String, Resolve: resolveProductPageNextCursor},
+			"prevCursor": &graphql.Field{Type: graphql.String, Resolve: resolveProductPagePrevCursor},
+		},
+	})
+
+	category.AddFieldConfig("products", &graphql.Field{
+		Type:        graphql.NewNonNull(productPage),
+		Description: "Products of the category, filter.categoryIds is ignored.",
+		Args:        productsArgs,
+		Resolve:     h.resolveCategoryProducts,
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"product": &graphql.Field{
+				Type:    product,
+				Args:    graphql.FieldConfigArgument{"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)}},
+				Resolve: h.resolveProduct,
+			},
+			"products": &graphql.Field{
+				Type:    graphql.NewNonNull(productPage),
+				Args:    productsArgs,
+				Resolve: h.resolveProducts,
+			},
+			"category": &graphql.Field{
+				Type:    category,
+				Args:    graphql.FieldConfigArgument{"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)}},
+				Resolve: h.resolveCategory,
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{
+		Query: query,
+	})
+}
+
+// resolveProduct resolve product by given p graphql.ResolveParams.
+//
+// It returns pointer of models.Product, nil when not found, and nil error when successful.
+// Otherwise, nil, and error will be returned.
+func (h *GraphQLHandler) resolveProduct(p graphql.ResolveParams) (interface{}, error) {
+	productID, err := parseGraphQLID("id", p.Args["id"])
+	if err != nil {
+		return nil, graphQLError(err)
+	}
+
+	product, err := h.ProductUsecase.GetProductByID(p.Context, productID)
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"productID": productID,
+		}).Errorf("h.ProductUsecase.GetProductByID() got error %v", err)
+
+		return nil, graphQLError(err)
+	}
+
+	if product.ID == 0 {
+		return nil, nil
+	}
+
+	return product, nil
+}
+
+// resolveProducts resolve products by given p graphql.ResolveParams.
+//
+// It returns pointer of models.SearchProductResult, and nil error when successful.
+// Otherwise, nil, and error will be returned.
+func (h *GraphQLHandler) resolveProducts(p graphql.ResolveParams) (interface{}, error) {
+	param, err := h.graphQLSearchParameter(p.Args)
+	if err != nil {
+		return nil, graphQLError(err)
+	}
+
+	return h.searchProducts(p.Context, param)
+}
+
+// resolveCategory resolve category by given p graphql.ResolveParams.
+//
+// It returns pointer of models.ProductCategory, nil when not found, and nil error when successful.
+// Otherwise, nil, and error will be returned.
+func (h *GraphQLHandler) resolveCategory(p graphql.ResolveParams) (interface{}, error) {
+	productCategoryID, err := parseGraphQLID("id", p.Args["id"])
+	if err != nil {
+		return nil, graphQLError(err)
+	}
+
+	productCategory, err := h.ProductUsecase.GetProductCategoryByID(p.Context, int(productCategoryID))
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"productCategoryID": productCategoryID,
+		}).Errorf("h.ProductUsecase.GetProductCategoryByID() got error %v", err)
+
+		return nil, graphQLError(err)
+	}
+
+	if productCategory.ID == 0 {
+		return nil, nil
+	}
+
+	return productCategory, nil
+}
+
+// resolveCategoryProducts resolve category products by given p graphql.ResolveParams.
+//
+// It returns pointer of models.SearchProductResult, and nil error when successful.
+// Otherwise, nil, and error will be returned.
+func (h *GraphQLHandler) resolveCategoryProducts(p graphql.ResolveParams) (interface{}, error) {
+	param, err := h.graphQLSearchParameter(p.Args)
+	if err != nil {
+		return nil, graphQLError(err)
+	}
+
+	param.CategoryIDs = []int64{int64(p.Source.(*models.ProductCategory).ID)}
+
+	return h.searchProducts(p.Context, param)
+}
+
+// searchProducts search products by given param models.SearchProductParameter.
+//
+// It returns pointer of models.SearchProductResult, and nil error when successful.
+// Otherwise, nil, and error will be returned.
+func (h *GraphQLHandler) searchProducts(ctx context.Context, param models.SearchProductParameter) (interface{}, error) {
+	result, err := h.ProductUsecase.SearchProduct(ctx, param)
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"param": param,
+		}).Errorf("h.ProductUsecase.SearchProduct() got error %v", err)
+
+		return nil, graphQLError(err)
+	}
+
+	return result, nil
+}
+
+// graphQLSearchParameter graphql search parameter by given args of the filter and page arguments.
+//
+// The page is validated like GET /v1/product/search.
+//
+// It returns models.SearchProductParameter, and nil error when successful.
+// Otherwise, empty models.SearchProductParameter, and validation error will be returned.
+func (h *GraphQLHandler) graphQLSearchParameter(args map[string]interface{}) (models.SearchProductParameter, error) {
+	filter, _ := args["filter"].(map[string]interface{})
+	page, _ := args["page"].(map[string]interface{})
+
+	var rawPage, rawPageSize string
+	if value, ok := page["page"].(int); ok {
+		rawPage = strconv.Itoa(value)
+	}
+
+	if value, ok := page["pageSize"].(int); ok {
+		rawPageSize = strconv.Itoa(value)
+	}
+
+	pageNumber, pageSize, err := parseSearchPage(rawPage, rawPageSize, h.SearchConfig)
+	if err != nil {
+		return models.SearchProductParameter{}, err
+	}
+
+	param := models.SearchProductParameter{
+		Page:     pageNumber,
+		PageSize: pageSize,
+	}
+	param.Cursor, _ = page["cursor"].(string)
+	param.Query, _ = filter["query"].(string)
+	param.Name, _ = filter["name"].(string)
+	param.MinPrice, _ = filter["minPrice"].(float64)
+	param.MaxPrice, _ = filter["maxPrice"].(float64)
+	param.InStock, _ = filter["inStock"].(bool)
+
+	for field, ids := range map[string]*[]int64{
+		"categoryIds":        &param.CategoryIDs,
+		"excludeCategoryIds": &param.ExcludeCategoryIDs,
+		"ids":                &param.IDs,
+	} {
+		values, _ := filter[field].([]interface{})
+		for _, value := range values {
+			id, err := parseGraphQLID("filter."+field, value)
+			if err != nil {
+				return models.SearchProductParameter{}, err
+			}
+
+			*ids = append(*ids, id)
+		}
+	}
+
+	if len(param.IDs) > h.SearchConfig.MaxBatchProductIDs {
+		return models.SearchProductParameter{}, invalidField("filter.ids", "must contain at most "+strconv.Itoa(h.SearchConfig.MaxBatchProductIDs)+" product IDs")
+	}
+
+	return param, nil
+}
+
+// resolveProductCategory resolve product category by given p graphql.ResolveParams.
+//
+// The category is loaded through the request's category loader, the lookup runs once the whole level is collected.
+//
+// It returns thunk of pointer of models.ProductCategory.
+func resolveProductCategory(p graphql.ResolveParams) (interface{}, error) {
+	loader := p.Context.Value(categoryLoaderKey{}).(*dataloader.Loader[int, *models.ProductCategory])
+	thunk := loader.Load(p.Context, p.Source.(*models.Product).CategoryID)
+
+	return func() (interface{}, error) {
+		productCategory, err := thunk()
+		if err != nil {
+			return nil, graphQLError(err)
+		}
+
+		if productCategory == nil {
+			return nil, nil
+		}
+
+		return productCategory, nil
+	}, nil
+}
+
+// resolveProductInStock resolve product in stock by given p graphql.ResolveParams.
+func resolveProductInStock(p graphql.ResolveParams) (interface{}, error) {
+	return p.Source.(*models.Product).Stock > 0, nil
+}
+
+// resolveProductCategoryID resolve product category id by given p graphql.ResolveParams.
+func resolveProductCategoryID(p graphql.ResolveParams) (interface{}, error) {
+	return p.Source.(*models.Product).CategoryID, nil
+}
+
+// resolveProductCreatedAt resolve product created at by given p graphql.ResolveParams.
+func resolveProductCreatedAt(p graphql.ResolveParams) (interface{}, error) {
+	return p.Source.(*models.Product).CreatedAt, nil
+}
+
+// resolveProductUpdatedAt resolve product updated at by given p graphql.ResolveParams.
+func resolveProductUpdatedAt(p graphql.ResolveParams) (interface{}, error) {
+	return p.Source.(*models.Product).UpdatedAt, nil
+}
+
+// resolveCategoryCreatedAt resolve category created at by given p graphql.ResolveParams.
+func resolveCategoryCreatedAt(p graphql.ResolveParams) (interface{}, error) {
+	return p.Source.(*models.ProductCategory).CreatedAt, nil
+}
+
+// resolveCategoryUpdatedAt resolve category updated at by given p graphql.ResolveParams.
+func resolveCategoryUpdatedAt(p graphql.ResolveParams) (interface{}, error) {
+	return p.Source.(*models.ProductCategory).UpdatedAt, nil
+}
+
+// resolveProductPageItems resolve product page items by given p graphql.ResolveParams.
+//
+// It returns slice of pointer of models.Product, so every product resolver gets the same source type.
+func resolveProductPageItems(p graphql.ResolveParams) (interface{}, error) {
+	result := p.Source.(*models.SearchProductResult)
+
+	products := make([]*models.Product, len(result.Products))
+	for i := range result.Products {
+		products[i] = &result.Products[i]
+	}
+
+	return products, nil
+}
+
+// resolveProductPageNextCursor resolve product page next cursor by given p graphql.ResolveParams.
+func resolveProductPageNextCursor(p graphql.ResolveParams) (interface{}, error) {
+	return nullableString(p.Source.(*models.SearchProductResult).NextCursor), nil
+}
+
+// resolveProductPagePrevCursor resolve product page prev cursor by given p graphql.ResolveParams.
+func resolveProductPagePrevCursor(p graphql.ResolveParams) (interface{}, error) {
+	return nullableString(p.Source.(*models.SearchProductResult).PrevCursor), nil
+}
+
+// nullableString nullable string by given value.
+//
+// It returns nil when value is empty, so it's null in the response.
+func nullableString(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+
+	return value
+}
+
+// parseGraphQLID parse graphql id by given field, and value of an ID argument.
+//
+// It returns int64 of the id, and nil error when successful.
+// Otherwise, 0, and validation error will be returned.
+func parseGraphQLID(field string, value interface{}) (int64, error) {
+	raw, _ := value.(string)
+
+	id, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, invalidField(field, "must be a positive integer")
+	}
+
+	return id, nil
+}
+
+// graphQLResolveError is an error of a field, with the code and field errors of the domain error as extensions.
+type graphQLResolveError struct {
+	message    string
+	extensions map[string]interface{}
+}
+
+// Error error.
+func (e *graphQLResolveError) Error() string {
+	return e.message
+}
+
+// Extensions extensions.
+func (e *graphQLResolveError) Extensions() map[string]interface{} {
+	return e.extensions
+}
+
+// graphQLError graphql error by given err.
+//
+// Errors that aren't domain errors are internal, their message is not exposed.
+//
+// It returns error with extensions.
+func graphQLError(err error) error {
+	var domainErr *service.Error
+	if !errors.As(err, &domainErr) {
+		return &graphQLResolveError{
+			message:    "an unexpected error occurred",
+			extensions: map[string]interface{}{"code": "internal_error"},
+		}
+	}
+
+	extensions := map[string]interface{}{"code": domainErr.Code}
+	if len(domainErr.Fields) > 0 {
+		extensions["errors"] = domainErr.Fields
+	}
+
+	return &graphQLResolveError{
+		message:    domainErr.Message,
+		extensions: extensions,
+	}
+}
@@ -0,0 +1,171 @@
+package handler
+
+import (
+	// golang package
+	"fmt"
+	"strconv"
+	"strings"
+
+	// external package
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/location"
+)
+
+// graphQLListFields are the fields returning a page of products, their selection is resolved once per product.
+var graphQLListFields = map[string]bool{
+	"products": true,
+}
+
+// graphQLCost is the cost of a query, counted before it runs.
+type graphQLCost struct {
+	fragments       map[string]*ast.FragmentDefinition
+	variables       map[string]interface{}
+	defaultPageSize int
+	maxPageSize     int
+}
+
+// checkQueryLimits check query limits by given document pointer of ast.Document, operationName, and variables.
+//
+// Every field costs 1, the selection of a list field costs once per item of the page it asks for.
+// Introspection isn't counted, it doesn't reach the database.
+//
+// It returns nil error when the query is within GraphQLConfig.
+// Otherwise, error with code query_too_deep or query_too_complex will be returned.
+func (h *GraphQLHandler) checkQueryLimits(document *ast.Document, operationName string, variables map[string]interface{}) error {
+	cost := graphQLCost{
+		fragments:       make(map[string]*ast.FragmentDefinition),
+		variables:       variables,
+		defaultPageSize: h.SearchConfig.DefaultPageSize,
+		maxPageSize:     h.SearchConfig.MaxPageSize,
+	}
+
+	var operation *ast.OperationDefinition
+	for _, definition := range document.Definitions {
+		switch definition := definition.(type) {
+		case *ast.FragmentDefinition:
+			cost.fragments[definition.Name.Value] = definition
+		case *ast.OperationDefinition:
+			if operationName == "" || (definition.Name != nil && definition.Name.Value == operationName) {
+				operation = definition
+			}
+		}
+	}
+
+	// an unknown operation is reported when the query runs
+	if operation == nil {
+		return nil
+	}
+
+	depth, complexity := cost.selectionSet(operation.SelectionSet, 1)
+	if depth > h.GraphQLConfig.MaxDepth {
+		return graphQLLimitError("query_too_deep", fmt.Sprintf("query depth %d is over the limit of %d", depth, h.GraphQLConfig.MaxDepth))
+	}
+
+	if complexity > h.GraphQLConfig.MaxComplexity {
+		return graphQLLimitError("query_too_complex", fmt.Sprintf("query complexity %d is over the limit of %d", complexity, h.GraphQLConfig.MaxComplexity))
+	}
+
+	return nil
+}
+
+// graphQLLimitError graphql limit error by given code, and message.
+//
+// It returns error with the code as extension, it has no location as it's about the whole query.
+func graphQLLimitError(code string, message string) error {
+	return gqlerrors.FormattedError{
+		Message:    message,
+		Locations:  []location.SourceLocation{},
+		Extensions: map[string]interface{}{"code": code},
+	}
+}
+
+// selectionSet selection set by given selectionSet pointer of ast.SelectionSet, and depth of its fields.
+//
+// Fragments don't add depth, their fields are counted as if they were selected in place.
+//
+// It returns int of the deepest field, and int of the complexity.
+func (g graphQLCost) selectionSet(selectionSet *ast.SelectionSet, depth int) (int, int) {
+	if selectionSet == nil {
+		return 0, 0
+	}
+
+	maxDepth, complexity := 0, 0
+	for _, selection := range selectionSet.Selections {
+		var selectionDepth, selectionComplexity int
+
+		switch selection := selection.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(selection.Name.Value, "__") {
+				continue
+			}
+
+			childDepth, childComplexity := g.selectionSet(selection.SelectionSet, depth+1)
+			selectionDepth = max(depth, childDepth)
+			selectionComplexity = 1 + g.multiplier(selection)*childComplexity
+		case *ast.InlineFragment:
+			selectionDepth, selectionComplexity = g.selectionSet(selection.SelectionSet, depth)
+		case *ast.FragmentSpread:
+			// fragment cycles are rejected by validation before this runs
+			if fragment, ok := g.fragments[selection.Name.Value]; ok {
+				selectionDepth, selectionComplexity = g.selectionSet(fragment.SelectionSet, depth)
+			}
+		}
+
+		maxDepth = max(maxDepth, selectionDepth)
+		complexity += selectionComplexity
+	}
+
+	return maxDepth, complexity
+}
+
+// multiplier multiplier by given field pointer of ast.Field.
+//
+// It returns int of the page size a list field asks for, capped at the max page size, 1 for other fields.
+func (g graphQLCost) multiplier(field *ast.Field) int {
+	if !graphQLListFields[field.Name.Value] {
+		return 1
+	}
+
+	pageSize := g.defaultPageSize
+	for _, argument := range field.Arguments {
+		if argument.Name.Value != "page" {
+			continue
+		}
+
+		switch value := argument.Value.(type) {
+		case *ast.ObjectValue:
+			for _, objectField := range value.Fields {
+				if objectField.Name.Value == "pageSize" {
+					pageSize = g.intValue(objectField.Value, pageSize)
+				}
+			}
+		case *ast.Variable:
+			page, _ := g.variables[value.Name.Value].(map[string]interface{})
+			if size, ok := page["pageSize"].(float64); ok {
+				pageSize = int(size)
+			}
+		}
+	}
+
+	return max(1, min(pageSize, g.maxPageSize))
+}
+
+// intValue int value by given value of an argument, and fallback.
+//
+// It returns int of the literal or variable, fallback when it isn't an int.
+func (g graphQLCost) intValue(value ast.Value, fallback int) int {
+	switch value := value.(type) {
+	case *ast.IntValue:
+		number, err := strconv.Atoi(value.Value)
+		if err == nil {
+			return number
+		}
+	case *ast.Variable:
+		if number, ok := g.variables[value.Name.Value].(float64); ok {
+			return int(number)
+		}
+	}
+
+	return fallback
+}
@@ -0,0 +1,309 @@
+package handler
+
+import (
+	// golang package
+	"context"
+	"errors"
+	"fmt"
+	"productfc/cmd/product/service"
+	"productfc/cmd/product/usecase"
+	"productfc/config"
+	"productfc/infrastructure/log"
+	"productfc/models"
+	"productfc/proto/productpb"
+	"strconv"
+	"strings"
+
+	// external package
+	"github.com/sirupsen/logrus"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ProductGRPCServer serves productpb.ProductService, the gRPC counterpart of ProductHandler for internal callers.
+type ProductGRPCServer struct {
+	productpb.UnimplementedProductServiceServer
+
+	ProductUsecase usecase.ProductUsecase
+	SearchConfig   config.SearchConfig
+}
+
+// NewProductGRPCServer new product grpc server by given ProductUsecase, and searchConfig.
+//
+// It returns pointer of ProductGRPCServer.
+func NewProductGRPCServer(productUsecase usecase.ProductUsecase, searchConfig config.SearchConfig) *ProductGRPCServer {
+	return &ProductGRPCServer{
+		ProductUsecase: productUsecase,
+		SearchConfig:   searchConfig,
+	}
+}
+
+// GetProduct get product by given req pointer of productpb.GetProductRequest.
+//
+// It returns pointer of productpb.GetProductResponse, and nil error when successful.
+// Otherwise, nil pointer of productpb.GetProductResponse, and status error will be returned.
+func (s *ProductGRPCServer) GetProduct(ctx context.Context, req *productpb.GetProductRequest) (*productpb.GetProductResponse, error) {
+	if req.GetId() <= 0 {
+		return nil, grpcError(invalidField("id", "must be a positive integer"))
+	}
+
+	product, err := s.ProductUsecase.GetProductByID(ctx, req.GetId())
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"productID": req.GetId(),
+		}).Errorf("s.ProductUsecase.GetProductByID() got error %v", err)
+
+		return nil, grpcError(err)
+	}
+
+	if product.ID == 0 {
+		return nil, grpcError(service.ErrProductNotFound)
+	}
+
+	return &productpb.GetProductResponse{
+		Product: toProtoProduct(product),
+	}, nil
+}
+
+// BatchGetProducts batch get products by given req pointer of productpb.BatchGetProductsRequest.
+//
+// It returns pointer of productpb.BatchGetProductsResponse, and nil error when successful.
+// Otherwise, nil pointer of productpb.BatchGetProductsResponse, and status error will be returned.
+func (s *ProductGRPCServer) BatchGetProducts(ctx context.Context, req *productpb.BatchGetProductsRequest) (*productpb.BatchGetProductsResponse, error) {
+	if len(req.GetIds()) == 0 || len(req.GetIds()) > s.SearchConfig.MaxBatchProductIDs {
+		return nil, grpcError(invalidField("ids", fmt.Sprintf("must contain between 1 and %d product IDs", s.SearchConfig.MaxBatchProductIDs)))
+	}
+
+	results, err := s.ProductUsecase.GetProductsByIDs(ctx, req.GetIds())
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"ids": req.GetIds(),
+		}).Errorf("s.ProductUsecase.GetProductsByIDs() got error %v", err)
+
+		return nil, grpcError(err)
+	}
+
+	resp := &productpb.BatchGetProductsResponse{
+		Results: make([]*productpb.BatchProductResult, len(results)),
+	}
+	for i, result := range results {
+		resp.Results[i] = &productpb.BatchProductResult{
+			Id:      result.ID,
+			Found:   result.Found,
+			Product: toProtoProduct(result.Product),
+		}
+	}
+
+	return resp, nil
+}
+
+// SearchProducts search products by given req pointer of productpb.SearchProductsRequest.
+//
+// The request is validated like GET /v1/product/search.
+//
+// It returns pointer of productpb.SearchProductsResponse, and nil error when successful.
+// Otherwise, nil pointer of productpb.SearchProductsResponse, and status error will be returned.
+func (s *ProductGRPCServer) SearchProducts(ctx context.Context, req *productpb.SearchProductsRequest) (*productpb.SearchProductsResponse, error) {
+	var rawPage, rawPageSize string
+	if req.GetPage() != 0 {
+		rawPage = strconv.Itoa(int(req.GetPage()))
+	}
+
+	if req.GetPageSize() != 0 {
+		rawPageSize = strconv.Itoa(int(req.GetPageSize()))
+	}
+
+	page, pageSize, err := parseSearchPage(rawPage, rawPageSize, s.SearchConfig)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	sorts := make([]string, len(req.GetSort()))
+	for i, sortField := range req.GetSort() {
+		direction := "asc"
+		if sortField.GetDesc() {
+			direction = "desc"
+		}
+
+		sorts[i] = sortField.GetField() + ":" + direction
+	}
+
+	sortFields, err := parseSearchSort("", strings.Join(sorts, ","))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	var mode string
+	if req.GetFuzzy() {
+		mode = models.SearchModeFuzzy
+	}
+
+	param := models.SearchProductParameter{
+		Query:    req.GetQuery(),
+		Name:     req.GetName(),
+		Mode:     mode,
+		Category: req.GetCategory(),
+		MinPrice: req.GetMinPrice(),
+		MaxPrice: req.GetMaxPrice(),
+		Page:     page,
+		PageSize: pageSize,
+		Cursor:   req.GetCursor(),
+
+		CategoryIDs:        req.GetCategoryIds(),
+		ExcludeCategoryIDs: req.GetExcludeCategoryIds(),
+		IDs:                req.GetIds(),
+		ExcludeIDs:         req.GetExcludeIds(),
+		InStock:            req.GetInStock(),
+
+		SortFields: sortFields,
+	}
+
+	if len(param.IDs) > s.SearchConfig.MaxBatchProductIDs {
+		return nil, grpcError(invalidField("ids", fmt.Sprintf("must contain at most %d product IDs", s.SearchConfig.MaxBatchProductIDs)))
+	}
+
+	result, err := s.ProductUsecase.SearchProduct(ctx, param)
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"param": param,
+		}).Errorf("s.ProductUsecase.SearchProduct() got error %v", err)
+
+		return nil, grpcError(err)
+	}
+
+	resp := &productpb.SearchProductsResponse{
+		Products:   make([]*productpb.Product, len(result.Products)),
+		TotalCount: int32(result.TotalCount),
+		NextCursor: result.NextCursor,
+		PrevCursor: result.PrevCursor,
+		SearchId:   result.SearchID,
+	}
+	for i := range result.Products {
+		resp.Products[i] = toProtoProduct(&result.Products[i])
+	}
+
+	return resp, nil
+}
+
+// ReserveStock reserve stock by given req pointer of productpb.ReserveStockRequest.
+//
+// It returns pointer of productpb.ReserveStockResponse, and nil error when successful.
+// Otherwise, nil pointer of productpb.ReserveStockResponse, and status error will be returned.
+func (s *ProductGRPCServer) ReserveStock(ctx context.Context, req *productpb.ReserveStockRequest) (*productpb.ReserveStockResponse, error) {
+	if req.GetProductId() <= 0 {
+		return nil, grpcError(invalidField("product_id", "must be a positive integer"))
+	}
+
+	err := s.ProductUsecase.DeductProductStockByProductID(ctx, req.GetProductId(), int(req.GetQuantity()))
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"productID": req.GetProductId(),
+			"quantity":  req.GetQuantity(),
+		}).Errorf("s.ProductUsecase.DeductProductStockByProductID() got error %v", err)
+
+		return nil, grpcError(err)
+	}
+
+	return &productpb.ReserveStockResponse{}, nil
+}
+
+// ReleaseStock release stock by given req pointer of productpb.ReleaseStockRequest.
+//
+// It returns pointer of productpb.ReleaseStockResponse, and nil error when successful.
+// Otherwise, nil pointer of productpb.ReleaseStockResponse, and status error will be returned.
+func (s *ProductGRPCServer) ReleaseStock(ctx context.Context, req *productpb.ReleaseStockRequest) (*productpb.ReleaseStockResponse, error) {
+	if req.GetProductId() <= 0 {
+		return nil, grpcError(invalidField("product_id", "must be a positive integer"))
+	}
+
+	err := s.ProductUsecase.AddProductStockByProductID(ctx, req.GetProductId(), int(req.GetQuantity()))
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"productID": req.GetProductId(),
+			"quantity":  req.GetQuantity(),
+		}).Errorf("s.ProductUsecase.AddProductStockByProductID() got error %v", err)
+
+		return nil, grpcError(err)
+	}
+
+	return &productpb.ReleaseStockResponse{}, nil
+}
+
+// toProtoProduct to proto product by given product pointer of models.Product.
+//
+// It returns pointer of productpb.Product, nil when product is nil.
+func toProtoProduct(product *models.Product) *productpb.Product {
+	if product == nil {
+		return nil
+	}
+
+	return &productpb.Product{
+		Id:          product.ID,
+		Name:        product.Name,
+		Description: product.Description,
+		Price:       product.Price,
+		Stock:       int32(product.Stock),
+		CategoryId:  int32(product.CategoryID),
+		Popularity:  product.Popularity,
+		CreatedAt:   timestamppb.New(product.CreatedAt),
+		UpdatedAt:   timestamppb.New(product.UpdatedAt),
+	}
+}
+
+// grpcError grpc error by given err.
+//
+// A canceled or timed out call keeps its context code, so clients can tell it from a failure, whatever wrapped it.
+// Domain errors keep their code as the message, and field errors become BadRequest details.
+// Other errors are internal, their message is not exposed.
+//
+// It returns error of grpc status.
+func grpcError(err error) error {
+	if errors.Is(err, context.Canceled) {
+		return status.Error(codes.Canceled, context.Canceled.Error())
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return status.Error(codes.DeadlineExceeded, context.DeadlineExceeded.Error())
+	}
+
+	var domainErr *service.Error
+	if !errors.As(err, &domainErr) {
+		return status.Error(codes.Internal, "an unexpected error occurred")
+	}
+
+	code := codes.Internal
+	switch domainErr.Kind {
+	case service.ErrNotFound:
+		code = codes.NotFound
+	case service.ErrConflict:
+		code = codes.AlreadyExists
+	case service.ErrInsufficientStock:
+		code = codes.FailedPrecondition
+	case service.ErrValidation:
+		code = codes.InvalidArgument
+	case service.ErrUnavailable:
+		code = codes.Unavailable
+	}
+
+	st := status.New(code, domainErr.Code+": "+domainErr.Message)
+	if len(domainErr.Fields) == 0 {
+		return st.Err()
+	}
+
+	badRequest := &errdetails.BadRequest{}
+	for _, field := range domainErr.Fields {
+		badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       field.Field,
+			Description: field.Message,
+		})
+	}
+
+	detailed, detailErr := st.WithDetails(badRequest)
+	if detailErr != nil {
+		return st.Err()
+	}
+
+	return detailed.Err()
+}
@@ -2,29 +2,42 @@ package handler
 
 import (
 	// golang package
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"productfc/cmd/product/service"
 	"productfc/cmd/product/usecase"
+	"productfc/config"
 	"productfc/infrastructure/log"
 	"productfc/models"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	// external package
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 )
 
+const maxAutocompleteLimit = 50
+
 type ProductHandler struct {
-	ProductUsecase usecase.ProductUsecase
+	ProductUsecase    usecase.ProductUsecase
+	SearchConfig      config.SearchConfig
+	StockStreamConfig config.StockStreamConfig
 }
 
-// NewProductHandler new product handler by given ProductUsecase.
+// NewProductHandler new product handler by given ProductUsecase, SearchConfig, and StockStreamConfig.
 //
 // It returns pointer of ProductHandler when successful.
 // Otherwise, nil pointer of ProductHandler will be returned.
-func NewProductHandler(orderUsecase usecase.ProductUsecase) *ProductHandler {
+func NewProductHandler(orderUsecase usecase.ProductUsecase, searchConfig config.SearchConfig, stockStreamConfig config.StockStreamConfig) *ProductHandler {
 	return &ProductHandler{
-		ProductUsecase: orderUsecase,
+		ProductUsecase:    orderUsecase,
+		SearchConfig:      searchConfig,
+		StockStreamConfig: stockStreamConfig,
 	}
 }
 
@@ -37,9 +50,7 @@ func (h *ProductHandler) GetProductInfo(c *gin.Context) {
 		log.Logger.WithFields(logrus.Fields{
 			"productID": productIDstr,
 		}).Errorf("strconv.ParseInt got error %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error_message": "Invalid Product ID",
-		})
+		c.Error(invalidField("id", "must be a positive integer"))
 
 		return
 	}
@@ -49,9 +60,7 @@ func (h *ProductHandler) GetProductInfo(c *gin.Context) {
 		log.Logger.WithFields(logrus.Fields{
 			"productID": productID,
 		}).Errorf("h.ProductUsecase.GetProductByID() got error %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error_message": err,
-		})
+		c.Error(err)
 
 		return
 	}
@@ -60,9 +69,7 @@ func (h *ProductHandler) GetProductInfo(c *gin.Context) {
 		log.Logger.WithFields(logrus.Fields{
 			"productID": productID,
 		}).Info("Product ID not found")
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error_message": "Product Not Exists",
-		})
+		c.Error(service.ErrProductNotFound)
 		return
 	}
 
@@ -71,6 +78,40 @@ func (h *ProductHandler) GetProductInfo(c *gin.Context) {
 	})
 }
 
+// GetProductBatchInfo get product batch info by given c pointer of gin.Context.
+func (h *ProductHandler) GetProductBatchInfo(c *gin.Context) {
+	var param models.BatchProductParameter
+	if err := c.ShouldBindJSON(&param); err != nil {
+		log.Logger.Error(err.Error())
+		c.Error(invalidBody(err))
+
+		return
+	}
+
+	if len(param.IDs) == 0 || len(param.IDs) > h.SearchConfig.MaxBatchProductIDs {
+		log.Logger.WithFields(logrus.Fields{
+			"ids": len(param.IDs),
+		}).Error("invalid request - ids out of range")
+		c.Error(invalidField("ids", fmt.Sprintf("must contain between 1 and %d product IDs", h.SearchConfig.MaxBatchProductIDs)))
+
+		return
+	}
+
+	results, err := h.ProductUsecase.GetProductsByIDs(c.Request.Context(), param.IDs)
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"ids": param.IDs,
+		}).Errorf("h.ProductUsecase.GetProductsByIDs() got error %v", err)
+		c.Error(err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"products": results,
+	})
+}
+
 // GetProductCategoryInfo get product category info by given c pointer of gin.Context.
 func (h *ProductHandler) GetProductCategoryInfo(c *gin.Context) {
 	productCategoryIDstr := c.Param("id")
@@ -80,9 +121,7 @@ func (h *ProductHandler) GetProductCategoryInfo(c *gin.Context) {
 		log.Logger.WithFields(logrus.Fields{
 			"productID": productCategoryIDstr,
 		}).Errorf("strconv.Atoi got error %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error_message": "Invalid Product ID",
-		})
+		c.Error(invalidField("id", "must be a positive integer"))
 
 		return
 	}
@@ -92,9 +131,7 @@ func (h *ProductHandler) GetProductCategoryInfo(c *gin.Context) {
 		log.Logger.WithFields(logrus.Fields{
 			"productCategoryID": productCategoryID,
 		}).Errorf("h.ProductUsecase.GetProductCategoryByID() got error %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error_message": err,
-		})
+		c.Error(err)
 
 		return
 	}
@@ -103,9 +140,7 @@ func (h *ProductHandler) GetProductCategoryInfo(c *gin.Context) {
 		log.Logger.WithFields(logrus.Fields{
 			"productCategoryID": productCategoryID,
 		}).Info("Product Category Not Found")
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error_message": "Product Category Not Exists",
-		})
+		c.Error(service.ErrProductCategoryNotFound)
 		return
 	}
 
@@ -119,9 +154,7 @@ func (h *ProductHandler) ProductManagement(c *gin.Context) {
 	var param models.ProductManagementParameter
 	if err := c.ShouldBindJSON(&param); err != nil {
 		log.Logger.Error(err.Error())
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error_message": "Invalid Input",
-		})
+		c.Error(invalidBody(err))
 
 		return
 	}
@@ -129,9 +162,7 @@ func (h *ProductHandler) ProductManagement(c *gin.Context) {
 	// validateProductManagementParameter
 	if param.Action == "" {
 		log.Logger.Error("missing parameter action")
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error_message": "Missing required parameter",
-		})
+		c.Error(invalidField("action", "is required"))
 
 		return
 	}
@@ -142,9 +173,7 @@ func (h *ProductHandler) ProductManagement(c *gin.Context) {
 			log.Logger.WithFields(logrus.Fields{
 				"param": param,
 			}).Error("invalid request - product id is not empty")
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error_message": "Invalid Request",
-			})
+			c.Error(invalidField("id", "must not be set"))
 
 			return
 		}
@@ -155,9 +184,7 @@ func (h *ProductHandler) ProductManagement(c *gin.Context) {
 				"param": param,
 			}).Errorf("h.ProductUsecase.CreateNewProduct() got error %v", err)
 
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error_message": err,
-			})
+			c.Error(err)
 			return
 		}
 
@@ -171,22 +198,24 @@ func (h *ProductHandler) ProductManagement(c *gin.Context) {
 			log.Logger.WithFields(logrus.Fields{
 				"param": param,
 			}).Error("invalid request - product id is empty")
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error_message": "Invalid Request",
-			})
+			c.Error(invalidField("id", "is required"))
 
 			return
 		}
 
 		product, err := h.ProductUsecase.EditProduct(c.Request.Context(), &param.Product)
+		if errors.Is(err, service.ErrProductNotFound) {
+			c.Error(service.ErrProductNotFound)
+
+			return
+		}
+
 		if err != nil {
 			log.Logger.WithFields(logrus.Fields{
 				"param": param,
 			}).Errorf("h.ProductUsecase.EditProduct() got error %v", err)
 
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error_message": err,
-			})
+			c.Error(err)
 			return
 		}
 
@@ -201,21 +230,23 @@ func (h *ProductHandler) ProductManagement(c *gin.Context) {
 			log.Logger.WithFields(logrus.Fields{
 				"param": param,
 			}).Error("invalid request - product id is empty")
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error_message": "Invalid Request",
-			})
+			c.Error(invalidField("id", "is required"))
 
 			return
 		}
 
 		err := h.ProductUsecase.DeleteProduct(c.Request.Context(), param.ID)
+		if errors.Is(err, service.ErrProductNotFound) {
+			c.Error(service.ErrProductNotFound)
+
+			return
+		}
+
 		if err != nil {
 			log.Logger.WithFields(logrus.Fields{
 				"param": param,
 			}).Errorf("h.ProductUsecase.DeleteProduct() got error %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error_message": err,
-			})
+			c.Error(err)
 			return
 		}
 
@@ -224,9 +255,7 @@ func (h *ProductHandler) ProductManagement(c *gin.Context) {
 		})
 	default:
 		log.Logger.Errorf("Invalid action: %s", param.Action)
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error_message": "Invalid Action",
-		})
+		c.Error(invalidField("action", "must be add, edit or delete"))
 
 		return
 	}
@@ -238,17 +267,13 @@ func (h *ProductHandler) ProductCategoryManagement(c *gin.Context) {
 	var param models.ProductCategoryManagementParameter
 	if err := c.ShouldBindJSON(&param); err != nil {
 		log.Logger.Error(err.Error()) // utk debugging
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error_message": "Invalid Input",
-		})
+		c.Error(invalidBody(err))
 		return
 	}
 
 	if param.Action == "" {
 		log.Logger.Error("missing parameter action")
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error_message": "Missing required parameter",
-		})
+		c.Error(invalidField("action", "is required"))
 
 		return
 	}
@@ -259,9 +284,7 @@ func (h *ProductHandler) ProductCategoryManagement(c *gin.Context) {
 			log.Logger.WithFields(logrus.Fields{
 				"param": param,
 			}).Error("invalid request - product category id is not empty")
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error_message": "Invalid Request",
-			})
+			c.Error(invalidField("id", "must not be set"))
 
 			return
 		}
@@ -272,9 +295,7 @@ func (h *ProductHandler) ProductCategoryManagement(c *gin.Context) {
 				"param": param,
 			}).Errorf("h.ProductUsecase.CreateNewProductCategory got error %v", err)
 
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error_message": err,
-			})
+			c.Error(err)
 			return
 		}
 
@@ -288,22 +309,24 @@ func (h *ProductHandler) ProductCategoryManagement(c *gin.Context) {
 			log.Logger.WithFields(logrus.Fields{
 				"param": param,
 			}).Error("invalid request - product id is empty")
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error_message": "Invalid Request",
-			})
+			c.Error(invalidField("id", "is required"))
 
 			return
 		}
 
 		productCategory, err := h.ProductUsecase.EditProductCategory(c.Request.Context(), &param.ProductCategory)
+		if errors.Is(err, service.ErrProductCategoryNotFound) {
+			c.Error(service.ErrProductCategoryNotFound)
+
+			return
+		}
+
 		if err != nil {
 			log.Logger.WithFields(logrus.Fields{
 				"param": param,
 			}).Errorf("h.ProductUsecase.EditProductCategory got error %v", err)
 
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error_message": err,
-			})
+			c.Error(err)
 			return
 		}
 
@@ -318,20 +341,22 @@ func (h *ProductHandler) ProductCategoryManagement(c *gin.Context) {
 			log.Logger.WithFields(logrus.Fields{
 				"param": param,
 			}).Error("invalid request - product id is empty")
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error_message": "Invalid Request",
-			})
+			c.Error(invalidField("id", "is required"))
 			return
 		}
 
 		err := h.ProductUsecase.DeleteProductCategory(c.Request.Context(), param.ID)
+		if errors.Is(err, service.ErrProductCategoryNotFound) {
+			c.Error(service.ErrProductCategoryNotFound)
+
+			return
+		}
+
 		if err != nil {
 			log.Logger.WithFields(logrus.Fields{
 				"param": param, // notes: kalau ada PII --> prevent print log PII data
 			}).Errorf("h.ProductUsecase.DeleteProductCategory() got error %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error_message": err,
-			})
+			c.Error(err)
 			return
 		}
 
@@ -342,9 +367,7 @@ func (h *ProductHandler) ProductCategoryManagement(c *gin.Context) {
 		return
 	default:
 		log.Logger.Errorf("Invalid action: %s", param.Action)
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error_message": "Invalid Action",
-		})
+		c.Error(invalidField("action", "must be add, edit or delete"))
 
 		return
 	}
@@ -352,60 +375,400 @@ func (h *ProductHandler) ProductCategoryManagement(c *gin.Context) {
 
 // /v1/search/product?name=iphone...
 func (h *ProductHandler) SearchProduct(c *gin.Context) {
+	q := c.Query("q")
 	name := c.Query("name")
+	mode := strings.ToLower(c.Query("mode"))
 	category := c.Query("category")
 
 	minPrice, _ := strconv.ParseFloat(c.Query("minPrice"), 64)
 	maxPrice, _ := strconv.ParseFloat(c.Query("maxPrice"), 64)
 
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "2"))
+	page, pageSize, err := parseSearchPage(c.Query("page"), c.Query("pageSize"), h.SearchConfig)
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"page":     c.Query("page"),
+			"pageSize": c.Query("pageSize"),
+		}).Error(err.Error())
+		c.Error(err)
+
+		return
+	}
+
+	cursor := c.Query("cursor")
+
+	// sort=price:desc,name:asc, or the legacy orderBy=price&sort=desc
+	sortFields, err := parseSearchSort(c.Query("orderBy"), c.Query("sort"))
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"orderBy": c.Query("orderBy"),
+			"sort":    c.Query("sort"),
+		}).Error(err.Error())
+		c.Error(err)
+
+		return
+	}
+
+	// facets=category,price or facets=category&facets=price
+	facets, err := parseSearchFacets(c.QueryArray("facets"))
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"facets": c.QueryArray("facets"),
+		}).Error(err.Error())
+		c.Error(err)
 
-	orderBy := c.Query("orderBy")
-	sort := c.Query("sort")
+		return
+	}
 
 	param := models.SearchProductParameter{
+		Query:    q,
 		Name:     name,
+		Mode:     mode,
 		Category: category,
 		MinPrice: minPrice,
 		MaxPrice: maxPrice,
 		Page:     page,
 		PageSize: pageSize,
-		OrderBy:  orderBy,
-		Sort:     sort,
+		Cursor:   cursor,
+
+		SortFields: sortFields,
 	}
-	products, totalCount, err := h.ProductUsecase.SearchProduct(c.Request.Context(), param)
+
+	// category_id=1&category_id=2 or category_id=1,2, and so on
+	err = h.parseSearchFilters(c.Request.URL.Query(), &param)
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"query": c.Request.URL.RawQuery,
+		}).Error(err.Error())
+		c.Error(err)
+
+		return
+	}
+
+	result, err := h.ProductUsecase.SearchProduct(c.Request.Context(), param)
 	if err != nil {
 		log.Logger.WithFields(logrus.Fields{
 			"param": param,
 		}).Errorf("h.ProductUsecase.SearchProduct() got error %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error_message": err,
-		})
+		c.Error(err)
 
 		return
 	}
 
-	// next page url
+	var searchProductFacets *models.SearchProductFacets
+	if len(facets) > 0 {
+		searchProductFacets, err = h.ProductUsecase.SearchProductFacets(c.Request.Context(), param, facets)
+		if err != nil {
+			log.Logger.WithFields(logrus.Fields{
+				"param":  param,
+				"facets": facets,
+			}).Errorf("h.ProductUsecase.SearchProductFacets() got error %v", err)
+			c.Error(err)
+
+			return
+		}
+	}
+
+	totalCount := result.TotalCount
+
+	// did you mean
+	var suggestions []string
+	if totalCount == 0 && (q != "" || name != "") {
+		term := name
+		if term == "" {
+			term = q
+		}
+
+		suggestions, err = h.ProductUsecase.SuggestProductNames(c.Request.Context(), term)
+		if err != nil {
+			log.Logger.WithFields(logrus.Fields{
+				"term": term,
+			}).Errorf("h.ProductUsecase.SuggestProductNames() got error %v", err)
+		}
+	}
 
 	totalPages := (totalCount + pageSize - 1) / pageSize
+	links := searchProductLinks(c.Request, page, pageSize, totalPages, result)
+
+	var nextCursor, prevCursor *string
+	if result.NextCursor != "" {
+		nextCursor = &result.NextCursor
+	}
 
-	var nextPageUrl *string
-	if page < totalPages {
-		url := fmt.Sprintf("%s/v1/product/search?name=%s&category=%s&minPrice=%0.f&maxPrice=%0.f&page=%d&pageSize=%d",
-			c.Request.Host, name, category, minPrice, maxPrice, page+1, pageSize)
-		nextPageUrl = &url
+	if result.PrevCursor != "" {
+		prevCursor = &result.PrevCursor
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		// auto sorting a-z
 		"data": models.SearchProductResponse{
-			Products:    products,
+			Products:    result.Products,
 			Page:        page,
 			PageSize:    pageSize,
 			TotalCount:  totalCount,
 			TotalPages:  totalPages,
-			NextPageUrl: nextPageUrl,
+			NextPageUrl: links.Next,
+			NextCursor:  nextCursor,
+			PrevCursor:  prevCursor,
+			Suggestions: suggestions,
+			Facets:      searchProductFacets,
+			Links:       links,
+			SearchID:    result.SearchID,
 		},
 	})
 }
+
+// AutocompleteProduct autocomplete product by given c pointer of gin.Context.
+//
+// /v1/product/suggest?prefix=iph&limit=10&weighted=true, weighted ranks a bounded window of the lexical matches by popularity.
+func (h *ProductHandler) AutocompleteProduct(c *gin.Context) {
+	prefix := strings.TrimSpace(c.Query("prefix"))
+	if prefix == "" {
+		log.Logger.Error("missing parameter prefix")
+		c.Error(invalidField("prefix", "is required"))
+
+		return
+	}
+
+	// zero falls back to the configured limit
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	if limit > maxAutocompleteLimit {
+		limit = maxAutocompleteLimit
+	}
+
+	weighted, _ := strconv.ParseBool(c.Query("weighted"))
+
+	suggestions, err := h.ProductUsecase.Autocomplete(c.Request.Context(), prefix, limit, weighted)
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"prefix": prefix,
+		}).Errorf("h.ProductUsecase.Autocomplete() got error %v", err)
+		c.Error(err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"suggestions": suggestions,
+	})
+}
+
+// parseSearchFacets parse search facets by given slice of values.
+//
+// It returns slice of string without duplicates, and nil error when successful.
+// Otherwise, nil value of string slice, and error will be returned.
+func parseSearchFacets(values []string) ([]string, error) {
+	var facets []string
+	seen := make(map[string]bool)
+	for _, value := range values {
+		for _, facet := range strings.Split(value, ",") {
+			facet = strings.ToLower(strings.TrimSpace(facet))
+			if facet == "" || seen[facet] {
+				continue
+			}
+
+			switch facet {
+			case models.SearchFacetCategory, models.SearchFacetPrice, models.SearchFacetStock:
+			default:
+				return nil, invalidField("facets", fmt.Sprintf("unknown facet %q, valid facets are: %s, %s, %s", facet, models.SearchFacetCategory, models.SearchFacetPrice, models.SearchFacetStock))
+			}
+
+			seen[facet] = true
+			facets = append(facets, facet)
+		}
+	}
+
+	return facets, nil
+}
+
+// parseSearchSort parse search sort by given orderBy, and sort.
+//
+// sort is either a list of field:direction pairs (sort=price:desc,name:asc),
+// or the legacy ASC / DESC applied to orderBy.
+//
+// It returns slice of SortField, and nil error when successful.
+// Otherwise, nil value of SortField slice, and error will be returned.
+func parseSearchSort(orderBy string, sort string) ([]models.SortField, error) {
+	orderBy = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(orderBy)), "product.")
+	sort = strings.ToLower(strings.TrimSpace(sort))
+
+	// legacy orderBy & sort
+	if sort == "" || sort == "asc" || sort == "desc" {
+		if orderBy == "" {
+			if sort == "" {
+				return nil, nil
+			}
+
+			orderBy = models.SortFieldName
+		}
+
+		sort = orderBy + ":" + sort
+	} else if orderBy != "" {
+		return nil, invalidField("orderBy", "can't be combined with sort=field:direction, use sort only")
+	}
+
+	var sortFields []models.SortField
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(sort, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		field, direction, _ := strings.Cut(part, ":")
+		field = strings.TrimSpace(field)
+		direction = strings.TrimSpace(direction)
+
+		if !slices.Contains(models.SearchProductSortFields, field) {
+			return nil, invalidField("sort", fmt.Sprintf("unknown field %q, valid fields are: %s", field, strings.Join(models.SearchProductSortFields, ", ")))
+		}
+
+		if direction != "" && direction != "asc" && direction != "desc" {
+			return nil, invalidField("sort", fmt.Sprintf("unknown direction %q for field %q, valid directions are: asc, desc", direction, field))
+		}
+
+		if seen[field] {
+			return nil, invalidField("sort", fmt.Sprintf("duplicate field %q", field))
+		}
+
+		seen[field] = true
+		sortFields = append(sortFields, models.SortField{Field: field, Desc: direction == "desc"})
+	}
+
+	return sortFields, nil
+}
+
+// parseSearchFilters parse search filters by given query, and param pointer of SearchProductParameter.
+//
+// It returns nil error when successful.
+// Otherwise, error will be returned.
+func (h *ProductHandler) parseSearchFilters(query url.Values, param *models.SearchProductParameter) error {
+	var err error
+	param.CategoryIDs, err = parseIDList("category_id", query["category_id"])
+	if err != nil {
+		return err
+	}
+
+	param.ExcludeCategoryIDs, err = parseIDList("exclude_category_id", query["exclude_category_id"])
+	if err != nil {
+		return err
+	}
+
+	param.IDs, err = parseIDList("ids", query["ids"])
+	if err != nil {
+		return err
+	}
+
+	if len(param.IDs) > h.SearchConfig.MaxBatchProductIDs {
+		return invalidField("ids", fmt.Sprintf("must contain at most %d product IDs", h.SearchConfig.MaxBatchProductIDs))
+	}
+
+	param.ExcludeIDs, err = parseIDList("exclude_ids", query["exclude_ids"])
+	if err != nil {
+		return err
+	}
+
+	if value := query.Get("in_stock"); value != "" {
+		param.InStock, err = strconv.ParseBool(value)
+		if err != nil {
+			return invalidField("in_stock", fmt.Sprintf("%q is not true or false", value))
+		}
+	}
+
+	timeFilters := []struct {
+		name  string
+		value *time.Time
+	}{
+		{"created_after", &param.CreatedAfter},
+		{"created_before", &param.CreatedBefore},
+		{"updated_after", &param.UpdatedAfter},
+		{"updated_before", &param.UpdatedBefore},
+	}
+	for _, timeFilter := range timeFilters {
+		*timeFilter.value, err = parseTimeFilter(timeFilter.name, query.Get(timeFilter.name))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseIDList parse id list by given name, and slice of values.
+//
+// Every value may hold several comma separated ids.
+//
+// It returns slice of int64, and nil error when successful.
+// Otherwise, nil value of int64 slice, and error will be returned.
+func parseIDList(name string, values []string) ([]int64, error) {
+	var ids []int64
+	for _, value := range values {
+		for _, rawID := range strings.Split(value, ",") {
+			rawID = strings.TrimSpace(rawID)
+			if rawID == "" {
+				continue
+			}
+
+			id, err := strconv.ParseInt(rawID, 10, 64)
+			if err != nil || id <= 0 {
+				return nil, invalidField(name, fmt.Sprintf("%q is not a positive integer", rawID))
+			}
+
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, nil
+}
+
+// parseTimeFilter parse time filter by given name, and value.
+//
+// value is either RFC 3339 (2024-01-02T15:04:05Z) or a date (2024-01-02, midnight UTC).
+//
+// It returns time.Time, zero when value is empty, and nil error when successful.
+// Otherwise, zero time.Time, and error will be returned.
+func parseTimeFilter(name string, value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err == nil {
+		return parsed, nil
+	}
+
+	parsed, err = time.Parse(time.DateOnly, value)
+	if err == nil {
+		return parsed, nil
+	}
+
+	return time.Time{}, invalidField(name, fmt.Sprintf("%q is not RFC 3339 or YYYY-MM-DD", value))
+}
+
+// parseSearchPage parse search page by given page, pageSize, and SearchConfig.
+//
+// It's shared by every API searching products, so they all validate pages alike.
+//
+// It returns int of page, int of pageSize falling back to the configured default, and nil error when successful.
+// Otherwise, zero page, zero pageSize, and error will be returned.
+func parseSearchPage(rawPage string, rawPageSize string, searchConfig config.SearchConfig) (int, int, error) {
+	page := 1
+	if rawPage != "" {
+		var err error
+		page, err = strconv.Atoi(rawPage)
+		if err != nil || page < 1 {
+			return 0, 0, invalidField("page", fmt.Sprintf("%q is not a positive integer", rawPage))
+		}
+	}
+
+	pageSize := searchConfig.DefaultPageSize
+	if rawPageSize != "" {
+		var err error
+		pageSize, err = strconv.Atoi(rawPageSize)
+		if err != nil || pageSize < 1 || pageSize > searchConfig.MaxPageSize {
+			return 0, 0, invalidField("pageSize", fmt.Sprintf("%q is not between 1 and %d", rawPageSize, searchConfig.MaxPageSize))
+		}
+	}
+
+	return page, pageSize, nil
+}
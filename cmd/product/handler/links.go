@@ -0,0 +1,150 @@
+package handler
+
+import (
+	// golang package
+	"net/http"
+	"net/url"
+	"productfc/models"
+	"strconv"
+	"strings"
+)
+
+// searchLinkBuilder builds absolute links to other pages of the same search.
+type searchLinkBuilder struct {
+	baseURL url.URL
+	query   url.Values
+}
+
+// newSearchLinkBuilder new search link builder by given req pointer of http.Request.
+//
+// The scheme honours X-Forwarded-Proto, so links stay correct behind a TLS terminating proxy.
+// Anything but http or https there is ignored, the scheme is then the one the request came in with.
+//
+// It returns pointer of searchLinkBuilder.
+func newSearchLinkBuilder(req *http.Request) *searchLinkBuilder {
+	scheme := "http"
+	if req.TLS != nil {
+		scheme = "https"
+	}
+
+	// "https, http" when there's more than one proxy, the first one is the client facing
+	forwardedProto, _, _ := strings.Cut(req.Header.Get("X-Forwarded-Proto"), ",")
+	switch forwardedProto = strings.ToLower(strings.TrimSpace(forwardedProto)); forwardedProto {
+	case "http", "https":
+		scheme = forwardedProto
+	}
+
+	return &searchLinkBuilder{
+		baseURL: url.URL{
+			Scheme: scheme,
+			Host:   req.Host,
+			Path:   req.URL.Path,
+		},
+		query: req.URL.Query(),
+	}
+}
+
+// self build self link.
+//
+// It returns string of the requested URL.
+func (b *searchLinkBuilder) self() string {
+	return b.build(b.query)
+}
+
+// page build page link by given page, and pageSize.
+//
+// It returns string of the URL for the given page, without any cursor.
+func (b *searchLinkBuilder) page(page int, pageSize int) string {
+	query := b.clone()
+	query.Del("cursor")
+	query.Set("page", strconv.Itoa(page))
+	query.Set("pageSize", strconv.Itoa(pageSize))
+
+	return b.build(query)
+}
+
+// cursor build cursor link by given cursor, and pageSize.
+//
+// It returns string of the URL for the given cursor, without any page.
+func (b *searchLinkBuilder) cursor(cursor string, pageSize int) string {
+	query := b.clone()
+	query.Del("page")
+	query.Set("cursor", cursor)
+	query.Set("pageSize", strconv.Itoa(pageSize))
+
+	return b.build(query)
+}
+
+// clone clone request query.
+//
+// It returns deep copy of the request query, so repeated params survive.
+func (b *searchLinkBuilder) clone() url.Values {
+	query := make(url.Values, len(b.query))
+	for key, values := range b.query {
+		query[key] = append([]string(nil), values...)
+	}
+
+	return query
+}
+
+// build build link by given query.
+//
+// It returns string of absolute URL with query encoded.
+func (b *searchLinkBuilder) build(query url.Values) string {
+	link := b.baseURL
+	link.RawQuery = query.Encode()
+
+	return link.String()
+}
+
+// searchProductLinks search product links by given req pointer of http.Request, page, pageSize, totalPages, and result pointer of SearchProductResult.
+//
+// In cursor mode next and prev follow the result cursors, otherwise they follow the page number.
+//
+// It returns SearchProductLinks.
+func searchProductLinks(req *http.Request, page int, pageSize int, totalPages int, result *models.SearchProductResult) models.SearchProductLinks {
+	builder := newSearchLinkBuilder(req)
+
+	lastPage := totalPages
+	if lastPage < 1 {
+		lastPage = 1
+	}
+
+	links := models.SearchProductLinks{
+		Self:  builder.self(),
+		First: builder.page(1, pageSize),
+		Last:  builder.page(lastPage, pageSize),
+	}
+
+	if req.URL.Query().Get("cursor") != "" {
+		if result.NextCursor != "" {
+			next := builder.cursor(result.NextCursor, pageSize)
+			links.Next = &next
+		}
+
+		if result.PrevCursor != "" {
+			prev := builder.cursor(result.PrevCursor, pageSize)
+			links.Prev = &prev
+		}
+
+		return links
+	}
+
+	if page < totalPages {
+		next := builder.page(page+1, pageSize)
+		links.Next = &next
+	}
+
+	if page > 1 {
+		// past the end, prev goes back to the last page
+		prevPage := page - 1
+		if prevPage > lastPage {
+			prevPage = lastPage
+		}
+
+		prev := builder.page(prevPage, pageSize)
+		links.Prev = &prev
+	}
+
+	return links
+}
@@ -0,0 +1,44 @@
+package handler
+
+import (
+	// golang package
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSearchLinkScheme(t *testing.T) {
+	tests := []struct {
+		name           string
+		tls            bool
+		forwardedProto string
+		want           string
+	}{
+		{"plain", false, "", "http://example.com/v1/product/search?q=phone"},
+		{"tls", true, "", "https://example.com/v1/product/search?q=phone"},
+		{"forwarded https", false, "https", "https://example.com/v1/product/search?q=phone"},
+		{"forwarded by two proxies", false, " HTTPS , http", "https://example.com/v1/product/search?q=phone"},
+		{"forwarded http over tls", true, "http", "http://example.com/v1/product/search?q=phone"},
+		{"forwarded garbage", false, "javascript", "http://example.com/v1/product/search?q=phone"},
+		{"forwarded garbage over tls", true, "ftp", "https://example.com/v1/product/search?q=phone"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://example.com/v1/product/search?q=phone", nil)
+			req.TLS = nil
+			if tt.tls {
+				req.TLS = &tls.ConnectionState{}
+			}
+
+			if tt.forwardedProto != "" {
+				req.Header.Set("X-Forwarded-Proto", tt.forwardedProto)
+			}
+
+			if got := newSearchLinkBuilder(req).self(); got != tt.want {
+				t.Fatalf("self() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
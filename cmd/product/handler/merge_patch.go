@@ -0,0 +1,164 @@
+package handler
+
+import (
+	// golang package
+	"bytes"
+	"encoding/json"
+	"productfc/cmd/product/service"
+	"sort"
+)
+
+const mergePatchContentType = "application/merge-patch+json"
+
+var errMergePatchNotObject = service.NewValidationError("merge patch must be a JSON object")
+
+// mergePatchField decodes one member of a merge patch into its column value.
+type mergePatchField struct {
+	column   string
+	nullable bool // null resets the column to its zero value, otherwise null is rejected
+	decode   func(raw json.RawMessage) (interface{}, error)
+}
+
+// productPatchFields are the product fields a merge patch may change, keyed by json name.
+var productPatchFields = map[string]mergePatchField{
+	"name":        {column: "name", decode: decodePatchString},
+	"description": {column: "description", nullable: true, decode: decodePatchString},
+	"price":       {column: "price", decode: decodePatchFloat},
+	"stock":       {column: "stock", decode: decodePatchInt},
+	"category_id": {column: "category_id", decode: decodePatchInt},
+}
+
+// productImmutableFields can never be changed by a client.
+var productImmutableFields = map[string]bool{
+	"id":         true,
+	"created_at": true,
+	"updated_at": true,
+	"popularity": true,
+}
+
+// productCategoryPatchFields are the product category fields a merge patch may change, keyed by json name.
+var productCategoryPatchFields = map[string]mergePatchField{
+	"name": {column: "name", decode: decodePatchString},
+}
+
+// productCategoryImmutableFields can never be changed by a client.
+var productCategoryImmutableFields = map[string]bool{
+	"id":         true,
+	"created_at": true,
+	"updated_at": true,
+}
+
+// parseProductMergePatch parse product merge patch by given body.
+//
+// It returns map of column to value, and nil error when successful.
+// Otherwise, nil map, and error will be returned.
+func parseProductMergePatch(body []byte) (map[string]interface{}, error) {
+	return parseMergePatch(body, "product", productPatchFields, productImmutableFields)
+}
+
+// parseProductCategoryMergePatch parse product category merge patch by given body.
+//
+// It returns map of column to value, and nil error when successful.
+// Otherwise, nil map, and error will be returned.
+func parseProductCategoryMergePatch(body []byte) (map[string]interface{}, error) {
+	return parseMergePatch(body, "product category", productCategoryPatchFields, productCategoryImmutableFields)
+}
+
+// parseMergePatch parse merge patch by given body, entity name, patchFields, and immutableFields.
+//
+// The body follows RFC 7396, members present are changed, members absent are kept,
+// and null removes the value where the column allows it.
+//
+// It returns map of column to value, and nil error when successful.
+// Otherwise, nil map, and error will be returned.
+func parseMergePatch(body []byte, entity string, patchFields map[string]mergePatchField, immutableFields map[string]bool) (map[string]interface{}, error) {
+	// a patch that isn't an object would replace the whole product
+	if !bytes.HasPrefix(bytes.TrimSpace(body), []byte("{")) {
+		return nil, errMergePatchNotObject
+	}
+
+	var members map[string]json.RawMessage
+	err := json.Unmarshal(body, &members)
+	if err != nil {
+		return nil, service.NewValidationError("invalid merge patch: " + err.Error())
+	}
+
+	names := make([]string, 0, len(members))
+	for name := range members {
+		names = append(names, name)
+	}
+
+	// report errors in a stable order
+	sort.Strings(names)
+
+	fields := make(map[string]interface{}, len(members))
+	for _, name := range names {
+		if immutableFields[name] {
+			return nil, invalidField(name, "is immutable")
+		}
+
+		field, ok := patchFields[name]
+		if !ok {
+			return nil, invalidField(name, "is not a "+entity+" field")
+		}
+
+		raw := members[name]
+		if string(bytes.TrimSpace(raw)) == "null" {
+			if !field.nullable {
+				return nil, invalidField(name, "can't be removed")
+			}
+
+			fields[field.column] = ""
+			continue
+		}
+
+		fields[field.column], err = field.decode(raw)
+		if err != nil {
+			return nil, invalidField(name, err.Error())
+		}
+	}
+
+	return fields, nil
+}
+
+// decodePatchString decode patch string by given raw json.RawMessage.
+//
+// It returns interface of string, and nil error when successful.
+// Otherwise, nil interface, and error will be returned.
+func decodePatchString(raw json.RawMessage) (interface{}, error) {
+	var value string
+	err := json.Unmarshal(raw, &value)
+	if err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// decodePatchFloat decode patch float by given raw json.RawMessage.
+//
+// It returns interface of float64, and nil error when successful.
+// Otherwise, nil interface, and error will be returned.
+func decodePatchFloat(raw json.RawMessage) (interface{}, error) {
+	var value float64
+	err := json.Unmarshal(raw, &value)
+	if err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// decodePatchInt decode patch int by given raw json.RawMessage.
+//
+// It returns interface of int, and nil error when successful.
+// Otherwise, nil interface, and error will be returned.
+func decodePatchInt(raw json.RawMessage) (interface{}, error) {
+	var value int
+	err := json.Unmarshal(raw, &value)
+	if err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
@@ -0,0 +1,491 @@
+package handler
+
+import (
+	// golang package
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"productfc/cmd/product/service"
+	"productfc/infrastructure/log"
+	"productfc/middleware"
+	"productfc/models"
+	"strconv"
+	"time"
+
+	// external package
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// CreateProduct create product by given c pointer of gin.Context.
+//
+// POST /v2/products
+func (h *ProductHandler) CreateProduct(c *gin.Context) {
+	var product models.Product
+	if err := c.ShouldBindJSON(&product); err != nil {
+		log.Logger.Error(err.Error())
+		c.Error(invalidBody(err))
+
+		return
+	}
+
+	// the id is assigned by DB
+	if product.ID != 0 {
+		log.Logger.WithFields(logrus.Fields{
+			"product": product,
+		}).Error("invalid request - product id is not empty")
+		c.Error(invalidField("id", "must not be set"))
+
+		return
+	}
+
+	// the timestamps are kept by DB, whatever the client sent
+	product.CreatedAt = time.Time{}
+	product.UpdatedAt = time.Time{}
+
+	productID, err := h.ProductUsecase.CreateNewProduct(c.Request.Context(), &product)
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"product": product,
+		}).Errorf("h.ProductUsecase.CreateNewProduct() got error %v", err)
+		c.Error(err)
+
+		return
+	}
+
+	c.Header("Location", fmt.Sprintf("/v2/products/%d", productID))
+	c.JSON(http.StatusCreated, gin.H{
+		"product": product,
+	})
+}
+
+// GetProduct get product by given c pointer of gin.Context.
+//
+// GET /v2/products/:id
+func (h *ProductHandler) GetProduct(c *gin.Context) {
+	productID, ok := parseProductIDParam(c)
+	if !ok {
+		return
+	}
+
+	product, err := h.ProductUsecase.GetProductByID(c.Request.Context(), productID)
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"productID": productID,
+		}).Errorf("h.ProductUsecase.GetProductByID() got error %v", err)
+		c.Error(err)
+
+		return
+	}
+
+	if product.ID == 0 {
+		c.Error(service.ErrProductNotFound)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"product": product,
+	})
+}
+
+// ReplaceProduct replace product by given c pointer of gin.Context.
+//
+// PUT /v2/products/:id, every field is written, absent ones become zero.
+func (h *ProductHandler) ReplaceProduct(c *gin.Context) {
+	productID, ok := parseProductIDParam(c)
+	if !ok {
+		return
+	}
+
+	var product models.Product
+	if err := c.ShouldBindJSON(&product); err != nil {
+		log.Logger.Error(err.Error())
+		c.Error(invalidBody(err))
+
+		return
+	}
+
+	if product.ID != 0 && product.ID != productID {
+		log.Logger.WithFields(logrus.Fields{
+			"productID": productID,
+			"product":   product,
+		}).Error("invalid request - product id doesn't match the path")
+		c.Error(invalidField("id", "doesn't match the path"))
+
+		return
+	}
+
+	product.ID = productID
+	product.CreatedAt = time.Time{}
+	product.UpdatedAt = time.Time{}
+	h.editProduct(c, &product)
+}
+
+// UpdateProduct update product by given c pointer of gin.Context.
+//
+// PATCH /v2/products/:id with a JSON merge patch (RFC 7396), only the fields present in the body are changed.
+func (h *ProductHandler) UpdateProduct(c *gin.Context) {
+	productID, ok := parseProductIDParam(c)
+	if !ok {
+		return
+	}
+
+	body, ok := readMergePatch(c)
+	if !ok {
+		return
+	}
+
+	fields, err := parseProductMergePatch(body)
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"productID": productID,
+		}).Error(err.Error())
+		c.Error(err)
+
+		return
+	}
+
+	product, err := h.ProductUsecase.PatchProduct(c.Request.Context(), productID, fields)
+	if errors.Is(err, service.ErrProductNotFound) {
+		c.Error(service.ErrProductNotFound)
+
+		return
+	}
+
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"productID": productID,
+			"fields":    fields,
+		}).Errorf("h.ProductUsecase.PatchProduct() got error %v", err)
+		c.Error(err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"product": product,
+	})
+}
+
+// readMergePatch read merge patch by given c pointer of gin.Context.
+//
+// It returns slice of byte of the body, and true when it's a merge patch or plain JSON.
+// Otherwise, nil slice of byte, and false will be returned, with the error already set on c.
+func readMergePatch(c *gin.Context) ([]byte, bool) {
+	contentType := c.ContentType()
+	if contentType != mergePatchContentType && contentType != gin.MIMEJSON {
+		c.Error(middleware.NewHTTPError(http.StatusUnsupportedMediaType, "unsupported_media_type", fmt.Sprintf("Content-Type must be %s", mergePatchContentType)))
+
+		return nil, false
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		log.Logger.Error(err.Error())
+		c.Error(invalidBody(err))
+
+		return nil, false
+	}
+
+	return body, true
+}
+
+// editProduct edit product by given c pointer of gin.Context, and product pointer of models.Product.
+func (h *ProductHandler) editProduct(c *gin.Context, product *models.Product) {
+	updatedProduct, err := h.ProductUsecase.EditProduct(c.Request.Context(), product)
+	if errors.Is(err, service.ErrProductNotFound) {
+		c.Error(service.ErrProductNotFound)
+
+		return
+	}
+
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"product": product,
+		}).Errorf("h.ProductUsecase.EditProduct() got error %v", err)
+		c.Error(err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"product": updatedProduct,
+	})
+}
+
+// DeleteProduct delete product by given c pointer of gin.Context.
+//
+// DELETE /v2/products/:id
+func (h *ProductHandler) DeleteProduct(c *gin.Context) {
+	productID, ok := parseProductIDParam(c)
+	if !ok {
+		return
+	}
+
+	err := h.ProductUsecase.DeleteProduct(c.Request.Context(), productID)
+	if errors.Is(err, service.ErrProductNotFound) {
+		c.Error(service.ErrProductNotFound)
+
+		return
+	}
+
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"productID": productID,
+		}).Errorf("h.ProductUsecase.DeleteProduct() got error %v", err)
+		c.Error(err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// CreateProductCategory create product category by given c pointer of gin.Context.
+//
+// POST /v2/categories
+func (h *ProductHandler) CreateProductCategory(c *gin.Context) {
+	var productCategory models.ProductCategory
+	if err := c.ShouldBindJSON(&productCategory); err != nil {
+		log.Logger.Error(err.Error())
+		c.Error(invalidBody(err))
+
+		return
+	}
+
+	if productCategory.ID != 0 {
+		log.Logger.WithFields(logrus.Fields{
+			"productCategory": productCategory,
+		}).Error("invalid request - product category id is not empty")
+		c.Error(invalidField("id", "must not be set"))
+
+		return
+	}
+
+	// the timestamps are kept by DB, whatever the client sent
+	productCategory.CreatedAt = time.Time{}
+	productCategory.UpdatedAt = time.Time{}
+
+	productCategoryID, err := h.ProductUsecase.CreateNewProductCategory(c.Request.Context(), &productCategory)
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"productCategory": productCategory,
+		}).Errorf("h.ProductUsecase.CreateNewProductCategory() got error %v", err)
+		c.Error(err)
+
+		return
+	}
+
+	c.Header("Location", fmt.Sprintf("/v2/categories/%d", productCategoryID))
+	c.JSON(http.StatusCreated, gin.H{
+		"productCategory": productCategory,
+	})
+}
+
+// GetProductCategory get product category by given c pointer of gin.Context.
+//
+// GET /v2/categories/:id
+func (h *ProductHandler) GetProductCategory(c *gin.Context) {
+	productCategoryID, ok := parseProductCategoryIDParam(c)
+	if !ok {
+		return
+	}
+
+	productCategory, err := h.ProductUsecase.GetProductCategoryByID(c.Request.Context(), productCategoryID)
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"productCategoryID": productCategoryID,
+		}).Errorf("h.ProductUsecase.GetProductCategoryByID() got error %v", err)
+		c.Error(err)
+
+		return
+	}
+
+	if productCategory.ID == 0 {
+		c.Error(service.ErrProductCategoryNotFound)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"productCategory": productCategory,
+	})
+}
+
+// ReplaceProductCategory replace product category by given c pointer of gin.Context.
+//
+// PUT /v2/categories/:id
+func (h *ProductHandler) ReplaceProductCategory(c *gin.Context) {
+	productCategoryID, ok := parseProductCategoryIDParam(c)
+	if !ok {
+		return
+	}
+
+	var productCategory models.ProductCategory
+	if err := c.ShouldBindJSON(&productCategory); err != nil {
+		log.Logger.Error(err.Error())
+		c.Error(invalidBody(err))
+
+		return
+	}
+
+	if productCategory.ID != 0 && productCategory.ID != productCategoryID {
+		log.Logger.WithFields(logrus.Fields{
+			"productCategoryID": productCategoryID,
+			"productCategory":   productCategory,
+		}).Error("invalid request - product category id doesn't match the path")
+		c.Error(invalidField("id", "doesn't match the path"))
+
+		return
+	}
+
+	productCategory.ID = productCategoryID
+	productCategory.CreatedAt = time.Time{}
+	productCategory.UpdatedAt = time.Time{}
+	h.editProductCategory(c, &productCategory)
+}
+
+// UpdateProductCategory update product category by given c pointer of gin.Context.
+//
+// PATCH /v2/categories/:id with a JSON merge patch (RFC 7396), only the fields present in the body are changed.
+func (h *ProductHandler) UpdateProductCategory(c *gin.Context) {
+	productCategoryID, ok := parseProductCategoryIDParam(c)
+	if !ok {
+		return
+	}
+
+	body, ok := readMergePatch(c)
+	if !ok {
+		return
+	}
+
+	fields, err := parseProductCategoryMergePatch(body)
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"productCategoryID": productCategoryID,
+		}).Error(err.Error())
+		c.Error(err)
+
+		return
+	}
+
+	productCategory, err := h.ProductUsecase.GetProductCategoryByID(c.Request.Context(), productCategoryID)
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"productCategoryID": productCategoryID,
+		}).Errorf("h.ProductUsecase.GetProductCategoryByID() got error %v", err)
+		c.Error(err)
+
+		return
+	}
+
+	if productCategory.ID == 0 {
+		c.Error(service.ErrProductCategoryNotFound)
+
+		return
+	}
+
+	// an empty patch changes nothing
+	if len(fields) == 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"productCategory": productCategory,
+		})
+
+		return
+	}
+
+	if name, ok := fields["name"].(string); ok {
+		productCategory.Name = name
+	}
+
+	h.editProductCategory(c, productCategory)
+}
+
+// editProductCategory edit product category by given c pointer of gin.Context, and productCategory pointer of models.ProductCategory.
+func (h *ProductHandler) editProductCategory(c *gin.Context, productCategory *models.ProductCategory) {
+	updatedProductCategory, err := h.ProductUsecase.EditProductCategory(c.Request.Context(), productCategory)
+	if errors.Is(err, service.ErrProductCategoryNotFound) {
+		c.Error(service.ErrProductCategoryNotFound)
+
+		return
+	}
+
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"productCategory": productCategory,
+		}).Errorf("h.ProductUsecase.EditProductCategory() got error %v", err)
+		c.Error(err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"productCategory": updatedProductCategory,
+	})
+}
+
+// DeleteProductCategory delete product category by given c pointer of gin.Context.
+//
+// DELETE /v2/categories/:id
+func (h *ProductHandler) DeleteProductCategory(c *gin.Context) {
+	productCategoryID, ok := parseProductCategoryIDParam(c)
+	if !ok {
+		return
+	}
+
+	err := h.ProductUsecase.DeleteProductCategory(c.Request.Context(), productCategoryID)
+	if errors.Is(err, service.ErrProductCategoryNotFound) {
+		c.Error(service.ErrProductCategoryNotFound)
+
+		return
+	}
+
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"productCategoryID": productCategoryID,
+		}).Errorf("h.ProductUsecase.DeleteProductCategory() got error %v", err)
+		c.Error(err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// parseProductIDParam parse product id param by given c pointer of gin.Context.
+//
+// It returns int64 of product id, and true when successful.
+// Otherwise, 400 is written, and zero id, and false will be returned.
+func parseProductIDParam(c *gin.Context) (int64, bool) {
+	productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || productID <= 0 {
+		log.Logger.WithFields(logrus.Fields{
+			"productID": c.Param("id"),
+		}).Error("invalid request - invalid product id")
+		c.Error(invalidField("id", "must be a positive integer"))
+
+		return 0, false
+	}
+
+	return productID, true
+}
+
+// parseProductCategoryIDParam parse product category id param by given c pointer of gin.Context.
+//
+// It returns int of product category id, and true when successful.
+// Otherwise, 400 is written, and zero id, and false will be returned.
+func parseProductCategoryIDParam(c *gin.Context) (int, bool) {
+	productCategoryID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || productCategoryID <= 0 {
+		log.Logger.WithFields(logrus.Fields{
+			"productCategoryID": c.Param("id"),
+		}).Error("invalid request - invalid product category id")
+		c.Error(invalidField("id", "must be a positive integer"))
+
+		return 0, false
+	}
+
+	return productCategoryID, true
+}
@@ -0,0 +1,178 @@
+package handler
+
+import (
+	// golang package
+	"fmt"
+	"net/http"
+	"productfc/cmd/product/service"
+	"productfc/infrastructure/log"
+	"productfc/models"
+	"strconv"
+
+	// external package
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const maxSearchReportLimit = 100
+
+// RecordSearchClick record search click by given c pointer of gin.Context.
+//
+// POST /v1/search/click {"searchId": "...", "productId": 1, "position": 3}
+func (h *ProductHandler) RecordSearchClick(c *gin.Context) {
+	var param models.SearchClickParameter
+	if err := c.ShouldBindJSON(&param); err != nil {
+		log.Logger.Error(err.Error())
+		c.Error(invalidBody(err))
+
+		return
+	}
+
+	var fields []service.FieldError
+	if uuid.Validate(param.SearchID) != nil {
+		fields = append(fields, service.FieldError{Field: "searchId", Message: "must be the searchId of a search response"})
+	}
+
+	if param.ProductID <= 0 {
+		fields = append(fields, service.FieldError{Field: "productId", Message: "must be a positive integer"})
+	}
+
+	if param.Position < 0 {
+		fields = append(fields, service.FieldError{Field: "position", Message: "must not be negative"})
+	}
+
+	if len(fields) > 0 {
+		log.Logger.WithFields(logrus.Fields{
+			"param": param,
+		}).Error("invalid request - invalid search click")
+		c.Error(service.NewValidationError("invalid search click", fields...))
+
+		return
+	}
+
+	err := h.ProductUsecase.RecordSearchClick(c.Request.Context(), param)
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"param": param,
+		}).Errorf("h.ProductUsecase.RecordSearchClick() got error %v", err)
+		c.Error(err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetTopSearchQueries get top search queries by given c pointer of gin.Context.
+//
+// /v1/search/analytics/top-queries?from=2024-01-01&to=2024-02-01&limit=20
+func (h *ProductHandler) GetTopSearchQueries(c *gin.Context) {
+	param, err := parseSearchReportParameter(c)
+	if err != nil {
+		log.Logger.Error(err.Error())
+		c.Error(err)
+
+		return
+	}
+
+	stats, err := h.ProductUsecase.GetTopSearchQueries(c.Request.Context(), param)
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"param": param,
+		}).Errorf("h.ProductUsecase.GetTopSearchQueries() got error %v", err)
+		c.Error(err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"queries": stats,
+	})
+}
+
+// GetZeroResultSearchQueries get zero result search queries by given c pointer of gin.Context.
+//
+// /v1/search/analytics/zero-results?from=2024-01-01&to=2024-02-01&limit=20
+func (h *ProductHandler) GetZeroResultSearchQueries(c *gin.Context) {
+	param, err := parseSearchReportParameter(c)
+	if err != nil {
+		log.Logger.Error(err.Error())
+		c.Error(err)
+
+		return
+	}
+
+	stats, err := h.ProductUsecase.GetZeroResultSearchQueries(c.Request.Context(), param)
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"param": param,
+		}).Errorf("h.ProductUsecase.GetZeroResultSearchQueries() got error %v", err)
+		c.Error(err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"queries": stats,
+	})
+}
+
+// GetSearchClickThroughRates get search click through rates by given c pointer of gin.Context.
+//
+// /v1/search/analytics/ctr?from=2024-01-01&to=2024-02-01&limit=20
+func (h *ProductHandler) GetSearchClickThroughRates(c *gin.Context) {
+	param, err := parseSearchReportParameter(c)
+	if err != nil {
+		log.Logger.Error(err.Error())
+		c.Error(err)
+
+		return
+	}
+
+	stats, err := h.ProductUsecase.GetSearchClickThroughRates(c.Request.Context(), param)
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"param": param,
+		}).Errorf("h.ProductUsecase.GetSearchClickThroughRates() got error %v", err)
+		c.Error(err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"queries": stats,
+	})
+}
+
+// parseSearchReportParameter parse search report parameter by given c pointer of gin.Context.
+//
+// It returns SearchReportParameter, zero values are defaulted by the service, and nil error when successful.
+// Otherwise, empty SearchReportParameter, and error will be returned.
+func parseSearchReportParameter(c *gin.Context) (models.SearchReportParameter, error) {
+	var param models.SearchReportParameter
+
+	var err error
+	param.From, err = parseTimeFilter("from", c.Query("from"))
+	if err != nil {
+		return models.SearchReportParameter{}, err
+	}
+
+	param.To, err = parseTimeFilter("to", c.Query("to"))
+	if err != nil {
+		return models.SearchReportParameter{}, err
+	}
+
+	if !param.From.IsZero() && !param.To.IsZero() && !param.From.Before(param.To) {
+		return models.SearchReportParameter{}, invalidField("from", "must be before to")
+	}
+
+	if rawLimit := c.Query("limit"); rawLimit != "" {
+		param.Limit, err = strconv.Atoi(rawLimit)
+		if err != nil || param.Limit < 1 || param.Limit > maxSearchReportLimit {
+			return models.SearchReportParameter{}, invalidField("limit", fmt.Sprintf("%q is not between 1 and %d", rawLimit, maxSearchReportLimit))
+		}
+	}
+
+	return param, nil
+}
@@ -0,0 +1,128 @@
+package handler
+
+import (
+	// golang package
+	"context"
+	"fmt"
+	"net/http"
+	"productfc/infrastructure/log"
+	"productfc/models"
+	"slices"
+	"time"
+
+	// external package
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	stockStreamEvent       = "stock"
+	stockStreamSnapshotTTL = 2 * time.Second
+)
+
+// StreamProductStock stream product stock by given c pointer of gin.Context.
+//
+// It's a Server-Sent Events stream of the stock and price of the products in ids. The current stock is sent first,
+// then an event on every change of a higher version than the last one sent, and a heartbeat comment while nothing changes. The stream ends after MaxDuration,
+// or when it falls behind, the client is expected to reconnect.
+func (h *ProductHandler) StreamProductStock(c *gin.Context) {
+	productIDs, err := parseIDList("ids", c.QueryArray("ids"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	slices.Sort(productIDs)
+	productIDs = slices.Compact(productIDs)
+	if len(productIDs) == 0 || len(productIDs) > h.StockStreamConfig.MaxProductIDs {
+		c.Error(invalidField("ids", fmt.Sprintf("must contain between 1 and %d product IDs", h.StockStreamConfig.MaxProductIDs)))
+		return
+	}
+
+	subscription, err := h.ProductUsecase.SubscribeProductStock(productIDs)
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"ids": productIDs,
+		}).Errorf("h.ProductUsecase.SubscribeProductStock() got error %v", err)
+		c.Error(err)
+
+		return
+	}
+
+	defer h.ProductUsecase.UnsubscribeProductStock(subscription)
+
+	// the current stock is read once subscribed, so no change is missed in between
+	ctx := c.Request.Context()
+	snapshotCtx, cancel := context.WithTimeout(ctx, stockStreamSnapshotTTL)
+	defer cancel()
+
+	snapshot, err := h.ProductUsecase.GetProductStockSnapshot(snapshotCtx, productIDs)
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"ids": productIDs,
+		}).Errorf("h.ProductUsecase.GetProductStockSnapshot() got error %v", err)
+		c.Error(err)
+
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	sent := make(stockStreamVersions, len(snapshot))
+	for _, event := range snapshot {
+		sent.newer(event)
+		c.SSEvent(stockStreamEvent, event)
+	}
+
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(h.StockStreamConfig.HeartbeatInterval)
+	defer heartbeat.Stop()
+
+	maxDuration := time.NewTimer(h.StockStreamConfig.MaxDuration)
+	defer maxDuration.Stop()
+
+	for {
+		select {
+		case event, ok := <-subscription.Events:
+			if !ok {
+				return
+			}
+
+			if !sent.newer(event) {
+				continue
+			}
+
+			c.SSEvent(stockStreamEvent, event)
+		case <-heartbeat.C:
+			c.Writer.WriteString(": heartbeat\n\n")
+		case <-maxDuration.C:
+			return
+		case <-ctx.Done():
+			return
+		}
+
+		c.Writer.Flush()
+	}
+}
+
+// stockStreamVersions are the versions of the products last sent on a stream, by product id.
+type stockStreamVersions map[int64]int64
+
+// newer newer by given event models.ProductStockEvent.
+//
+// Events aren't published in order, one that isn't newer than what was sent is already superseded.
+//
+// It returns true, and records its version, when event is newer than the last one sent of its product.
+func (v stockStreamVersions) newer(event models.ProductStockEvent) bool {
+	if last, ok := v[event.ProductID]; ok && event.Version <= last {
+		return false
+	}
+
+	v[event.ProductID] = event.Version
+
+	return true
+}
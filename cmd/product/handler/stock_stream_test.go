@@ -0,0 +1,31 @@
+package handler
+
+import (
+	// golang package
+	"productfc/models"
+	"testing"
+)
+
+func TestStockStreamVersions(t *testing.T) {
+	sent := make(stockStreamVersions)
+
+	tests := []struct {
+		name  string
+		event models.ProductStockEvent
+		want  bool
+	}{
+		{"first of a product", models.ProductStockEvent{ProductID: 1, Version: 3}, true},
+		{"first of another product", models.ProductStockEvent{ProductID: 2, Version: 1}, true},
+		{"newer", models.ProductStockEvent{ProductID: 1, Version: 5}, true},
+		{"published twice", models.ProductStockEvent{ProductID: 1, Version: 5}, false},
+		{"published late", models.ProductStockEvent{ProductID: 1, Version: 4}, false},
+		{"newer after a late one", models.ProductStockEvent{ProductID: 1, Version: 6}, true},
+		{"other product unaffected", models.ProductStockEvent{ProductID: 2, Version: 2}, true},
+	}
+
+	for _, tt := range tests {
+		if got := sent.newer(tt.event); got != tt.want {
+			t.Fatalf("%s: newer(%+v) = %v, want %v", tt.name, tt.event, got, tt.want)
+		}
+	}
+}
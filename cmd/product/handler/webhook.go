@@ -0,0 +1,249 @@
+package handler
+
+import (
+	// golang package
+	"errors"
+	"fmt"
+	"net/http"
+	"productfc/cmd/product/service"
+	"productfc/infrastructure/log"
+	"productfc/models"
+	"strconv"
+
+	// external package
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const maxWebhookDeliveryLimit = 200
+
+// CreateWebhook create webhook by given c pointer of gin.Context.
+//
+// The secret is only ever returned here, it's generated when none is given.
+//
+// POST /v2/webhooks
+func (h *ProductHandler) CreateWebhook(c *gin.Context) {
+	var subscription models.WebhookSubscription
+	if err := c.ShouldBindJSON(&subscription); err != nil {
+		log.Logger.Error(err.Error())
+		c.Error(invalidBody(err))
+
+		return
+	}
+
+	// the id is assigned by DB
+	if subscription.ID != 0 {
+		log.Logger.WithFields(logrus.Fields{
+			"url": subscription.URL,
+		}).Error("invalid request - webhook id is not empty")
+		c.Error(invalidField("id", "must not be set"))
+
+		return
+	}
+
+	createdSubscription, err := h.ProductUsecase.CreateWebhookSubscription(c.Request.Context(), &subscription)
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"url": subscription.URL,
+		}).Errorf("h.ProductUsecase.CreateWebhookSubscription() got error %v", err)
+		c.Error(err)
+
+		return
+	}
+
+	c.Header("Location", fmt.Sprintf("/v2/webhooks/%d", createdSubscription.ID))
+	c.JSON(http.StatusCreated, gin.H{
+		"webhook": createdSubscription,
+	})
+}
+
+// ListWebhooks list webhooks by given c pointer of gin.Context.
+//
+// GET /v2/webhooks
+func (h *ProductHandler) ListWebhooks(c *gin.Context) {
+	subscriptions, err := h.ProductUsecase.GetWebhookSubscriptions(c.Request.Context())
+	if err != nil {
+		log.Logger.Errorf("h.ProductUsecase.GetWebhookSubscriptions() got error %v", err)
+		c.Error(err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"webhooks": subscriptions,
+	})
+}
+
+// GetWebhook get webhook by given c pointer of gin.Context.
+//
+// GET /v2/webhooks/:id
+func (h *ProductHandler) GetWebhook(c *gin.Context) {
+	subscriptionID, ok := parseWebhookIDParam(c)
+	if !ok {
+		return
+	}
+
+	subscription, err := h.ProductUsecase.GetWebhookSubscriptionByID(c.Request.Context(), subscriptionID)
+	if errors.Is(err, service.ErrWebhookNotFound) {
+		c.Error(service.ErrWebhookNotFound)
+
+		return
+	}
+
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"subscriptionID": subscriptionID,
+		}).Errorf("h.ProductUsecase.GetWebhookSubscriptionByID() got error %v", err)
+		c.Error(err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"webhook": subscription,
+	})
+}
+
+// ReplaceWebhook replace webhook by given c pointer of gin.Context.
+//
+// Fields missing from the body keep their stored value, the secret included.
+//
+// PUT /v2/webhooks/:id
+func (h *ProductHandler) ReplaceWebhook(c *gin.Context) {
+	subscriptionID, ok := parseWebhookIDParam(c)
+	if !ok {
+		return
+	}
+
+	subscription, err := h.ProductUsecase.GetWebhookSubscriptionByID(c.Request.Context(), subscriptionID)
+	if errors.Is(err, service.ErrWebhookNotFound) {
+		c.Error(service.ErrWebhookNotFound)
+
+		return
+	}
+
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"subscriptionID": subscriptionID,
+		}).Errorf("h.ProductUsecase.GetWebhookSubscriptionByID() got error %v", err)
+		c.Error(err)
+
+		return
+	}
+
+	if err := c.ShouldBindJSON(subscription); err != nil {
+		log.Logger.Error(err.Error())
+		c.Error(invalidBody(err))
+
+		return
+	}
+
+	subscription.ID = subscriptionID
+	updatedSubscription, err := h.ProductUsecase.EditWebhookSubscription(c.Request.Context(), subscription)
+	if errors.Is(err, service.ErrWebhookNotFound) {
+		c.Error(service.ErrWebhookNotFound)
+
+		return
+	}
+
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"subscriptionID": subscriptionID,
+		}).Errorf("h.ProductUsecase.EditWebhookSubscription() got error %v", err)
+		c.Error(err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"webhook": updatedSubscription,
+	})
+}
+
+// DeleteWebhook delete webhook by given c pointer of gin.Context.
+//
+// DELETE /v2/webhooks/:id
+func (h *ProductHandler) DeleteWebhook(c *gin.Context) {
+	subscriptionID, ok := parseWebhookIDParam(c)
+	if !ok {
+		return
+	}
+
+	err := h.ProductUsecase.DeleteWebhookSubscription(c.Request.Context(), subscriptionID)
+	if errors.Is(err, service.ErrWebhookNotFound) {
+		c.Error(service.ErrWebhookNotFound)
+
+		return
+	}
+
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"subscriptionID": subscriptionID,
+		}).Errorf("h.ProductUsecase.DeleteWebhookSubscription() got error %v", err)
+		c.Error(err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListWebhookDeliveries list webhook deliveries by given c pointer of gin.Context.
+//
+// GET /v2/webhooks/:id/deliveries?limit=50
+func (h *ProductHandler) ListWebhookDeliveries(c *gin.Context) {
+	subscriptionID, ok := parseWebhookIDParam(c)
+	if !ok {
+		return
+	}
+
+	// zero falls back to the default limit
+	var limit int
+	if rawLimit := c.Query("limit"); rawLimit != "" {
+		var err error
+		limit, err = strconv.Atoi(rawLimit)
+		if err != nil || limit < 1 || limit > maxWebhookDeliveryLimit {
+			c.Error(invalidField("limit", fmt.Sprintf("%q is not between 1 and %d", rawLimit, maxWebhookDeliveryLimit)))
+
+			return
+		}
+	}
+
+	deliveries, err := h.ProductUsecase.GetWebhookDeliveries(c.Request.Context(), subscriptionID, limit)
+	if errors.Is(err, service.ErrWebhookNotFound) {
+		c.Error(service.ErrWebhookNotFound)
+
+		return
+	}
+
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"subscriptionID": subscriptionID,
+		}).Errorf("h.ProductUsecase.GetWebhookDeliveries() got error %v", err)
+		c.Error(err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"deliveries": deliveries,
+	})
+}
+
+// parseWebhookIDParam parse webhook id param by given c pointer of gin.Context.
+//
+// It returns int64 of webhook id, and true when successful.
+// Otherwise, 400 is written, and zero id, and false will be returned.
+func parseWebhookIDParam(c *gin.Context) (int64, bool) {
+	subscriptionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || subscriptionID <= 0 {
+		log.Logger.WithFields(logrus.Fields{
+			"subscriptionID": c.Param("id"),
+		}).Error("invalid request - invalid webhook id")
+		c.Error(invalidField("id", "must be a positive integer"))
+
+		return 0, false
+	}
+
+	return subscriptionID, true
+}
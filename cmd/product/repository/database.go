@@ -6,9 +6,13 @@ import (
 	"errors"
 	"fmt"
 	"productfc/models"
+	"sort"
+	"strings"
+	"time"
 
 	// external package
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // FindProductByID find product by id by given productID.
@@ -29,6 +33,20 @@ func (r *ProductRepository) FindProductByID(ctx context.Context, productID int64
 	return &product, nil
 }
 
+// FindProductsByIDs find products by ids by given slice of productIDs.
+//
+// It returns slice of models.Product, and nil error when successful. Missing IDs are simply absent from the result.
+// Otherwise, nil value of models.Product slice, and error will be returned.
+func (r *ProductRepository) FindProductsByIDs(ctx context.Context, productIDs []int64) ([]models.Product, error) {
+	var products []models.Product
+	err := r.Database.WithContext(ctx).Table("product").Where("id IN ?", productIDs).Find(&products).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return products, nil
+}
+
 // FindProductCategoryByID find product category by id by given productCategoryID.
 //
 // It returns pointer of models.ProductCategory, and nil error when successful.
@@ -47,6 +65,129 @@ func (r *ProductRepository) FindProductCategoryByID(ctx context.Context, product
 	return &productCategory, nil
 }
 
+// FindProductCategoriesByIDs find product categories by ids by given slice of productCategoryIDs.
+//
+// It returns slice of models.ProductCategory, and nil error when successful. Missing IDs are simply absent from the result.
+// Otherwise, nil value of models.ProductCategory slice, and error will be returned.
+func (r *ProductRepository) FindProductCategoriesByIDs(ctx context.Context, productCategoryIDs []int) ([]models.ProductCategory, error) {
+	var productCategories []models.ProductCategory
+	err := r.Database.WithContext(ctx).Table("product_category").Where("id IN ?", productCategoryIDs).Find(&productCategories).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return productCategories, nil
+}
+
+// ExistsProductNameInCategory exists product name in category by given name, categoryID, and excludeProductID.
+//
+// Names are compared case insensitively, excludeProductID is skipped so a product doesn't clash with itself.
+//
+// It returns bool, and nil error when successful.
+// Otherwise, false, and error will be returned.
+func (r *ProductRepository) ExistsProductNameInCategory(ctx context.Context, name string, categoryID int, excludeProductID int64) (bool, error) {
+	var count int64
+	err := r.Database.WithContext(ctx).Table("product").
+		Where("category_id = ? AND LOWER(name) = LOWER(?) AND id <> ?", categoryID, name, excludeProductID).
+		Limit(1).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// FindLatestUpdatedProducts find latest updated products by given limit.
+//
+// It returns slice of models.Product, and nil error when successful.
+// Otherwise, nil value of models.Product slice, and error will be returned.
+func (r *ProductRepository) FindLatestUpdatedProducts(ctx context.Context, limit int) ([]models.Product, error) {
+	var products []models.Product
+	err := r.Database.WithContext(ctx).Table("product").Order("updated_at DESC").Limit(limit).Find(&products).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return products, nil
+}
+
+// productDocumentFilter narrows FindProductDocuments, zero values don't filter.
+type productDocumentFilter struct {
+	AfterID           int64
+	Limit             int
+	ProductIDs        []int64
+	ProductCategoryID int
+}
+
+// productDocumentRow is a product joined with its category name.
+type productDocumentRow struct {
+	models.Product
+	Category string
+}
+
+// findProductDocuments find product documents by given productDocumentFilter.
+//
+// It returns slice of productDocumentRow ordered by id, and nil error when successful.
+// Otherwise, nil value of productDocumentRow slice, and error will be returned.
+func (r *ProductRepository) findProductDocuments(ctx context.Context, filter productDocumentFilter) ([]productDocumentRow, error) {
+	query := r.Database.WithContext(ctx).Table("product").
+		Select("product.*, product_category.name AS category").
+		Joins("JOIN product_category ON product.category_id = product_category.id").
+		Where("product.id > ?", filter.AfterID).
+		Order("product.id")
+
+	if len(filter.ProductIDs) > 0 {
+		query = query.Where("product.id IN ?", filter.ProductIDs)
+	}
+
+	if filter.ProductCategoryID != 0 {
+		query = query.Where("product.category_id = ?", filter.ProductCategoryID)
+	}
+
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var rows []productDocumentRow
+	err := query.Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// FindAllProductCategories find all product categories.
+//
+// It returns slice of models.ProductCategory, and nil error when successful.
+// Otherwise, nil value of models.ProductCategory slice, and error will be returned.
+func (r *ProductRepository) FindAllProductCategories(ctx context.Context) ([]models.ProductCategory, error) {
+	var productCategories []models.ProductCategory
+	err := r.Database.WithContext(ctx).Table("product_category").Order("id").Find(&productCategories).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return productCategories, nil
+}
+
+// FindAllProductSuggestions find all product suggestions.
+//
+// It returns slice of models.AutocompleteSuggestion, and nil error when successful.
+// Otherwise, nil value of models.AutocompleteSuggestion slice, and error will be returned.
+func (r *ProductRepository) FindAllProductSuggestions(ctx context.Context) ([]models.AutocompleteSuggestion, error) {
+	var suggestions []models.AutocompleteSuggestion
+	err := r.Database.WithContext(ctx).Table("product").
+		Select("? AS type, id, name, popularity", models.AutocompleteTypeProduct).
+		Scan(&suggestions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return suggestions, nil
+}
+
 // InsertNewProduct insert new product by given product pointer of models.Product.
 //
 // It returns int64, and nil error when successful.
@@ -73,104 +214,182 @@ func (r *ProductRepository) InsertNewProductCategory(ctx context.Context, produc
 	return productCategory.ID, nil
 }
 
-// UpdateProductStockByProductID update product stock by product id by given productID, and qty.
-//
-// It returns nil error when successful.
-// Otherwise, error will be returned.
-func (r *ProductRepository) DeductProductStockByProductID(ctx context.Context, productID int64, qty int) error {
-	err := r.Database.Table("product").WithContext(ctx).Model(&models.Product{}).
-		Updates(map[string]interface{}{
-			"stock": gorm.Expr("stock - %d", qty),
-		}).Where("id = ?", productID).Error
-	if err != nil {
-		return err
-	}
+// productFieldColumns are the product columns UpdateProductFields may write.
+var productFieldColumns = map[string]bool{
+	"name":        true,
+	"description": true,
+	"price":       true,
+	"stock":       true,
+	"category_id": true,
+}
 
-	return nil
+// DeductProductStockByProductID deduct product stock by product id by given productID, and qty.
+//
+// Sold units are counted towards the product popularity.
+//
+// It returns pointer of models.Product as updated, and nil error when successful, with an empty one when the product
+// is missing or has less than qty in stock.
+// Otherwise, nil pointer of models.Product, and error will be returned.
+func (r *ProductRepository) DeductProductStockByProductID(ctx context.Context, productID int64, qty int) (*models.Product, error) {
+	// never below zero, the product is left untouched when there's not enough stock,
+	// raw since popularity is read only to the model
+	return r.updateProductReturning(ctx, `
+		UPDATE product SET stock = stock - ?, popularity = popularity + ?, updated_at = ?, version = version + 1
+		WHERE id = ? AND stock >= ?
+		RETURNING *`, qty, qty, time.Now(), productID, qty)
 }
 
 // AddProductStockByProductID add product stock by product id by given productID, and qty.
 //
-// It returns nil error when successful.
-// Otherwise, error will be returned.
-func (r *ProductRepository) AddProductStockByProductID(ctx context.Context, productID int64, qty int) error {
-	err := r.Database.Table("product").WithContext(ctx).Model(&models.Product{}).
-		Updates(map[string]interface{}{
-			"stock": gorm.Expr("stock + %d", qty),
-		}).Where("id = ?", productID).Error
-	if err != nil {
-		return err
-	}
-
-	return nil
+// It is the rollback of DeductProductStockByProductID, so the popularity is reverted as well.
+//
+// It returns pointer of models.Product as updated, and nil error when successful, with an empty one when the product is missing.
+// Otherwise, nil pointer of models.Product, and error will be returned.
+func (r *ProductRepository) AddProductStockByProductID(ctx context.Context, productID int64, qty int) (*models.Product, error) {
+	return r.updateProductReturning(ctx, `
+		UPDATE product SET stock = stock + ?, popularity = GREATEST(popularity - ?, 0), updated_at = ?, version = version + 1
+		WHERE id = ?
+		RETURNING *`, qty, qty, time.Now(), productID)
 }
 
 // UpdateProduct update product by given product pointer of models.Product.
 //
-// It returns pointer of models.Product, and nil error when successful.
+// It returns pointer of models.Product as updated, and nil error when successful, with an empty one when the product is missing.
 // Otherwise, nil pointer of models.Product, and error will be returned.
 func (r *ProductRepository) UpdateProduct(ctx context.Context, product *models.Product) (*models.Product, error) {
-	err := r.Database.WithContext(ctx).Table("product").Save(product).Error
+	return r.updateProductReturning(ctx, `
+		UPDATE product SET name = ?, description = ?, price = ?, stock = ?, category_id = ?, updated_at = ?, version = version + 1
+		WHERE id = ?
+		RETURNING *`, product.Name, product.Description, product.Price, product.Stock, product.CategoryID, time.Now(), product.ID)
+}
+
+// UpdateProductFields update product fields by given productID, and fields.
+//
+// Only the given columns are written, along with updated_at.
+//
+// It returns pointer of models.Product as updated, and nil error when successful, with an empty one when the product is missing.
+// Otherwise, nil pointer of models.Product, and error will be returned.
+func (r *ProductRepository) UpdateProductFields(ctx context.Context, productID int64, fields map[string]interface{}) (*models.Product, error) {
+	columns := make([]string, 0, len(fields))
+	for column := range fields {
+		if !productFieldColumns[column] {
+			return nil, fmt.Errorf("product column %q can't be updated", column)
+		}
+
+		columns = append(columns, column)
+	}
+
+	// stable statements for the prepared statement cache
+	sort.Strings(columns)
+
+	set := make([]string, 0, len(columns)+2)
+	args := make([]interface{}, 0, len(columns)+2)
+	for _, column := range columns {
+		set = append(set, column+" = ?")
+		args = append(args, fields[column])
+	}
+
+	set = append(set, "updated_at = ?", "version = version + 1")
+	args = append(args, time.Now(), productID)
+
+	return r.updateProductReturning(ctx, "UPDATE product SET "+strings.Join(set, ", ")+" WHERE id = ? RETURNING *", args...)
+}
+
+// updateProductReturning update product returning by given query, and args.
+//
+// The query must be an UPDATE of product ending with RETURNING *, raw since version and popularity are read only to the model.
+//
+// It returns pointer of models.Product as updated, and nil error when successful, with an empty one when no row matched.
+// Otherwise, nil pointer of models.Product, and error will be returned.
+func (r *ProductRepository) updateProductReturning(ctx context.Context, query string, args ...interface{}) (*models.Product, error) {
+	var products []models.Product
+	err := r.Database.WithContext(ctx).Raw(query, args...).Scan(&products).Error
 	if err != nil {
 		return nil, err
 	}
 
-	return product, nil // updated data
+	if len(products) == 0 {
+		return &models.Product{}, nil
+	}
+
+	return &products[0], nil
 }
 
 // UpdateProductCategory update product category by given productCategory pointer of models.ProductCategory.
 //
-// It returns pointer of models.ProductCategory, and nil error when successful.
+// It returns pointer of models.ProductCategory as updated, and nil error when successful, with an empty one when the product
+// category is missing.
 // Otherwise, nil pointer of models.ProductCategory, and error will be returned.
 func (r *ProductRepository) UpdateProductCategory(ctx context.Context, productCategory *models.ProductCategory) (*models.ProductCategory, error) {
-	err := r.Database.WithContext(ctx).Table("product_category").Save(productCategory).Error
+	var productCategories []models.ProductCategory
+	err := r.Database.WithContext(ctx).Raw(`
+		UPDATE product_category SET name = ?, updated_at = ?, version = version + 1
+		WHERE id = ?
+		RETURNING *`, productCategory.Name, time.Now(), productCategory.ID).Scan(&productCategories).Error
 	if err != nil {
 		return nil, err
 	}
 
-	return productCategory, nil
+	if len(productCategories) == 0 {
+		return &models.ProductCategory{}, nil
+	}
+
+	return &productCategories[0], nil
 }
 
 // DeleteProduct delete product by given productID.
 //
-// It returns nil error when successful.
-// Otherwise, error will be returned.
-func (r *ProductRepository) DeleteProduct(ctx context.Context, productID int64) error {
-	err := r.Database.WithContext(ctx).Table("product").Delete(&models.Product{}, productID).Error
+// It returns int64 of the deleted version, and nil error when successful, with zero when the product is missing.
+// Otherwise, zero int64, and error will be returned.
+func (r *ProductRepository) DeleteProduct(ctx context.Context, productID int64) (int64, error) {
+	var products []models.Product
+	err := r.Database.WithContext(ctx).Raw("DELETE FROM product WHERE id = ? RETURNING *", productID).Scan(&products).Error
 	if err != nil {
-		return err
+		return 0, err
+	}
+
+	if len(products) == 0 {
+		return 0, nil
 	}
 
-	return nil
+	return products[0].Version, nil
 }
 
 // DeleteProductCategory delete product category by given productCategoryID.
 //
-// It returns nil error when successful.
-// Otherwise, error will be returned.
-func (r *ProductRepository) DeleteProductCategory(ctx context.Context, productCategoryID int) error {
-	err := r.Database.WithContext(ctx).Table("product_category").Delete(&models.ProductCategory{}, productCategoryID).Error
+// It returns int64 of the deleted version, and nil error when successful, with zero when the product category is missing.
+// Otherwise, zero int64, and error will be returned.
+func (r *ProductRepository) DeleteProductCategory(ctx context.Context, productCategoryID int) (int64, error) {
+	var productCategories []models.ProductCategory
+	err := r.Database.WithContext(ctx).Raw("DELETE FROM product_category WHERE id = ? RETURNING *", productCategoryID).Scan(&productCategories).Error
 	if err != nil {
-		return err
+		return 0, err
+	}
+
+	if len(productCategories) == 0 {
+		return 0, nil
 	}
 
-	return nil
+	return productCategories[0].Version, nil
 }
 
-// SearchProduct search product by given SearchProductParameter.
+// searchProductQuery search product query by given SearchProductParameter.
 //
-// It returns slice of models.Product, int, and nil error when successful.
-// Otherwise, nil value of models.Product slice, empty int, and error will be returned.
-func (r *ProductRepository) SearchProduct(ctx context.Context, param models.SearchProductParameter) ([]models.Product, int, error) {
-	var products []models.Product
-	var totalCount int64
-
+// Every search filter lives here, so results, counts and facets are always computed over the same rows.
+//
+// It returns pointer of gorm.DB.
+func (r *ProductRepository) searchProductQuery(ctx context.Context, param models.SearchProductParameter) *gorm.DB {
 	query := r.Database.WithContext(ctx).Table("product").
-		Select("product.id, product.name, product.description, product.price, product.stock, product.category_id, product_category.name AS category").
 		Joins("JOIN product_category ON product.category_id = product_category.id")
 
 	// filtering
-	if param.Name != "" { // iphone --> iphone X, etc.
+	if param.Query != "" { // full-text over name and description, e.g. "iphone -case"
+		query = query.Where("product.search_vector @@ websearch_to_tsquery('simple', ?)", param.Query)
+	}
+
+	if param.Name != "" && param.Mode == models.SearchModeFuzzy { // ipohne --> iphone
+		query = query.Where("similarity(product.name, ?) >= ?", param.Name, param.SimilarityThreshold)
+	} else if param.Name != "" { // iphone --> iphone X, etc.
 		query = query.Where("product.name ILIKE ?", "%"+param.Name+"%")
 	}
 
@@ -186,30 +405,249 @@ func (r *ProductRepository) SearchProduct(ctx context.Context, param models.Sear
 		query = query.Where("product.price <= ?", param.MaxPrice)
 	}
 
-	// pagination
+	if len(param.CategoryIDs) > 0 {
+		query = query.Where("product.category_id IN ?", param.CategoryIDs)
+	}
+
+	if len(param.ExcludeCategoryIDs) > 0 {
+		query = query.Where("product.category_id NOT IN ?", param.ExcludeCategoryIDs)
+	}
+
+	if len(param.IDs) > 0 {
+		query = query.Where("product.id IN ?", param.IDs)
+	}
+
+	if len(param.ExcludeIDs) > 0 {
+		query = query.Where("product.id NOT IN ?", param.ExcludeIDs)
+	}
+
+	if param.InStock {
+		query = query.Where("product.stock > 0")
+	}
+
+	// ranges are inclusive of the lower bound only, so consecutive ranges never overlap
+	if !param.CreatedAfter.IsZero() {
+		query = query.Where("product.created_at >= ?", param.CreatedAfter)
+	}
+
+	if !param.CreatedBefore.IsZero() {
+		query = query.Where("product.created_at < ?", param.CreatedBefore)
+	}
+
+	if !param.UpdatedAfter.IsZero() {
+		query = query.Where("product.updated_at >= ?", param.UpdatedAfter)
+	}
+
+	if !param.UpdatedBefore.IsZero() {
+		query = query.Where("product.updated_at < ?", param.UpdatedBefore)
+	}
+
+	return query
+}
+
+// SearchProduct search product by given SearchProductParameter.
+//
+// Pages are addressed by param.Cursor when it is set (keyset pagination), otherwise by param.Page (offset pagination).
+// Either way the result carries cursors for the neighbouring pages.
+//
+// It returns pointer of models.SearchProductResult, and nil error when successful.
+// Otherwise, nil pointer of models.SearchProductResult, and error will be returned.
+func (r *ProductRepository) SearchProduct(ctx context.Context, param models.SearchProductParameter) (*models.SearchProductResult, error) {
+	sortKeys, err := searchProductSortKeys(param)
+	if err != nil {
+		return nil, err
+	}
+
+	direction := cursorDirectionNext
+	var cursorValues []interface{}
+	if param.Cursor != "" {
+		var err error
+		direction, cursorValues, err = decodeSearchProductCursor(param.Cursor, sortKeys)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	// dapetin total counts dari hasil query
-	query.Model(&models.Product{}).Count(&totalCount)
+	var totalCount int64
+	err = r.searchProductQuery(ctx, param).Count(&totalCount).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// computed sort keys are selected too, so cursors can be built from the rows
+	selectSQL := "product.id, product.name, product.description, product.price, product.stock, product.category_id, product.popularity, product.created_at, product.updated_at, product_category.name AS category"
+	var selectVars []interface{}
+	for _, sortKey := range sortKeys {
+		if len(sortKey.vars) > 0 {
+			selectSQL += fmt.Sprintf(", %s AS %s", sortKey.column.sql, sortKey.name)
+			selectVars = append(selectVars, sortKey.vars...)
+		}
+	}
+
+	query := r.searchProductQuery(ctx, param).Select(selectSQL, selectVars...)
+
+	// pagination
+	reverse := direction == cursorDirectionPrev
+	offset := 0
+	if param.Cursor != "" {
+		query = query.Where(searchProductKeysetCondition(sortKeys, cursorValues, reverse))
+	} else if param.Page > 1 {
+		offset = (param.Page - 1) * param.PageSize
+		query = query.Offset(offset)
+	}
+
+	// one extra row tells whether there is another page in the paging direction
+	query = query.Order(searchProductOrderClause(sortKeys, reverse)).Limit(param.PageSize + 1)
 
-	// default order by
-	if param.OrderBy == "" {
-		param.OrderBy = "product.name"
+	var rows []searchProductRow
+	err = query.Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	hasMore := len(rows) > param.PageSize
+	if hasMore {
+		rows = rows[:param.PageSize]
+	}
+
+	if reverse {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+
+	result := &models.SearchProductResult{
+		Products:   make([]models.Product, len(rows)),
+		TotalCount: int(totalCount),
+	}
+	for i := range rows {
+		result.Products[i] = rows[i].Product
+	}
+
+	if len(rows) == 0 {
+		return result, nil
+	}
+
+	hasNext, hasPrev := hasMore, offset > 0
+	if param.Cursor != "" {
+		// the row the cursor was made from lies on the side we came from
+		hasNext, hasPrev = hasMore || reverse, !reverse || hasMore
+	}
+
+	if hasNext {
+		result.NextCursor = encodeSearchProductCursor(cursorDirectionNext, sortKeys, &rows[len(rows)-1])
+	}
+
+	if hasPrev {
+		result.PrevCursor = encodeSearchProductCursor(cursorDirectionPrev, sortKeys, &rows[0])
+	}
+
+	return result, nil
+}
+
+// FindSimilarProductNames find similar product names by given term, threshold, and limit.
+//
+// It returns slice of string ordered by trigram similarity, and nil error when successful.
+// Otherwise, nil value of string slice, and error will be returned.
+func (r *ProductRepository) FindSimilarProductNames(ctx context.Context, term string, threshold float64, limit int) ([]string, error) {
+	var names []string
+	err := r.Database.WithContext(ctx).Table("product").
+		Select("name").
+		Where("similarity(name, ?) >= ?", term, threshold).
+		Group("name").
+		Order(clause.OrderBy{
+			Expression: clause.Expr{SQL: "MAX(similarity(name, ?)) DESC", Vars: []interface{}{term}},
+		}).
+		Limit(limit).
+		Pluck("name", &names).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return names, nil
+}
+
+// SearchProductCategoryFacets search product category facets by given SearchProductParameter.
+//
+// It returns slice of models.CategoryFacet, and nil error when successful.
+// Otherwise, nil value of models.CategoryFacet slice, and error will be returned.
+func (r *ProductRepository) SearchProductCategoryFacets(ctx context.Context, param models.SearchProductParameter) ([]models.CategoryFacet, error) {
+	var categoryFacets []models.CategoryFacet
+	err := r.searchProductQuery(ctx, param).
+		Select("product_category.id, product_category.name, COUNT(*) AS count").
+		Group("product_category.id, product_category.name").
+		Order("count DESC, product_category.name").
+		Scan(&categoryFacets).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return categoryFacets, nil
+}
+
+// SearchProductPriceFacets search product price facets by given SearchProductParameter, and slice of priceBuckets.
+//
+// priceBuckets are ascending bucket boundaries, [100, 500] gives the ranges <100, 100-500 and >=500.
+//
+// It returns slice of models.PriceRangeFacet, and nil error when successful.
+// Otherwise, nil value of models.PriceRangeFacet slice, and error will be returned.
+func (r *ProductRepository) SearchProductPriceFacets(ctx context.Context, param models.SearchProductParameter, priceBuckets []float64) ([]models.PriceRangeFacet, error) {
+	if len(priceBuckets) == 0 {
+		return []models.PriceRangeFacet{}, nil
+	}
+
+	var buckets []struct {
+		Bucket int
+		Count  int64
+	}
+	// one placeholder per boundary, a slice argument would be expanded into a row instead of an array
+	placeholders := make([]string, len(priceBuckets))
+	boundaries := make([]interface{}, len(priceBuckets))
+	for i, priceBucket := range priceBuckets {
+		placeholders[i] = "?"
+		boundaries[i] = priceBucket
 	}
 
-	if param.Sort == "" || (param.Sort != "ASC" && param.Sort != "DESC") {
-		param.Sort = "ASC"
+	err := r.searchProductQuery(ctx, param).
+		Select(fmt.Sprintf("width_bucket(product.price::float8, ARRAY[%s]::float8[]) AS bucket, COUNT(*) AS count", strings.Join(placeholders, ", ")), boundaries...).
+		Group("bucket").
+		Scan(&buckets).Error
+	if err != nil {
+		return nil, err
 	}
 
-	orderBy := fmt.Sprintf("%s %s", param.OrderBy, param.Sort)
-	query = query.Order(orderBy)
+	// bucket 0 is below the first boundary, bucket len(priceBuckets) is at or above the last one
+	priceFacets := make([]models.PriceRangeFacet, len(priceBuckets)+1)
+	for i := range priceFacets {
+		if i > 0 {
+			priceFacets[i].Min = &priceBuckets[i-1]
+		}
 
-	offset := (param.Page - 1) * param.PageSize
-	query = query.Limit(param.PageSize).Offset(offset)
+		if i < len(priceBuckets) {
+			priceFacets[i].Max = &priceBuckets[i]
+		}
+	}
 
-	err := query.Scan(&products).Error
+	for _, bucket := range buckets {
+		priceFacets[bucket.Bucket].Count = bucket.Count
+	}
+
+	return priceFacets, nil
+}
+
+// SearchProductStockFacet search product stock facet by given SearchProductParameter.
+//
+// It returns pointer of models.StockFacet, and nil error when successful.
+// Otherwise, nil pointer of models.StockFacet, and error will be returned.
+func (r *ProductRepository) SearchProductStockFacet(ctx context.Context, param models.SearchProductParameter) (*models.StockFacet, error) {
+	var stockFacet models.StockFacet
+	err := r.searchProductQuery(ctx, param).
+		Select("COUNT(*) FILTER (WHERE product.stock > 0) AS in_stock, COUNT(*) FILTER (WHERE product.stock <= 0) AS out_of_stock").
+		Scan(&stockFacet).Error
 	if err != nil {
-		return nil, 0, err
+		return nil, err
 	}
 
-	return products, int(totalCount), nil
+	return &stockFacet, nil
 }
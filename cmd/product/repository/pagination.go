@@ -0,0 +1,320 @@
+package repository
+
+import (
+	// golang package
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"productfc/models"
+	"strings"
+	"time"
+
+	// external package
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidSort   = errors.New("invalid sort")
+)
+
+const (
+	cursorDirectionNext = "next"
+	cursorDirectionPrev = "prev"
+)
+
+type sortValueKind int
+
+const (
+	sortValueString sortValueKind = iota
+	sortValueFloat
+	sortValueInt
+	sortValueTime
+)
+
+// searchProductRow is a search result row, with the computed relevance cursors may need.
+type searchProductRow struct {
+	models.Product
+	Relevance float64
+}
+
+type searchProductColumn struct {
+	sql   string
+	kind  sortValueKind
+	value func(row *searchProductRow) interface{}
+}
+
+// searchProductColumns are the whitelisted sort fields mapped to safe column expressions.
+var searchProductColumns = map[string]searchProductColumn{
+	models.SortFieldName: {
+		sql:   "product.name",
+		kind:  sortValueString,
+		value: func(row *searchProductRow) interface{} { return row.Name },
+	},
+	models.SortFieldPrice: {
+		sql:   "product.price",
+		kind:  sortValueFloat,
+		value: func(row *searchProductRow) interface{} { return row.Price },
+	},
+	models.SortFieldStock: {
+		sql:   "product.stock",
+		kind:  sortValueInt,
+		value: func(row *searchProductRow) interface{} { return row.Stock },
+	},
+	models.SortFieldCreatedAt: {
+		sql:   "product.created_at",
+		kind:  sortValueTime,
+		value: func(row *searchProductRow) interface{} { return row.CreatedAt },
+	},
+	models.SortFieldUpdatedAt: {
+		sql:   "product.updated_at",
+		kind:  sortValueTime,
+		value: func(row *searchProductRow) interface{} { return row.UpdatedAt },
+	},
+	models.SortFieldPopularity: {
+		sql:   "product.popularity",
+		kind:  sortValueInt,
+		value: func(row *searchProductRow) interface{} { return row.Popularity },
+	},
+}
+
+// searchProductIDColumn is always the last sort key, so every row has a unique position.
+var searchProductIDColumn = searchProductColumn{
+	sql:   "product.id",
+	kind:  sortValueInt,
+	value: func(row *searchProductRow) interface{} { return row.ID },
+}
+
+type searchProductSortKey struct {
+	name   string
+	column searchProductColumn
+	vars   []interface{}
+	desc   bool
+}
+
+// searchProductRelevanceKey search product relevance key by given SearchProductParameter.
+//
+// It returns pointer of searchProductSortKey, or nil when the search has nothing to rank by.
+func searchProductRelevanceKey(param models.SearchProductParameter) *searchProductSortKey {
+	sortKey := searchProductSortKey{
+		name: models.SortFieldRelevance,
+		column: searchProductColumn{
+			kind:  sortValueFloat,
+			value: func(row *searchProductRow) interface{} { return row.Relevance },
+		},
+	}
+
+	switch {
+	case param.Query != "":
+		sortKey.column.sql = "ts_rank(product.search_vector, websearch_to_tsquery('simple', ?))"
+		sortKey.vars = []interface{}{param.Query}
+	case param.Name != "" && param.Mode == models.SearchModeFuzzy:
+		sortKey.column.sql = "similarity(product.name, ?)"
+		sortKey.vars = []interface{}{param.Name}
+	default:
+		return nil
+	}
+
+	return &sortKey
+}
+
+// searchProductSortKeys search product sort keys by given SearchProductParameter.
+//
+// It returns slice of searchProductSortKey ending with the product id tiebreaker, and nil error when successful.
+// Otherwise, nil slice, and ErrInvalidSort will be returned.
+func searchProductSortKeys(param models.SearchProductParameter) ([]searchProductSortKey, error) {
+	sortFields := param.SortFields
+	if len(sortFields) == 0 {
+		// best match first when searching, then by name
+		if searchProductRelevanceKey(param) != nil {
+			sortFields = append(sortFields, models.SortField{Field: models.SortFieldRelevance, Desc: true})
+		}
+
+		sortFields = append(sortFields, models.SortField{Field: models.SortFieldName})
+	}
+
+	sortKeys := make([]searchProductSortKey, 0, len(sortFields)+1)
+	for _, sortField := range sortFields {
+		if sortField.Field == models.SortFieldRelevance {
+			relevanceKey := searchProductRelevanceKey(param)
+			if relevanceKey == nil {
+				return nil, ErrInvalidSort
+			}
+
+			relevanceKey.desc = sortField.Desc
+			sortKeys = append(sortKeys, *relevanceKey)
+
+			continue
+		}
+
+		column, ok := searchProductColumns[sortField.Field]
+		if !ok {
+			return nil, ErrInvalidSort
+		}
+
+		sortKeys = append(sortKeys, searchProductSortKey{name: sortField.Field, column: column, desc: sortField.Desc})
+	}
+
+	sortKeys = append(sortKeys, searchProductSortKey{name: "id", column: searchProductIDColumn, desc: sortFields[len(sortFields)-1].Desc})
+
+	return sortKeys, nil
+}
+
+// searchProductSortSignature search product sort signature by given slice of searchProductSortKey.
+//
+// A cursor remembers the signature of the ordering it was made for, so it can't be replayed against another one.
+//
+// It returns string.
+func searchProductSortSignature(sortKeys []searchProductSortKey) string {
+	parts := make([]string, len(sortKeys))
+	for i, sortKey := range sortKeys {
+		direction := "asc"
+		if sortKey.desc {
+			direction = "desc"
+		}
+
+		parts[i] = sortKey.name + ":" + direction
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// searchProductOrderClause search product order clause by given slice of searchProductSortKey, and reverse.
+//
+// It returns clause.OrderBy.
+func searchProductOrderClause(sortKeys []searchProductSortKey, reverse bool) clause.OrderBy {
+	orderSQL := make([]string, len(sortKeys))
+	var orderVars []interface{}
+	for i, sortKey := range sortKeys {
+		direction := "ASC"
+		if sortKey.desc != reverse {
+			direction = "DESC"
+		}
+
+		orderSQL[i] = fmt.Sprintf("%s %s", sortKey.column.sql, direction)
+		orderVars = append(orderVars, sortKey.vars...)
+	}
+
+	return clause.OrderBy{
+		Expression: clause.Expr{SQL: strings.Join(orderSQL, ", "), Vars: orderVars},
+	}
+}
+
+// searchProductKeysetCondition search product keyset condition by given slice of searchProductSortKey, slice of values, and reverse.
+//
+// For keys (a, b, id) it builds "a > ? OR (a = ? AND b > ?) OR (a = ? AND b = ? AND id > ?)",
+// with each comparison flipped for descending keys and again when paging backwards.
+//
+// It returns clause.Expr.
+func searchProductKeysetCondition(sortKeys []searchProductSortKey, values []interface{}, reverse bool) clause.Expr {
+	var conditions []string
+	var vars []interface{}
+	for i, sortKey := range sortKeys {
+		var parts []string
+		for j := 0; j < i; j++ {
+			parts = append(parts, fmt.Sprintf("%s = ?", sortKeys[j].column.sql))
+			vars = append(vars, sortKeys[j].vars...)
+			vars = append(vars, values[j])
+		}
+
+		operator := ">"
+		if sortKey.desc != reverse {
+			operator = "<"
+		}
+
+		parts = append(parts, fmt.Sprintf("%s %s ?", sortKey.column.sql, operator))
+		vars = append(vars, sortKey.vars...)
+		vars = append(vars, values[i])
+
+		conditions = append(conditions, "("+strings.Join(parts, " AND ")+")")
+	}
+
+	return clause.Expr{SQL: "(" + strings.Join(conditions, " OR ") + ")", Vars: vars}
+}
+
+type searchProductCursor struct {
+	Direction string            `json:"d"`
+	Sort      string            `json:"s"`
+	Values    []json.RawMessage `json:"v"`
+}
+
+// encodeSearchProductCursor encode search product cursor by given direction, slice of searchProductSortKey, and row pointer of searchProductRow.
+//
+// It returns string of opaque cursor.
+func encodeSearchProductCursor(direction string, sortKeys []searchProductSortKey, row *searchProductRow) string {
+	values := make([]json.RawMessage, len(sortKeys))
+	for i, sortKey := range sortKeys {
+		values[i], _ = json.Marshal(sortKey.column.value(row))
+	}
+
+	cursorJSON, _ := json.Marshal(searchProductCursor{
+		Direction: direction,
+		Sort:      searchProductSortSignature(sortKeys),
+		Values:    values,
+	})
+
+	return base64.RawURLEncoding.EncodeToString(cursorJSON)
+}
+
+// decodeSearchProductCursor decode search product cursor by given cursor, and slice of searchProductSortKey.
+//
+// It returns string of direction, slice of sort key values, and nil error when successful.
+// Otherwise, empty string, nil slice, and ErrInvalidCursor will be returned.
+func decodeSearchProductCursor(cursor string, sortKeys []searchProductSortKey) (string, []interface{}, error) {
+	cursorJSON, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", nil, ErrInvalidCursor
+	}
+
+	var decoded searchProductCursor
+	err = json.Unmarshal(cursorJSON, &decoded)
+	if err != nil {
+		return "", nil, ErrInvalidCursor
+	}
+
+	if decoded.Direction != cursorDirectionNext && decoded.Direction != cursorDirectionPrev {
+		return "", nil, ErrInvalidCursor
+	}
+
+	// cursor was issued for another ordering
+	if decoded.Sort != searchProductSortSignature(sortKeys) || len(decoded.Values) != len(sortKeys) {
+		return "", nil, ErrInvalidCursor
+	}
+
+	values := make([]interface{}, len(sortKeys))
+	for i, sortKey := range sortKeys {
+		values[i], err = decodeSortValue(sortKey.column.kind, decoded.Values[i])
+		if err != nil {
+			return "", nil, ErrInvalidCursor
+		}
+	}
+
+	return decoded.Direction, values, nil
+}
+
+// decodeSortValue decode sort value by given kind, and raw json.RawMessage.
+//
+// It returns interface of the typed value, and nil error when successful.
+// Otherwise, nil interface, and error will be returned.
+func decodeSortValue(kind sortValueKind, raw json.RawMessage) (interface{}, error) {
+	var err error
+	switch kind {
+	case sortValueFloat:
+		var value float64
+		err = json.Unmarshal(raw, &value)
+		return value, err
+	case sortValueInt:
+		var value int64
+		err = json.Unmarshal(raw, &value)
+		return value, err
+	case sortValueTime:
+		var value time.Time
+		err = json.Unmarshal(raw, &value)
+		return value, err
+	default:
+		var value string
+		err = json.Unmarshal(raw, &value)
+		return value, err
+	}
+}
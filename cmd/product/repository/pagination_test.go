@@ -0,0 +1,167 @@
+package repository
+
+import (
+	// golang package
+	"encoding/base64"
+	"errors"
+	"productfc/models"
+	"reflect"
+	"testing"
+	"time"
+
+	// external package
+	"gorm.io/gorm/clause"
+)
+
+func TestSearchProductSortKeys(t *testing.T) {
+	tests := []struct {
+		name  string
+		param models.SearchProductParameter
+		want  string
+		err   error
+	}{
+		{"default", models.SearchProductParameter{}, "name:asc,id:asc", nil},
+		{"default when searching", models.SearchProductParameter{Query: "phone"}, "relevance:desc,name:asc,id:asc", nil},
+		{"default when fuzzy", models.SearchProductParameter{Name: "phne", Mode: models.SearchModeFuzzy}, "relevance:desc,name:asc,id:asc", nil},
+		{"id follows the last key", models.SearchProductParameter{SortFields: []models.SortField{{Field: models.SortFieldName}, {Field: models.SortFieldPrice, Desc: true}}}, "name:asc,price:desc,id:desc", nil},
+		{"unknown field", models.SearchProductParameter{SortFields: []models.SortField{{Field: "secret"}}}, "", ErrInvalidSort},
+		{"relevance without a query", models.SearchProductParameter{SortFields: []models.SortField{{Field: models.SortFieldRelevance}}}, "", ErrInvalidSort},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sortKeys, err := searchProductSortKeys(tt.param)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("searchProductSortKeys() got error %v, want %v", err, tt.err)
+			}
+
+			if got := searchProductSortSignature(sortKeys); err == nil && got != tt.want {
+				t.Fatalf("searchProductSortKeys() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchProductKeysetCondition(t *testing.T) {
+	sortKeys, err := searchProductSortKeys(models.SearchProductParameter{
+		SortFields: []models.SortField{{Field: models.SortFieldPrice, Desc: true}, {Field: models.SortFieldName}},
+	})
+	if err != nil {
+		t.Fatalf("searchProductSortKeys() got error %v", err)
+	}
+
+	values := []interface{}{100.5, "Phone", int64(7)}
+
+	tests := []struct {
+		name    string
+		reverse bool
+		want    string
+	}{
+		{"next", false, "((product.price < ?) OR (product.price = ? AND product.name > ?) OR (product.price = ? AND product.name = ? AND product.id > ?))"},
+		{"prev", true, "((product.price > ?) OR (product.price = ? AND product.name < ?) OR (product.price = ? AND product.name = ? AND product.id < ?))"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			condition := searchProductKeysetCondition(sortKeys, values, tt.reverse)
+			if condition.SQL != tt.want {
+				t.Fatalf("searchProductKeysetCondition() = %s, want %s", condition.SQL, tt.want)
+			}
+
+			wantVars := []interface{}{100.5, 100.5, "Phone", 100.5, "Phone", int64(7)}
+			if !reflect.DeepEqual(condition.Vars, wantVars) {
+				t.Fatalf("searchProductKeysetCondition() vars = %v, want %v", condition.Vars, wantVars)
+			}
+		})
+	}
+
+	// the rows are read the other way when paging backwards
+	order := searchProductOrderClause(sortKeys, true).Expression.(clause.Expr)
+	if want := "product.price ASC, product.name DESC, product.id DESC"; order.SQL != want {
+		t.Fatalf("searchProductOrderClause() = %s, want %s", order.SQL, want)
+	}
+}
+
+func TestSearchProductCursor(t *testing.T) {
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+	row := &searchProductRow{
+		Product:   models.Product{ID: 7, Name: "Phone", Price: 100.5, Stock: 3, Popularity: 9, CreatedAt: createdAt, UpdatedAt: createdAt},
+		Relevance: 0.25,
+	}
+
+	tests := []struct {
+		field string
+		want  interface{}
+	}{
+		{models.SortFieldName, "Phone"},
+		{models.SortFieldPrice, 100.5},
+		{models.SortFieldStock, int64(3)},
+		{models.SortFieldCreatedAt, createdAt},
+		{models.SortFieldUpdatedAt, createdAt},
+		{models.SortFieldPopularity, int64(9)},
+		{models.SortFieldRelevance, 0.25},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			param := models.SearchProductParameter{Query: "phone", SortFields: []models.SortField{{Field: tt.field, Desc: true}}}
+			sortKeys, err := searchProductSortKeys(param)
+			if err != nil {
+				t.Fatalf("searchProductSortKeys() got error %v", err)
+			}
+
+			cursor := encodeSearchProductCursor(cursorDirectionPrev, sortKeys, row)
+
+			direction, values, err := decodeSearchProductCursor(cursor, sortKeys)
+			if err != nil {
+				t.Fatalf("decodeSearchProductCursor() got error %v", err)
+			}
+
+			if want := []interface{}{tt.want, int64(7)}; direction != cursorDirectionPrev || !reflect.DeepEqual(values, want) {
+				t.Fatalf("decodeSearchProductCursor() = %s %v, want prev %v", direction, values, want)
+			}
+
+			// a cursor only pages the ordering it was made for
+			param.SortFields[0].Desc = false
+			otherSortKeys, _ := searchProductSortKeys(param)
+			if _, _, err := decodeSearchProductCursor(cursor, otherSortKeys); !errors.Is(err, ErrInvalidCursor) {
+				t.Fatalf("decodeSearchProductCursor() for another ordering got error %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
+
+func TestDecodeSearchProductCursorInvalid(t *testing.T) {
+	sortKeys, err := searchProductSortKeys(models.SearchProductParameter{SortFields: []models.SortField{{Field: models.SortFieldPrice}}})
+	if err != nil {
+		t.Fatalf("searchProductSortKeys() got error %v", err)
+	}
+
+	encode := func(cursorJSON string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(cursorJSON))
+	}
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"not base64", "!!!"},
+		{"not json", encode("price")},
+		{"unknown direction", encode(`{"d":"up","s":"price:asc,id:asc","v":[1,1]}`)},
+		{"missing value", encode(`{"d":"next","s":"price:asc,id:asc","v":[1]}`)},
+		{"wrong type", encode(`{"d":"next","s":"price:asc,id:asc","v":["1",1]}`)},
+		{"fractional id", encode(`{"d":"next","s":"price:asc,id:asc","v":[1,1.5]}`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := decodeSearchProductCursor(tt.cursor, sortKeys); !errors.Is(err, ErrInvalidCursor) {
+				t.Fatalf("decodeSearchProductCursor() got error %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+
+	if _, _, err := decodeSearchProductCursor(encode(`{"d":"next","s":"price:asc,id:asc","v":[1,1]}`), sortKeys); err != nil {
+		t.Fatalf("decodeSearchProductCursor() of a valid cursor got error %v", err)
+	}
+}
@@ -3,9 +3,14 @@ package repository
 import (
 	// golang package
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"productfc/models"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	// external package
@@ -15,32 +20,154 @@ import (
 var (
 	cacheKeyProductInfo         = "product:%d" // format: product:{productID} product:1
 	cacheKeyProductCategoryInfo = "product_category:%d"
+	cacheKeySearchProduct       = "product_search:%d:%d:%s" // format: product_search:{productGeneration}:{productCategoryGeneration}:{paramHash}
+
+	// generation counters, bumped on every write so cached search results are never served after an edit
+	cacheKeyProductGeneration         = "generation:product"
+	cacheKeyProductCategoryGeneration = "generation:product_category"
+
+	// autocomplete, members of the lex index are "{lowercased name}\x00{type}\x00{id}\x00{name}" all scored 0
+	cacheKeyAutocompleteIndex      = "autocomplete:index"
+	cacheKeyAutocompletePopularity = "autocomplete:popularity" // members are "{type}:{id}"
+
+	cacheTTLProductInfo         = 10 * time.Minute
+	cacheTTLProductCategoryInfo = 1 * time.Minute
+
+	// how long an expired product entry is kept around to be served while it is revalidated
+	cacheStaleTTLProductInfo = 1 * time.Hour
 )
 
+// setIfNewerScript stores ARGV[1] under KEYS[1] with a PX of ARGV[3] unless the cached entry
+// carries a row version newer than ARGV[2], so a slow writer can never overwrite a fresher entity.
+// Entries without a row version are always overwritten.
+var setIfNewerScript = redis.NewScript(`
+local current = redis.call('GET', KEYS[1])
+if current then
+	local ok, entry = pcall(cjson.decode, current)
+	if ok and type(entry) == 'table' and tonumber(entry.row_version) and tonumber(entry.row_version) > tonumber(ARGV[2]) then
+		return 0
+	end
+end
+
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[3])
+return 1
+`)
+
+type productCacheEntry struct {
+	Product    models.Product `json:"product"`
+	FreshUntil time.Time      `json:"fresh_until"`
+	Version    int64          `json:"row_version"`
+}
+
+type productCategoryCacheEntry struct {
+	ProductCategory models.ProductCategory `json:"product_category"`
+	Version         int64                  `json:"row_version"`
+}
+
+// newProductCacheEntryJSON new product cache entry json by given product pointer of models.Product.
+//
+// The version is the product's row version, bumped by every write in DB, so the newest write always wins in redis.
+//
+// It returns slice of byte, int64 of version, and nil error when successful.
+// Otherwise, nil slice of byte, empty int64, and error will be returned.
+func newProductCacheEntryJSON(product *models.Product) ([]byte, int64, error) {
+	entryJSON, err := json.Marshal(productCacheEntry{
+		Product:    *product,
+		FreshUntil: time.Now().Add(cacheTTLProductInfo),
+		Version:    product.Version,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return entryJSON, product.Version, nil
+}
+
+// newProductCategoryCacheEntryJSON new product category cache entry json by given productCategory pointer of models.ProductCategory.
+//
+// It returns slice of byte, int64 of version, and nil error when successful.
+// Otherwise, nil slice of byte, empty int64, and error will be returned.
+func newProductCategoryCacheEntryJSON(productCategory *models.ProductCategory) ([]byte, int64, error) {
+	entryJSON, err := json.Marshal(productCategoryCacheEntry{
+		ProductCategory: *productCategory,
+		Version:         productCategory.Version,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return entryJSON, productCategory.Version, nil
+}
+
 // GetProductByIDFromRedis get product by id from redis by given productID.
 //
-// It returns pointer of models.Product, and nil error when successful.
-// Otherwise, nil pointer of models.Product, and error will be returned.
-func (r *ProductRepository) GetProductByIDFromRedis(ctx context.Context, productID int64) (*models.Product, error) {
+// It returns pointer of models.Product, bool, and nil error when successful. The bool reports
+// whether the entry is past its freshness window and should be revalidated.
+// Otherwise, nil pointer of models.Product, false, and error will be returned.
+func (r *ProductRepository) GetProductByIDFromRedis(ctx context.Context, productID int64) (*models.Product, bool, error) {
 	cacheKey := fmt.Sprintf(cacheKeyProductInfo, productID)
 
-	var product models.Product
+	var entry productCacheEntry
 	productStr, err := r.Redis.Get(ctx, cacheKey).Result()
 	if err != nil {
 		if err == redis.Nil {
-			return &models.Product{}, nil
+			return &models.Product{}, false, nil
 		}
 
-		return nil, err
+		return nil, false, err
 	}
 
 	// unmarshal
-	err = json.Unmarshal([]byte(productStr), &product)
+	err = json.Unmarshal([]byte(productStr), &entry)
+	if err != nil {
+		return nil, false, err
+	}
+
+	entry.Product.Version = entry.Version // not part of the product json
+
+	return &entry.Product, time.Now().After(entry.FreshUntil), nil
+}
+
+// GetProductsByIDsFromRedis get products by ids from redis by given slice of productIDs with a single MGET.
+//
+// Missing and expired entries are left out so the caller can reload them.
+//
+// It returns map of product id to models.Product, and nil error when successful.
+// Otherwise, nil map, and error will be returned.
+func (r *ProductRepository) GetProductsByIDsFromRedis(ctx context.Context, productIDs []int64) (map[int64]models.Product, error) {
+	cacheKeys := make([]string, len(productIDs))
+	for i, productID := range productIDs {
+		cacheKeys[i] = fmt.Sprintf(cacheKeyProductInfo, productID)
+	}
+
+	values, err := r.Redis.MGet(ctx, cacheKeys...).Result()
 	if err != nil {
 		return nil, err
 	}
 
-	return &product, nil
+	products := make(map[int64]models.Product, len(productIDs))
+	for _, value := range values {
+		productStr, ok := value.(string)
+		if !ok { // cache miss
+			continue
+		}
+
+		var entry productCacheEntry
+		err = json.Unmarshal([]byte(productStr), &entry)
+		if err != nil {
+			return nil, err
+		}
+
+		// expired, or a tombstone left by DeleteProductByIDFromRedis
+		if entry.Product.ID == 0 || time.Now().After(entry.FreshUntil) {
+			continue
+		}
+
+		entry.Product.Version = entry.Version
+		products[entry.Product.ID] = entry.Product
+	}
+
+	return products, nil
 }
 
 // GetProductCategoryByIDFromRedis get product category by id from redis by given productCategoryID.
@@ -50,7 +177,7 @@ func (r *ProductRepository) GetProductByIDFromRedis(ctx context.Context, product
 func (r *ProductRepository) GetProductCategoryByIDFromRedis(ctx context.Context, productCategoryID int) (*models.ProductCategory, error) {
 	cacheKey := fmt.Sprintf(cacheKeyProductCategoryInfo, productCategoryID)
 
-	var productCategory models.ProductCategory
+	var entry productCategoryCacheEntry
 	productCategoryStr, err := r.Redis.Get(ctx, cacheKey).Result()
 	if err != nil {
 		if err == redis.Nil {
@@ -60,27 +187,53 @@ func (r *ProductRepository) GetProductCategoryByIDFromRedis(ctx context.Context,
 		return nil, err
 	}
 
-	err = json.Unmarshal([]byte(productCategoryStr), &productCategory)
+	err = json.Unmarshal([]byte(productCategoryStr), &entry)
 	if err != nil {
 		return nil, err
 	}
 
-	return &productCategory, nil
+	entry.ProductCategory.Version = entry.Version // not part of the product category json
+
+	return &entry.ProductCategory, nil
 }
 
 // SetProductByID set product by id by given product pointer of models.Product, and productID.
 //
+// The entry is only written when it is at least as new as the cached one.
+//
 // It returns nil error when successful.
 // Otherwise, error will be returned.
 func (r *ProductRepository) SetProductByID(ctx context.Context, product *models.Product, productID int64) error {
 	cacheKey := fmt.Sprintf(cacheKeyProductInfo, productID)
-	fmt.Println(cacheKey)
-	productJSON, err := json.Marshal(product)
+	productJSON, version, err := newProductCacheEntryJSON(product)
 	if err != nil {
 		return err
 	}
 
-	err = r.Redis.SetEx(ctx, cacheKey, productJSON, 10*time.Minute).Err()
+	ttl := cacheTTLProductInfo + cacheStaleTTLProductInfo
+	err = setIfNewerScript.Run(ctx, r.Redis, []string{cacheKey}, productJSON, version, ttl.Milliseconds()).Err()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteProductByIDFromRedis delete product by id from redis by given productID, and version of the deleted row.
+//
+// Instead of a plain DEL it leaves a short-lived tombstone versioned after the deleted row,
+// so a reader that loaded the product before it was deleted can't put it back.
+//
+// It returns nil error when successful.
+// Otherwise, error will be returned.
+func (r *ProductRepository) DeleteProductByIDFromRedis(ctx context.Context, productID int64, version int64) error {
+	cacheKey := fmt.Sprintf(cacheKeyProductInfo, productID)
+	tombstoneJSON, tombstoneVersion, err := newProductCacheEntryJSON(&models.Product{Version: version + 1})
+	if err != nil {
+		return err
+	}
+
+	err = setIfNewerScript.Run(ctx, r.Redis, []string{cacheKey}, tombstoneJSON, tombstoneVersion, cacheTTLProductInfo.Milliseconds()).Err()
 	if err != nil {
 		return err
 	}
@@ -90,20 +243,377 @@ func (r *ProductRepository) SetProductByID(ctx context.Context, product *models.
 
 // SetProductCategoryByID set product category by id by given productCategory pointer of models.ProductCategory, and productCategoryID.
 //
+// The entry is only written when it is at least as new as the cached one.
+//
 // It returns nil error when successful.
 // Otherwise, error will be returned.
 func (r *ProductRepository) SetProductCategoryByID(ctx context.Context, productCategory *models.ProductCategory, productCategoryID int) error {
 	cacheKey := fmt.Sprintf(cacheKeyProductCategoryInfo, productCategoryID)
 
-	productCategoryJSON, err := json.Marshal(productCategory)
+	productCategoryJSON, version, err := newProductCategoryCacheEntryJSON(productCategory)
 	if err != nil {
 		return err
 	}
 
-	err = r.Redis.SetEx(ctx, cacheKey, productCategoryJSON, 1*time.Minute).Err()
+	err = setIfNewerScript.Run(ctx, r.Redis, []string{cacheKey}, productCategoryJSON, version, cacheTTLProductCategoryInfo.Milliseconds()).Err()
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// DeleteProductCategoryByIDFromRedis delete product category by id from redis by given productCategoryID, and version
+// of the deleted row.
+//
+// It returns nil error when successful.
+// Otherwise, error will be returned.
+func (r *ProductRepository) DeleteProductCategoryByIDFromRedis(ctx context.Context, productCategoryID int, version int64) error {
+	cacheKey := fmt.Sprintf(cacheKeyProductCategoryInfo, productCategoryID)
+	tombstoneJSON, tombstoneVersion, err := newProductCategoryCacheEntryJSON(&models.ProductCategory{Version: version + 1})
+	if err != nil {
+		return err
+	}
+
+	err = setIfNewerScript.Run(ctx, r.Redis, []string{cacheKey}, tombstoneJSON, tombstoneVersion, cacheTTLProductCategoryInfo.Milliseconds()).Err()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SetProducts set products by given slice of models.Product in a single pipeline.
+//
+// It returns nil error when successful.
+// Otherwise, error will be returned.
+func (r *ProductRepository) SetProducts(ctx context.Context, products []models.Product) error {
+	ttl := cacheTTLProductInfo + cacheStaleTTLProductInfo
+
+	pipe := r.Redis.Pipeline()
+	for i := range products {
+		productJSON, version, err := newProductCacheEntryJSON(&products[i])
+		if err != nil {
+			return err
+		}
+
+		setIfNewerScript.Eval(ctx, pipe, []string{fmt.Sprintf(cacheKeyProductInfo, products[i].ID)}, productJSON, version, ttl.Milliseconds())
+	}
+
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SetProductCategories set product categories by given slice of models.ProductCategory in a single pipeline.
+//
+// It returns nil error when successful.
+// Otherwise, error will be returned.
+func (r *ProductRepository) SetProductCategories(ctx context.Context, productCategories []models.ProductCategory) error {
+	pipe := r.Redis.Pipeline()
+	for i := range productCategories {
+		productCategoryJSON, version, err := newProductCategoryCacheEntryJSON(&productCategories[i])
+		if err != nil {
+			return err
+		}
+
+		setIfNewerScript.Eval(ctx, pipe, []string{fmt.Sprintf(cacheKeyProductCategoryInfo, productCategories[i].ID)}, productCategoryJSON, version, cacheTTLProductCategoryInfo.Milliseconds())
+	}
+
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// normalizeSearchProductParameter normalize search product parameter by given param.
+//
+// It returns models.SearchProductParameter in its canonical form, so equivalent searches share one cache entry.
+func normalizeSearchProductParameter(param models.SearchProductParameter) models.SearchProductParameter {
+	param.Query = strings.ToLower(strings.TrimSpace(param.Query)) // the simple text search config is case-insensitive
+	param.Name = strings.ToLower(strings.TrimSpace(param.Name))   // name is matched with ILIKE
+	param.Category = strings.TrimSpace(param.Category)
+	param.Mode = strings.ToLower(strings.TrimSpace(param.Mode))
+
+	// id filters are sets, ids=2,1 and ids=1,2 share an entry
+	param.CategoryIDs = sortedIDs(param.CategoryIDs)
+	param.ExcludeCategoryIDs = sortedIDs(param.ExcludeCategoryIDs)
+	param.IDs = sortedIDs(param.IDs)
+	param.ExcludeIDs = sortedIDs(param.ExcludeIDs)
+
+	param.CreatedAfter = param.CreatedAfter.UTC()
+	param.CreatedBefore = param.CreatedBefore.UTC()
+	param.UpdatedAfter = param.UpdatedAfter.UTC()
+	param.UpdatedBefore = param.UpdatedBefore.UTC()
+
+	return param
+}
+
+// sortedIDs sorted ids by given slice of ids.
+//
+// It returns sorted copy of ids without duplicates.
+func sortedIDs(ids []int64) []int64 {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	sorted := slices.Clone(ids)
+	slices.Sort(sorted)
+
+	return slices.Compact(sorted)
+}
+
+// searchProductCacheKey search product cache key by given productGeneration, productCategoryGeneration, and param.
+//
+// It returns string.
+func searchProductCacheKey(productGeneration int64, productCategoryGeneration int64, param models.SearchProductParameter) string {
+	// struct fields are always marshalled in declaration order
+	paramJSON, _ := json.Marshal(normalizeSearchProductParameter(param))
+	hash := sha256.Sum256(paramJSON)
+
+	return fmt.Sprintf(cacheKeySearchProduct, productGeneration, productCategoryGeneration, hex.EncodeToString(hash[:]))
+}
+
+// GetSearchGenerations get search generations.
+//
+// It returns int64 of product generation, int64 of product category generation, and nil error when successful.
+// Otherwise, empty int64, empty int64, and error will be returned.
+func (r *ProductRepository) GetSearchGenerations(ctx context.Context) (int64, int64, error) {
+	values, err := r.Redis.MGet(ctx, cacheKeyProductGeneration, cacheKeyProductCategoryGeneration).Result()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	generations := make([]int64, len(values))
+	for i, value := range values {
+		valueStr, ok := value.(string)
+		if !ok { // not bumped yet
+			continue
+		}
+
+		generations[i], err = strconv.ParseInt(valueStr, 10, 64)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+
+	return generations[0], generations[1], nil
+}
+
+// IncrProductGeneration incr product generation.
+//
+// It returns nil error when successful.
+// Otherwise, error will be returned.
+func (r *ProductRepository) IncrProductGeneration(ctx context.Context) error {
+	return r.Redis.Incr(ctx, cacheKeyProductGeneration).Err()
+}
+
+// IncrProductCategoryGeneration incr product category generation.
+//
+// It returns nil error when successful.
+// Otherwise, error will be returned.
+func (r *ProductRepository) IncrProductCategoryGeneration(ctx context.Context) error {
+	return r.Redis.Incr(ctx, cacheKeyProductCategoryGeneration).Err()
+}
+
+// GetSearchProductFromRedis get search product from redis by given productGeneration, productCategoryGeneration, and param.
+//
+// It returns pointer of models.SearchProductResult, true, and nil error when the result is cached.
+// It returns nil pointer of models.SearchProductResult, false, and nil error on cache miss.
+// Otherwise, nil pointer of models.SearchProductResult, false, and error will be returned.
+func (r *ProductRepository) GetSearchProductFromRedis(ctx context.Context, productGeneration int64, productCategoryGeneration int64, param models.SearchProductParameter) (*models.SearchProductResult, bool, error) {
+	cacheKey := searchProductCacheKey(productGeneration, productCategoryGeneration, param)
+
+	resultStr, err := r.Redis.Get(ctx, cacheKey).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, false, nil
+		}
+
+		return nil, false, err
+	}
+
+	var result models.SearchProductResult
+	err = json.Unmarshal([]byte(resultStr), &result)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &result, true, nil
+}
+
+// SetSearchProduct set search product by given productGeneration, productCategoryGeneration, param, result pointer of models.SearchProductResult, and ttl.
+//
+// It returns nil error when successful.
+// Otherwise, error will be returned.
+func (r *ProductRepository) SetSearchProduct(ctx context.Context, productGeneration int64, productCategoryGeneration int64, param models.SearchProductParameter, result *models.SearchProductResult, ttl time.Duration) error {
+	cacheKey := searchProductCacheKey(productGeneration, productCategoryGeneration, param)
+
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	err = r.Redis.SetEx(ctx, cacheKey, resultJSON, ttl).Err()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// autocompleteMember autocomplete member by given suggestion.
+//
+// It returns string.
+func autocompleteMember(suggestion models.AutocompleteSuggestion) string {
+	return strings.Join([]string{strings.ToLower(suggestion.Name), suggestion.Type, strconv.FormatInt(suggestion.ID, 10), suggestion.Name}, "\x00")
+}
+
+// autocompletePopularityMember autocomplete popularity member by given suggestion.
+//
+// It returns string.
+func autocompletePopularityMember(suggestion models.AutocompleteSuggestion) string {
+	return fmt.Sprintf("%s:%d", suggestion.Type, suggestion.ID)
+}
+
+// parseAutocompleteMember parse autocomplete member by given member.
+//
+// It returns models.AutocompleteSuggestion, and true when successful.
+// Otherwise, empty models.AutocompleteSuggestion, and false will be returned.
+func parseAutocompleteMember(member string) (models.AutocompleteSuggestion, bool) {
+	parts := strings.SplitN(member, "\x00", 4)
+	if len(parts) != 4 {
+		return models.AutocompleteSuggestion{}, false
+	}
+
+	id, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return models.AutocompleteSuggestion{}, false
+	}
+
+	return models.AutocompleteSuggestion{
+		Type: parts[1],
+		ID:   id,
+		Name: parts[3],
+	}, true
+}
+
+// AddAutocompleteSuggestion add autocomplete suggestion by given suggestion.
+//
+// It returns nil error when successful.
+// Otherwise, error will be returned.
+func (r *ProductRepository) AddAutocompleteSuggestion(ctx context.Context, suggestion models.AutocompleteSuggestion) error {
+	return r.Redis.ZAdd(ctx, cacheKeyAutocompleteIndex, redis.Z{Member: autocompleteMember(suggestion)}).Err()
+}
+
+// RemoveAutocompleteSuggestion remove autocomplete suggestion by given suggestion.
+//
+// It returns nil error when successful.
+// Otherwise, error will be returned.
+func (r *ProductRepository) RemoveAutocompleteSuggestion(ctx context.Context, suggestion models.AutocompleteSuggestion, removePopularity bool) error {
+	pipe := r.Redis.Pipeline()
+	pipe.ZRem(ctx, cacheKeyAutocompleteIndex, autocompleteMember(suggestion))
+	if removePopularity {
+		pipe.ZRem(ctx, cacheKeyAutocompletePopularity, autocompletePopularityMember(suggestion))
+	}
+
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// IncrAutocompletePopularity incr autocomplete popularity by given suggestion, and delta.
+//
+// It returns nil error when successful.
+// Otherwise, error will be returned.
+func (r *ProductRepository) IncrAutocompletePopularity(ctx context.Context, suggestion models.AutocompleteSuggestion, delta float64) error {
+	return r.Redis.ZIncrBy(ctx, cacheKeyAutocompletePopularity, delta, autocompletePopularityMember(suggestion)).Err()
+}
+
+// ReplaceAutocompleteSuggestions replace autocomplete suggestions by given slice of models.AutocompleteSuggestion.
+//
+// The whole index is swapped in one transaction, so readers never see it half built.
+//
+// It returns nil error when successful.
+// Otherwise, error will be returned.
+func (r *ProductRepository) ReplaceAutocompleteSuggestions(ctx context.Context, suggestions []models.AutocompleteSuggestion) error {
+	members := make([]redis.Z, 0, len(suggestions))
+	popularities := make([]redis.Z, 0, len(suggestions))
+	for _, suggestion := range suggestions {
+		members = append(members, redis.Z{Member: autocompleteMember(suggestion)})
+		if suggestion.Popularity > 0 {
+			popularities = append(popularities, redis.Z{Score: suggestion.Popularity, Member: autocompletePopularityMember(suggestion)})
+		}
+	}
+
+	pipe := r.Redis.TxPipeline()
+	pipe.Del(ctx, cacheKeyAutocompleteIndex, cacheKeyAutocompletePopularity)
+	if len(members) > 0 {
+		pipe.ZAdd(ctx, cacheKeyAutocompleteIndex, members...)
+	}
+
+	if len(popularities) > 0 {
+		pipe.ZAdd(ctx, cacheKeyAutocompletePopularity, popularities...)
+	}
+
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetAutocompleteSuggestions get autocomplete suggestions by given prefix, limit, and withPopularity.
+//
+// It returns slice of models.AutocompleteSuggestion in lexical order, and nil error when successful.
+// Otherwise, nil value of models.AutocompleteSuggestion slice, and error will be returned.
+func (r *ProductRepository) GetAutocompleteSuggestions(ctx context.Context, prefix string, limit int, withPopularity bool) ([]models.AutocompleteSuggestion, error) {
+	prefix = strings.ToLower(prefix)
+	members, err := r.Redis.ZRangeByLex(ctx, cacheKeyAutocompleteIndex, &redis.ZRangeBy{
+		Min:   "[" + prefix,
+		Max:   "[" + prefix + "\xff",
+		Count: int64(limit),
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := make([]models.AutocompleteSuggestion, 0, len(members))
+	for _, member := range members {
+		suggestion, ok := parseAutocompleteMember(member)
+		if !ok {
+			continue
+		}
+
+		suggestions = append(suggestions, suggestion)
+	}
+
+	if !withPopularity || len(suggestions) == 0 {
+		return suggestions, nil
+	}
+
+	popularityMembers := make([]string, len(suggestions))
+	for i, suggestion := range suggestions {
+		popularityMembers[i] = autocompletePopularityMember(suggestion)
+	}
+
+	popularities, err := r.Redis.ZMScore(ctx, cacheKeyAutocompletePopularity, popularityMembers...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range suggestions {
+		suggestions[i].Popularity = popularities[i]
+	}
+
+	return suggestions, nil
+}
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	// golang package
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"productfc/models"
+	"testing"
+	"time"
+
+	// external package
+	"github.com/redis/go-redis/v9"
+)
+
+func TestProductCacheEntryJSON(t *testing.T) {
+	entryJSON, version, err := newProductCacheEntryJSON(&models.Product{ID: 1, Name: "Phone", Version: 7})
+	if err != nil {
+		t.Fatalf("newProductCacheEntryJSON() got error %v", err)
+	}
+
+	// setIfNewerScript compares what's stored under row_version with ARGV[2]
+	var entry map[string]json.RawMessage
+	err = json.Unmarshal(entryJSON, &entry)
+	if err != nil {
+		t.Fatalf("json.Unmarshal() got error %v", err)
+	}
+
+	if version != 7 || string(entry["row_version"]) != "7" {
+		t.Fatalf("newProductCacheEntryJSON() = %s, %d, want row_version 7", entryJSON, version)
+	}
+
+	entryJSON, version, err = newProductCategoryCacheEntryJSON(&models.ProductCategory{ID: 1, Name: "Gadgets", Version: 3})
+	if err != nil {
+		t.Fatalf("newProductCategoryCacheEntryJSON() got error %v", err)
+	}
+
+	err = json.Unmarshal(entryJSON, &entry)
+	if err != nil {
+		t.Fatalf("json.Unmarshal() got error %v", err)
+	}
+
+	if version != 3 || string(entry["row_version"]) != "3" {
+		t.Fatalf("newProductCategoryCacheEntryJSON() = %s, %d, want row_version 3", entryJSON, version)
+	}
+}
+
+// newTestRedis new test redis, connected to REDIS_ADDR. The test is skipped when it isn't set,
+// lua scripts can't run anywhere else.
+func newTestRedis(t *testing.T) *redis.Client {
+	t.Helper()
+
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		t.Skip("REDIS_ADDR is not set")
+	}
+
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	t.Cleanup(func() {
+		client.Close()
+	})
+
+	err := client.Ping(context.Background()).Err()
+	if err != nil {
+		t.Fatalf("redis.Ping() got error %v", err)
+	}
+
+	return client
+}
+
+func TestSetIfNewerScript(t *testing.T) {
+	client := newTestRedis(t)
+	r := &ProductRepository{Redis: client}
+	ctx := context.Background()
+
+	productID := time.Now().UnixNano()
+	t.Cleanup(func() {
+		client.Del(ctx, fmt.Sprintf(cacheKeyProductInfo, productID))
+	})
+
+	get := func() *models.Product {
+		t.Helper()
+
+		product, _, err := r.GetProductByIDFromRedis(ctx, productID)
+		if err != nil {
+			t.Fatalf("GetProductByIDFromRedis() got error %v", err)
+		}
+
+		return product
+	}
+
+	// a plain value left by an older release is overwritten
+	client.Set(ctx, fmt.Sprintf(cacheKeyProductInfo, productID), "not json", time.Minute)
+
+	err := r.SetProductByID(ctx, &models.Product{ID: productID, Name: "v2", Version: 2}, productID)
+	if err != nil {
+		t.Fatalf("SetProductByID() got error %v", err)
+	}
+
+	if product := get(); product.Name != "v2" || product.Version != 2 {
+		t.Fatalf("cached product = %+v, want v2", product)
+	}
+
+	// a slow reader that loaded version 1 can't overwrite version 2
+	err = r.SetProductByID(ctx, &models.Product{ID: productID, Name: "v1", Version: 1}, productID)
+	if err != nil {
+		t.Fatalf("SetProductByID() got error %v", err)
+	}
+
+	if product := get(); product.Name != "v2" {
+		t.Fatalf("cached product = %+v, want v2 kept", product)
+	}
+
+	// the same version is written again, e.g. to refresh its freshness window
+	err = r.SetProductByID(ctx, &models.Product{ID: productID, Name: "v2 again", Version: 2}, productID)
+	if err != nil {
+		t.Fatalf("SetProductByID() got error %v", err)
+	}
+
+	if product := get(); product.Name != "v2 again" {
+		t.Fatalf("cached product = %+v, want v2 again", product)
+	}
+
+	// the tombstone of the deleted version 2 keeps the product out
+	err = r.DeleteProductByIDFromRedis(ctx, productID, 2)
+	if err != nil {
+		t.Fatalf("DeleteProductByIDFromRedis() got error %v", err)
+	}
+
+	err = r.SetProducts(ctx, []models.Product{{ID: productID, Name: "v2", Version: 2}})
+	if err != nil {
+		t.Fatalf("SetProducts() got error %v", err)
+	}
+
+	if product := get(); product.ID != 0 || product.Version != 3 {
+		t.Fatalf("cached product = %+v, want the tombstone at version 3", product)
+	}
+}
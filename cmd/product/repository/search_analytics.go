@@ -0,0 +1,102 @@
+package repository
+
+import (
+	// golang package
+	"context"
+	"productfc/models"
+)
+
+// InsertSearchEvents insert search events by given slice of models.SearchEvent.
+//
+// It returns nil error when successful.
+// Otherwise, error will be returned.
+func (r *ProductRepository) InsertSearchEvents(ctx context.Context, events []models.SearchEvent) error {
+	err := r.Database.WithContext(ctx).Table("search_event").Omit("id").Create(&events).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// InsertSearchClick insert search click by given click pointer of models.SearchClick.
+//
+// It returns nil error when successful.
+// Otherwise, error will be returned.
+func (r *ProductRepository) InsertSearchClick(ctx context.Context, click *models.SearchClick) error {
+	err := r.Database.WithContext(ctx).Table("search_click").Omit("id").Create(click).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// FindTopSearchQueries find top search queries by given SearchReportParameter.
+//
+// It returns slice of models.SearchQueryStat ordered by searches, and nil error when successful.
+// Otherwise, nil value of models.SearchQueryStat slice, and error will be returned.
+func (r *ProductRepository) FindTopSearchQueries(ctx context.Context, param models.SearchReportParameter) ([]models.SearchQueryStat, error) {
+	var stats []models.SearchQueryStat
+	err := r.Database.WithContext(ctx).Table("search_event").
+		Select("term, COUNT(*) AS searches, AVG(result_count) AS avg_result_count, AVG(latency_ms) AS avg_latency_ms").
+		Where("term <> '' AND created_at >= ? AND created_at < ?", param.From, param.To).
+		Group("term").
+		Order("searches DESC, term").
+		Limit(param.Limit).
+		Scan(&stats).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// FindZeroResultSearchQueries find zero result search queries by given SearchReportParameter.
+//
+// It returns slice of models.SearchQueryStat ordered by searches, and nil error when successful.
+// Otherwise, nil value of models.SearchQueryStat slice, and error will be returned.
+func (r *ProductRepository) FindZeroResultSearchQueries(ctx context.Context, param models.SearchReportParameter) ([]models.SearchQueryStat, error) {
+	var stats []models.SearchQueryStat
+	err := r.Database.WithContext(ctx).Table("search_event").
+		Select("term, COUNT(*) AS searches, 0 AS avg_result_count, AVG(latency_ms) AS avg_latency_ms").
+		Where("term <> '' AND result_count = 0 AND created_at >= ? AND created_at < ?", param.From, param.To).
+		Group("term").
+		Order("searches DESC, term").
+		Limit(param.Limit).
+		Scan(&stats).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// FindSearchClickThroughRates find search click through rates by given SearchReportParameter.
+//
+// A search counts as clicked when at least one of its results was clicked, whenever that happened.
+//
+// It returns slice of models.SearchClickThroughStat ordered by searches, and nil error when successful.
+// Otherwise, nil value of models.SearchClickThroughStat slice, and error will be returned.
+func (r *ProductRepository) FindSearchClickThroughRates(ctx context.Context, param models.SearchReportParameter) ([]models.SearchClickThroughStat, error) {
+	clicks := r.Database.Table("search_click").
+		Select("search_id, COUNT(*) AS clicks").
+		Where("created_at >= ?", param.From).
+		Group("search_id")
+
+	var stats []models.SearchClickThroughStat
+	err := r.Database.WithContext(ctx).Table("search_event").
+		Select("search_event.term, COUNT(*) AS searches, COUNT(clicks.search_id) AS clicked_searches, "+
+			"COALESCE(SUM(clicks.clicks), 0) AS clicks, COUNT(clicks.search_id)::float8 / COUNT(*) AS ctr").
+		Joins("LEFT JOIN (?) AS clicks ON clicks.search_id = search_event.search_id", clicks).
+		Where("search_event.term <> '' AND search_event.created_at >= ? AND search_event.created_at < ?", param.From, param.To).
+		Group("search_event.term").
+		Order("searches DESC, search_event.term").
+		Limit(param.Limit).
+		Scan(&stats).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	// golang package
+	"context"
+	"fmt"
+	"net/http"
+	"productfc/config"
+	"productfc/models"
+)
+
+const (
+	SearchBackendSQL   = "sql"
+	SearchBackendIndex = "index"
+)
+
+// SearchBackend answers product searches and keeps whatever it searches in sync with the product table.
+type SearchBackend interface {
+	SearchProduct(ctx context.Context, param models.SearchProductParameter) (*models.SearchProductResult, error)
+	IndexProducts(ctx context.Context, productIDs []int64) error // products that are gone are removed
+	IndexProductCategory(ctx context.Context, productCategoryID int) error
+	Reindex(ctx context.Context) error
+}
+
+// NewSearchBackend new search backend by given SearchConfig, and ProductRepository.
+//
+// It returns SearchBackend, and nil error when successful.
+// Otherwise, nil SearchBackend, and error will be returned.
+func NewSearchBackend(searchConfig config.SearchConfig, productRepository ProductRepository) (SearchBackend, error) {
+	switch searchConfig.Backend {
+	case "", SearchBackendSQL:
+		return NewSQLSearchBackend(productRepository), nil
+	case SearchBackendIndex:
+		return NewIndexSearchBackend(productRepository, searchConfig.Index, &http.Client{Timeout: searchConfig.Index.Timeout}), nil
+	default:
+		return nil, fmt.Errorf("unknown search backend %q", searchConfig.Backend)
+	}
+}
+
+// SQLSearchBackend searches the product table directly, so there's nothing to keep in sync.
+type SQLSearchBackend struct {
+	ProductRepository ProductRepository
+}
+
+// NewSQLSearchBackend new sql search backend by given ProductRepository.
+//
+// It returns pointer of SQLSearchBackend when successful.
+// Otherwise, nil pointer of SQLSearchBackend will be returned.
+func NewSQLSearchBackend(productRepository ProductRepository) *SQLSearchBackend {
+	return &SQLSearchBackend{
+		ProductRepository: productRepository,
+	}
+}
+
+// SearchProduct search product by given SearchProductParameter.
+//
+// It returns pointer of models.SearchProductResult, and nil error when successful.
+// Otherwise, nil pointer of models.SearchProductResult, and error will be returned.
+func (b *SQLSearchBackend) SearchProduct(ctx context.Context, param models.SearchProductParameter) (*models.SearchProductResult, error) {
+	return b.ProductRepository.SearchProduct(ctx, param)
+}
+
+// IndexProducts index products by given slice of productIDs.
+//
+// It returns nil error.
+func (b *SQLSearchBackend) IndexProducts(ctx context.Context, productIDs []int64) error {
+	return nil
+}
+
+// IndexProductCategory index product category by given productCategoryID.
+//
+// It returns nil error.
+func (b *SQLSearchBackend) IndexProductCategory(ctx context.Context, productCategoryID int) error {
+	return nil
+}
+
+// Reindex reindex.
+//
+// It returns nil error.
+func (b *SQLSearchBackend) Reindex(ctx context.Context) error {
+	return nil
+}
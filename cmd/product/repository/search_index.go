@@ -0,0 +1,592 @@
+package repository
+
+import (
+	// golang package
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"productfc/config"
+	"productfc/models"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	defaultIndexBatchSize = 500
+	reindexSuffix         = "_reindex"
+
+	// how many times syncDocuments writes rows that keep changing before giving up
+	maxIndexSyncAttempts = 5
+)
+
+var errIndexSyncUnsettled = errors.New("products kept changing while they were indexed")
+
+// productIndexSettings are applied on every reindex, filters and sorts only work on declared attributes.
+var productIndexSettings = map[string]interface{}{
+	"searchableAttributes": []string{"name", "category", "description"},
+	"filterableAttributes": []string{"id", "category", "category_id", "price", "stock", "created_at", "updated_at"},
+	"sortableAttributes":   []string{"name", "price", "stock", "popularity", "created_at", "updated_at"},
+}
+
+// productDocument is a product as stored in the search index.
+type productDocument struct {
+	ID          int64   `json:"id"`
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Price       float64 `json:"price"`
+	Stock       int     `json:"stock"`
+	CategoryID  int     `json:"category_id"`
+	Category    string  `json:"category"`
+	Popularity  int64   `json:"popularity"`
+	CreatedAt   int64   `json:"created_at"` // unix millis, so the engine can range filter and sort
+	UpdatedAt   int64   `json:"updated_at"`
+}
+
+type indexSearchRequest struct {
+	Query       string   `json:"q"`
+	Filter      []string `json:"filter,omitempty"`
+	Sort        []string `json:"sort,omitempty"`
+	Page        int      `json:"page"`
+	HitsPerPage int      `json:"hitsPerPage"`
+}
+
+type indexSearchResponse struct {
+	Hits      []productDocument `json:"hits"`
+	TotalHits int               `json:"totalHits"`
+}
+
+// IndexSearchBackend searches a Meilisearch compatible engine over HTTP.
+//
+// The index is a copy of the product table, kept in sync by IndexProducts and IndexProductCategory,
+// and rebuilt by Reindex. Cursor pagination isn't supported.
+type IndexSearchBackend struct {
+	ProductRepository ProductRepository
+	Config            config.SearchIndexConfig
+	HTTPClient        *http.Client
+}
+
+// NewIndexSearchBackend new index search backend by given ProductRepository, SearchIndexConfig, and httpClient pointer of http.Client.
+//
+// It returns pointer of IndexSearchBackend when successful.
+// Otherwise, nil pointer of IndexSearchBackend will be returned.
+func NewIndexSearchBackend(productRepository ProductRepository, indexConfig config.SearchIndexConfig, httpClient *http.Client) *IndexSearchBackend {
+	if indexConfig.BatchSize <= 0 {
+		indexConfig.BatchSize = defaultIndexBatchSize
+	}
+
+	return &IndexSearchBackend{
+		ProductRepository: productRepository,
+		Config:            indexConfig,
+		HTTPClient:        httpClient,
+	}
+}
+
+// SearchProduct search product by given SearchProductParameter.
+//
+// Relevance always ranks first in the engine, so the requested sort fields only order equally relevant hits.
+//
+// It returns pointer of models.SearchProductResult, and nil error when successful.
+// Otherwise, nil pointer of models.SearchProductResult, and error will be returned.
+func (b *IndexSearchBackend) SearchProduct(ctx context.Context, param models.SearchProductParameter) (*models.SearchProductResult, error) {
+	if param.Cursor != "" {
+		return nil, fmt.Errorf("%w: cursor pagination isn't supported by the search index", ErrInvalidCursor)
+	}
+
+	request := indexSearchRequest{
+		Query:       strings.TrimSpace(param.Query + " " + param.Name), // the engine is typo tolerant, so fuzzy mode needs nothing else
+		Filter:      indexSearchFilter(param),
+		Page:        param.Page,
+		HitsPerPage: param.PageSize,
+	}
+
+	for _, sortField := range param.SortFields {
+		if sortField.Field == models.SortFieldRelevance {
+			if request.Query == "" {
+				return nil, ErrInvalidSort
+			}
+
+			continue
+		}
+
+		if _, ok := searchProductColumns[sortField.Field]; !ok {
+			return nil, ErrInvalidSort
+		}
+
+		direction := "asc"
+		if sortField.Desc {
+			direction = "desc"
+		}
+
+		request.Sort = append(request.Sort, sortField.Field+":"+direction)
+	}
+
+	if request.Query == "" && len(request.Sort) == 0 {
+		request.Sort = []string{"name:asc"}
+	}
+
+	var response indexSearchResponse
+	err := b.do(ctx, http.MethodPost, b.indexPath(b.Config.Name, "search"), request, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	products := make([]models.Product, len(response.Hits))
+	for i, hit := range response.Hits {
+		products[i] = models.Product{
+			ID:          hit.ID,
+			Name:        hit.Name,
+			Description: hit.Description,
+			Price:       hit.Price,
+			Stock:       hit.Stock,
+			CategoryID:  hit.CategoryID,
+			Popularity:  hit.Popularity,
+			CreatedAt:   time.UnixMilli(hit.CreatedAt),
+			UpdatedAt:   time.UnixMilli(hit.UpdatedAt),
+		}
+	}
+
+	return &models.SearchProductResult{
+		Products:   products,
+		TotalCount: response.TotalHits,
+	}, nil
+}
+
+// indexSearchFilter index search filter by given SearchProductParameter.
+//
+// It returns slice of filter expressions, all of them must match.
+func indexSearchFilter(param models.SearchProductParameter) []string {
+	var filter []string
+	if param.Category != "" {
+		filter = append(filter, "category = "+quoteIndexFilterValue(param.Category))
+	}
+
+	if param.MinPrice > 0 {
+		filter = append(filter, fmt.Sprintf("price >= %v", param.MinPrice))
+	}
+
+	if param.MaxPrice > 0 {
+		filter = append(filter, fmt.Sprintf("price <= %v", param.MaxPrice))
+	}
+
+	if len(param.CategoryIDs) > 0 {
+		filter = append(filter, "category_id IN "+indexFilterList(param.CategoryIDs))
+	}
+
+	if len(param.ExcludeCategoryIDs) > 0 {
+		filter = append(filter, "category_id NOT IN "+indexFilterList(param.ExcludeCategoryIDs))
+	}
+
+	if len(param.IDs) > 0 {
+		filter = append(filter, "id IN "+indexFilterList(param.IDs))
+	}
+
+	if len(param.ExcludeIDs) > 0 {
+		filter = append(filter, "id NOT IN "+indexFilterList(param.ExcludeIDs))
+	}
+
+	if param.InStock {
+		filter = append(filter, "stock > 0")
+	}
+
+	if !param.CreatedAfter.IsZero() {
+		filter = append(filter, fmt.Sprintf("created_at >= %d", param.CreatedAfter.UnixMilli()))
+	}
+
+	if !param.CreatedBefore.IsZero() {
+		filter = append(filter, fmt.Sprintf("created_at < %d", param.CreatedBefore.UnixMilli()))
+	}
+
+	if !param.UpdatedAfter.IsZero() {
+		filter = append(filter, fmt.Sprintf("updated_at >= %d", param.UpdatedAfter.UnixMilli()))
+	}
+
+	if !param.UpdatedBefore.IsZero() {
+		filter = append(filter, fmt.Sprintf("updated_at < %d", param.UpdatedBefore.UnixMilli()))
+	}
+
+	return filter
+}
+
+// quoteIndexFilterValue quote index filter value by given value.
+//
+// It returns string of value in double quotes, with quotes and backslashes escaped.
+func quoteIndexFilterValue(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+
+	return `"` + value + `"`
+}
+
+// indexFilterList index filter list by given slice of ids.
+//
+// It returns string of ids as a filter list, e.g. [1, 2].
+func indexFilterList(ids []int64) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = fmt.Sprint(id)
+	}
+
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+// IndexProducts index products by given slice of productIDs.
+//
+// The documents are reloaded from DB, products that are gone are removed. While a reindex is filling
+// its index they're written there as well, so the swap doesn't lose them.
+//
+// It returns nil error when successful.
+// Otherwise, error will be returned.
+func (b *IndexSearchBackend) IndexProducts(ctx context.Context, productIDs []int64) error {
+	if len(productIDs) == 0 {
+		return nil
+	}
+
+	indexes, err := b.writeIndexes(ctx)
+	if err != nil {
+		return err
+	}
+
+	for chunk := range slices.Chunk(productIDs, b.Config.BatchSize) {
+		rows, err := b.ProductRepository.findProductDocuments(ctx, productDocumentFilter{ProductIDs: chunk})
+		if err != nil {
+			return err
+		}
+
+		err = b.syncDocuments(ctx, indexes, chunk, rows)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// IndexProductCategory index product category by given productCategoryID.
+//
+// Every product of the category is reindexed, since documents carry the category name.
+//
+// It returns nil error when successful.
+// Otherwise, error will be returned.
+func (b *IndexSearchBackend) IndexProductCategory(ctx context.Context, productCategoryID int) error {
+	indexes, err := b.writeIndexes(ctx)
+	if err != nil {
+		return err
+	}
+
+	return b.indexAll(ctx, indexes, productCategoryID)
+}
+
+// Reindex reindex.
+//
+// The index is rebuilt from scratch next to the live one and swapped in once filled,
+// so searches keep being answered while it runs. Products changed meanwhile are written to both
+// by IndexProducts.
+//
+// It returns nil error when successful.
+// Otherwise, error will be returned.
+func (b *IndexSearchBackend) Reindex(ctx context.Context) error {
+	tmpIndex := b.Config.Name + reindexSuffix
+
+	// leftover of an interrupted reindex
+	err := b.do(ctx, http.MethodDelete, b.indexPath(tmpIndex), nil, nil)
+	if err != nil && !isIndexNotFound(err) {
+		return err
+	}
+
+	for _, index := range []string{b.Config.Name, tmpIndex} {
+		err = b.do(ctx, http.MethodPost, "/indexes", map[string]string{"uid": index, "primaryKey": "id"}, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = b.do(ctx, http.MethodPatch, b.indexPath(tmpIndex, "settings"), productIndexSettings, nil)
+	if err != nil {
+		return err
+	}
+
+	err = b.indexAll(ctx, []string{tmpIndex}, 0)
+	if err != nil {
+		return err
+	}
+
+	err = b.do(ctx, http.MethodPost, "/swap-indexes", []map[string][]string{{"indexes": {b.Config.Name, tmpIndex}}}, nil)
+	if err != nil {
+		return err
+	}
+
+	// tmpIndex now holds the previous documents
+	return b.do(ctx, http.MethodDelete, b.indexPath(tmpIndex), nil, nil)
+}
+
+// writeIndexes write indexes.
+//
+// The reindex may run in another process, so whether it is filling its index is asked to the engine.
+//
+// It returns slice of the index names to write to, and nil error when successful.
+// Otherwise, nil slice of string, and error will be returned.
+func (b *IndexSearchBackend) writeIndexes(ctx context.Context) ([]string, error) {
+	tmpIndex := b.Config.Name + reindexSuffix
+
+	err := b.do(ctx, http.MethodGet, b.indexPath(tmpIndex), nil, nil)
+	if isIndexNotFound(err) {
+		return []string{b.Config.Name}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{b.Config.Name, tmpIndex}, nil
+}
+
+// indexAll index all by given slice of indexes, and productCategoryID.
+//
+// Products are read in id order batches, zero productCategoryID means every category.
+//
+// It returns nil error when successful.
+// Otherwise, error will be returned.
+func (b *IndexSearchBackend) indexAll(ctx context.Context, indexes []string, productCategoryID int) error {
+	filter := productDocumentFilter{
+		Limit:             b.Config.BatchSize,
+		ProductCategoryID: productCategoryID,
+	}
+	for {
+		rows, err := b.ProductRepository.findProductDocuments(ctx, filter)
+		if err != nil {
+			return err
+		}
+
+		if len(rows) == 0 {
+			return nil
+		}
+
+		productIDs := make([]int64, len(rows))
+		for i, row := range rows {
+			productIDs[i] = row.ID
+		}
+
+		err = b.syncDocuments(ctx, indexes, productIDs, rows)
+		if err != nil {
+			return err
+		}
+
+		if len(rows) < filter.Limit {
+			return nil
+		}
+
+		filter.AfterID = rows[len(rows)-1].ID
+	}
+}
+
+// syncDocuments sync documents by given slice of indexes, slice of productIDs, and slice of productDocumentRow read for them.
+//
+// The engine applies writes in the order they arrive, so a writer that read the rows before the last change
+// but wrote after it would leave the index behind. The rows are read again once written, and whatever changed
+// meanwhile is written once more, until nothing did.
+//
+// It returns nil error when successful.
+// Otherwise, error will be returned, errIndexSyncUnsettled when the rows kept changing.
+func (b *IndexSearchBackend) syncDocuments(ctx context.Context, indexes []string, productIDs []int64, rows []productDocumentRow) error {
+	for attempt := 0; len(productIDs) > 0; attempt++ {
+		if attempt == maxIndexSyncAttempts {
+			return errIndexSyncUnsettled
+		}
+
+		for _, index := range indexes {
+			err := b.writeDocuments(ctx, index, productIDs, rows)
+			if err != nil {
+				return err
+			}
+		}
+
+		currentRows, err := b.ProductRepository.findProductDocuments(ctx, productDocumentFilter{ProductIDs: productIDs})
+		if err != nil {
+			return err
+		}
+
+		productIDs, rows = changedDocuments(productIDs, rows, currentRows)
+	}
+
+	return nil
+}
+
+// changedDocuments changed documents by given slice of productIDs, slice of written productDocumentRow, and slice of current productDocumentRow.
+//
+// A product changed when its row version differs from the written one, or it appeared or disappeared.
+//
+// It returns slice of the changed product ids, and slice of their current productDocumentRow.
+func changedDocuments(productIDs []int64, written []productDocumentRow, current []productDocumentRow) ([]int64, []productDocumentRow) {
+	writtenVersions := make(map[int64]int64, len(written))
+	for _, row := range written {
+		writtenVersions[row.ID] = row.Version
+	}
+
+	currentVersions := make(map[int64]int64, len(current))
+	var changedRows []productDocumentRow
+	for _, row := range current {
+		currentVersions[row.ID] = row.Version
+		if version, ok := writtenVersions[row.ID]; !ok || version != row.Version {
+			changedRows = append(changedRows, row)
+		}
+	}
+
+	var changedIDs []int64
+	for _, productID := range productIDs {
+		writtenVersion, wasWritten := writtenVersions[productID]
+		currentVersion, isCurrent := currentVersions[productID]
+		if wasWritten != isCurrent || writtenVersion != currentVersion {
+			changedIDs = append(changedIDs, productID)
+		}
+	}
+
+	return changedIDs, changedRows
+}
+
+// writeDocuments write documents by given index, slice of productIDs, and slice of productDocumentRow.
+//
+// The rows are added, and the products among productIDs without a row are deleted.
+//
+// It returns nil error when successful.
+// Otherwise, error will be returned.
+func (b *IndexSearchBackend) writeDocuments(ctx context.Context, index string, productIDs []int64, rows []productDocumentRow) error {
+	found := make(map[int64]bool, len(rows))
+	for _, row := range rows {
+		found[row.ID] = true
+	}
+
+	var missingIDs []int64
+	for _, productID := range productIDs {
+		if !found[productID] {
+			missingIDs = append(missingIDs, productID)
+		}
+	}
+
+	err := b.addDocuments(ctx, index, rows)
+	if err != nil {
+		return err
+	}
+
+	if len(missingIDs) == 0 {
+		return nil
+	}
+
+	return b.do(ctx, http.MethodPost, b.indexPath(index, "documents", "delete-batch"), missingIDs, nil)
+}
+
+// addDocuments add documents by given index, and slice of productDocumentRow.
+//
+// Documents with an existing id are replaced.
+//
+// It returns nil error when successful.
+// Otherwise, error will be returned.
+func (b *IndexSearchBackend) addDocuments(ctx context.Context, index string, rows []productDocumentRow) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	documents := make([]productDocument, len(rows))
+	for i, row := range rows {
+		documents[i] = productDocument{
+			ID:          row.ID,
+			Name:        row.Name,
+			Description: row.Description,
+			Price:       row.Price,
+			Stock:       row.Stock,
+			CategoryID:  row.CategoryID,
+			Category:    row.Category,
+			Popularity:  row.Popularity,
+			CreatedAt:   row.CreatedAt.UnixMilli(),
+			UpdatedAt:   row.UpdatedAt.UnixMilli(),
+		}
+	}
+
+	return b.do(ctx, http.MethodPost, b.indexPath(index, "documents")+"?primaryKey=id", documents, nil)
+}
+
+// indexPath index path by given index, and path elements.
+//
+// It returns string of /indexes/{index}/... with every element escaped.
+func (b *IndexSearchBackend) indexPath(index string, elements ...string) string {
+	path := "/indexes/" + url.PathEscape(index)
+	for _, element := range elements {
+		path += "/" + url.PathEscape(element)
+	}
+
+	return path
+}
+
+// indexStatusError is a non 2xx answer of the search engine.
+type indexStatusError struct {
+	StatusCode int
+	Body       string
+}
+
+// Error error.
+//
+// It returns string.
+func (e *indexStatusError) Error() string {
+	return fmt.Sprintf("search index responded with status %d: %s", e.StatusCode, e.Body)
+}
+
+// isIndexNotFound is index not found by given err.
+//
+// It returns true when the engine answered 404.
+func isIndexNotFound(err error) bool {
+	var statusErr *indexStatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound
+}
+
+// do send request by given method, path, body, and response.
+//
+// Writes are queued by the engine and answered with 202, they're applied in order per index.
+//
+// It returns nil error when successful.
+// Otherwise, error will be returned.
+func (b *IndexSearchBackend) do(ctx context.Context, method string, path string, body interface{}, response interface{}) error {
+	var requestBody io.Reader
+	if body != nil {
+		bodyJSON, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+
+		requestBody = bytes.NewReader(bodyJSON)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(b.Config.BaseURL, "/")+path, requestBody)
+	if err != nil {
+		return err
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if b.Config.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+b.Config.APIKey)
+	}
+
+	resp, err := b.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &indexStatusError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+
+	if response == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(response)
+}
@@ -0,0 +1,604 @@
+package repository
+
+import (
+	// golang package
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"productfc/config"
+	"productfc/models"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	// external package
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var documentTime = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// engineRequest is a request the fake engine received.
+type engineRequest struct {
+	Method string
+	URI    string
+	Body   string
+}
+
+// fakeEngine is a search engine recording every request, and answering them with respond.
+type fakeEngine struct {
+	mu       sync.Mutex
+	requests []engineRequest
+	respond  func(n int, req engineRequest) (int, string) // n is the index of req, nil answers 202 with {}
+}
+
+func (e *fakeEngine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	req := engineRequest{Method: r.Method, URI: r.URL.RequestURI(), Body: string(body)}
+
+	e.mu.Lock()
+	n := len(e.requests)
+	e.requests = append(e.requests, req)
+	e.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	status, answer := http.StatusAccepted, "{}"
+	if e.respond != nil {
+		status, answer = e.respond(n, req)
+	}
+
+	w.WriteHeader(status)
+	io.WriteString(w, answer)
+}
+
+// startFakeEngine start fake engine by given respond, and versions.
+//
+// It returns pointer of fakeEngine, and pointer of IndexSearchBackend of index "products" sending to it,
+// whose DB holds a single product. Its queries answer the product at versions in turn, the last one repeating,
+// zero meaning it's gone, none meaning it stays at version 1.
+func startFakeEngine(t *testing.T, respond func(n int, req engineRequest) (int, string), versions ...int64) (*fakeEngine, *IndexSearchBackend) {
+	t.Helper()
+
+	engine := &fakeEngine{respond: respond}
+	server := httptest.NewServer(engine)
+	t.Cleanup(server.Close)
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sql.OpenDB(documentConnector{versions: &documentVersions{next: versions}})}), &gorm.Config{
+		Logger: logger.Discard,
+	})
+	if err != nil {
+		t.Fatalf("gorm.Open() got error %v", err)
+	}
+
+	backend := NewIndexSearchBackend(ProductRepository{Database: db}, config.SearchIndexConfig{
+		BaseURL: server.URL + "/",
+		APIKey:  "secret",
+		Name:    "products",
+	}, server.Client())
+
+	return engine, backend
+}
+
+// assertJSON assert json by given got, and want, equal once decoded so key order and spacing don't matter.
+func assertJSON(t *testing.T, got string, want string) {
+	t.Helper()
+
+	var gotValue, wantValue interface{}
+	if err := json.Unmarshal([]byte(got), &gotValue); err != nil {
+		t.Fatalf("json.Unmarshal(%q) got error %v", got, err)
+	}
+
+	if err := json.Unmarshal([]byte(want), &wantValue); err != nil {
+		t.Fatalf("json.Unmarshal(%q) got error %v", want, err)
+	}
+
+	if !reflect.DeepEqual(gotValue, wantValue) {
+		t.Fatalf("body = %s, want %s", got, want)
+	}
+}
+
+func TestSearchProductRequest(t *testing.T) {
+	createdAfter := time.UnixMilli(1700000000000)
+	updatedBefore := time.UnixMilli(1710000000000)
+
+	tests := []struct {
+		name  string
+		param models.SearchProductParameter
+		want  string
+	}{
+		{
+			name:  "no query sorts by name",
+			param: models.SearchProductParameter{Page: 1, PageSize: 20},
+			want:  `{"q":"","sort":["name:asc"],"page":1,"hitsPerPage":20}`,
+		},
+		{
+			name:  "query and name are searched together, relevance is left to the engine",
+			param: models.SearchProductParameter{Query: "phone", Name: "case", Page: 2, PageSize: 10, SortFields: []models.SortField{{Field: models.SortFieldRelevance}}},
+			want:  `{"q":"phone case","page":2,"hitsPerPage":10}`,
+		},
+		{
+			name: "sort fields keep their order and direction",
+			param: models.SearchProductParameter{Query: "phone", Page: 1, PageSize: 20, SortFields: []models.SortField{
+				{Field: models.SortFieldRelevance},
+				{Field: models.SortFieldPrice, Desc: true},
+				{Field: models.SortFieldCreatedAt},
+			}},
+			want: `{"q":"phone","sort":["price:desc","created_at:asc"],"page":1,"hitsPerPage":20}`,
+		},
+		{
+			name: "every filter",
+			param: models.SearchProductParameter{
+				Category:           `Men's "Best" \ Gear`,
+				MinPrice:           10.5,
+				MaxPrice:           99,
+				CategoryIDs:        []int64{1, 2},
+				ExcludeCategoryIDs: []int64{3},
+				IDs:                []int64{4, 5, 6},
+				ExcludeIDs:         []int64{7},
+				InStock:            true,
+				CreatedAfter:       createdAfter,
+				UpdatedBefore:      updatedBefore,
+				Page:               1,
+				PageSize:           20,
+			},
+			want: `{"q":"","filter":[
+				"category = \"Men's \\\"Best\\\" \\\\ Gear\"",
+				"price >= 10.5",
+				"price <= 99",
+				"category_id IN [1, 2]",
+				"category_id NOT IN [3]",
+				"id IN [4, 5, 6]",
+				"id NOT IN [7]",
+				"stock > 0",
+				"created_at >= 1700000000000",
+				"updated_at < 1710000000000"
+			],"sort":["name:asc"],"page":1,"hitsPerPage":20}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, backend := startFakeEngine(t, func(int, engineRequest) (int, string) {
+				return http.StatusOK, `{"hits":[],"totalHits":0}`
+			})
+
+			_, err := backend.SearchProduct(context.Background(), tt.param)
+			if err != nil {
+				t.Fatalf("SearchProduct() got error %v", err)
+			}
+
+			if len(engine.requests) != 1 || engine.requests[0].Method != http.MethodPost || engine.requests[0].URI != "/indexes/products/search" {
+				t.Fatalf("requests = %+v, want a single POST /indexes/products/search", engine.requests)
+			}
+
+			assertJSON(t, engine.requests[0].Body, tt.want)
+		})
+	}
+}
+
+func TestSearchProductResult(t *testing.T) {
+	_, backend := startFakeEngine(t, func(int, engineRequest) (int, string) {
+		return http.StatusOK, `{"hits":[{"id":1,"name":"Phone","description":"A phone","price":100.5,"stock":5,
+			"category_id":2,"category":"Gadgets","popularity":3,"created_at":1700000000000,"updated_at":1710000000000}],"totalHits":41}`
+	})
+
+	result, err := backend.SearchProduct(context.Background(), models.SearchProductParameter{Query: "phone", Page: 1, PageSize: 1})
+	if err != nil {
+		t.Fatalf("SearchProduct() got error %v", err)
+	}
+
+	want := []models.Product{{
+		ID:          1,
+		Name:        "Phone",
+		Description: "A phone",
+		Price:       100.5,
+		Stock:       5,
+		CategoryID:  2,
+		Popularity:  3,
+		CreatedAt:   time.UnixMilli(1700000000000),
+		UpdatedAt:   time.UnixMilli(1710000000000),
+	}}
+	if result.TotalCount != 41 || !reflect.DeepEqual(result.Products, want) {
+		t.Fatalf("SearchProduct() = %+v, want %+v of 41", result, want)
+	}
+}
+
+func TestSearchProductInvalidParameter(t *testing.T) {
+	tests := []struct {
+		name    string
+		param   models.SearchProductParameter
+		wantErr error
+	}{
+		{
+			name:    "unknown sort field",
+			param:   models.SearchProductParameter{Query: "phone", SortFields: []models.SortField{{Field: "id"}}},
+			wantErr: ErrInvalidSort,
+		},
+		{
+			name:    "relevance without query",
+			param:   models.SearchProductParameter{SortFields: []models.SortField{{Field: models.SortFieldRelevance}}},
+			wantErr: ErrInvalidSort,
+		},
+		{
+			name:    "cursor",
+			param:   models.SearchProductParameter{Query: "phone", Cursor: "eyJ2IjpbXX0"},
+			wantErr: ErrInvalidCursor,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, backend := startFakeEngine(t, nil)
+
+			_, err := backend.SearchProduct(context.Background(), tt.param)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SearchProduct() got error %v, want %v", err, tt.wantErr)
+			}
+
+			if len(engine.requests) != 0 {
+				t.Fatalf("requests = %+v, want none", engine.requests)
+			}
+		})
+	}
+}
+
+func TestQuoteIndexFilterValue(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{`Gadgets`, `"Gadgets"`},
+		{`Men's`, `"Men's"`},
+		{`12" screens`, `"12\" screens"`},
+		{`C:\temp`, `"C:\\temp"`},
+		{`\" OR id > 0`, `"\\\" OR id > 0"`},
+	}
+
+	for _, tt := range tests {
+		if got := quoteIndexFilterValue(tt.value); got != tt.want {
+			t.Errorf("quoteIndexFilterValue(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+// withoutReindex answers 404 to the check for a reindex in progress, and 202 to everything else.
+func withoutReindex(_ int, req engineRequest) (int, string) {
+	if req.Method == http.MethodGet && req.URI == "/indexes/products_reindex" {
+		return http.StatusNotFound, `{"code":"index_not_found"}`
+	}
+
+	return http.StatusAccepted, `{}`
+}
+
+// assertRequests assert requests by given engine pointer of fakeEngine, and want as "METHOD URI".
+func assertRequests(t *testing.T, engine *fakeEngine, want ...string) {
+	t.Helper()
+
+	got := make([]string, len(engine.requests))
+	for i, req := range engine.requests {
+		got[i] = req.Method + " " + req.URI
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("requests = %q, want %q", got, want)
+	}
+}
+
+func TestIndexProducts(t *testing.T) {
+	engine, backend := startFakeEngine(t, withoutReindex)
+
+	err := backend.IndexProducts(context.Background(), nil)
+	if err != nil || len(engine.requests) != 0 {
+		t.Fatalf("IndexProducts(nil) got error %v, and requests %+v, want neither", err, engine.requests)
+	}
+
+	err = backend.IndexProducts(context.Background(), []int64{1})
+	if err != nil {
+		t.Fatalf("IndexProducts() got error %v", err)
+	}
+
+	assertRequests(t, engine, "GET /indexes/products_reindex", "POST /indexes/products/documents?primaryKey=id")
+	assertJSON(t, engine.requests[1].Body, fmt.Sprintf(`[{"id":1,"name":"Phone","description":"A phone","price":100.5,"stock":5,
+		"category_id":2,"category":"Gadgets","popularity":3,"created_at":%d,"updated_at":%d}]`, documentTime.UnixMilli(), documentTime.UnixMilli()))
+}
+
+func TestIndexProductsRemovesMissing(t *testing.T) {
+	engine, backend := startFakeEngine(t, withoutReindex, 0)
+
+	err := backend.IndexProducts(context.Background(), []int64{1})
+	if err != nil {
+		t.Fatalf("IndexProducts() got error %v", err)
+	}
+
+	assertRequests(t, engine, "GET /indexes/products_reindex", "POST /indexes/products/documents/delete-batch")
+	assertJSON(t, engine.requests[1].Body, `[1]`)
+}
+
+func TestIndexProductsRewritesChanged(t *testing.T) {
+	tests := []struct {
+		name     string
+		versions []int64
+		want     []string
+	}{
+		{
+			name:     "changed while written",
+			versions: []int64{1, 2},
+			want: []string{
+				"GET /indexes/products_reindex",
+				"POST /indexes/products/documents?primaryKey=id",
+				"POST /indexes/products/documents?primaryKey=id",
+			},
+		},
+		{
+			name:     "deleted while written",
+			versions: []int64{1, 0},
+			want: []string{
+				"GET /indexes/products_reindex",
+				"POST /indexes/products/documents?primaryKey=id",
+				"POST /indexes/products/documents/delete-batch",
+			},
+		},
+		{
+			name:     "created while removed",
+			versions: []int64{0, 1},
+			want: []string{
+				"GET /indexes/products_reindex",
+				"POST /indexes/products/documents/delete-batch",
+				"POST /indexes/products/documents?primaryKey=id",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, backend := startFakeEngine(t, withoutReindex, tt.versions...)
+
+			err := backend.IndexProducts(context.Background(), []int64{1})
+			if err != nil {
+				t.Fatalf("IndexProducts() got error %v", err)
+			}
+
+			assertRequests(t, engine, tt.want...)
+		})
+	}
+}
+
+func TestIndexProductsUnsettled(t *testing.T) {
+	engine, backend := startFakeEngine(t, withoutReindex, 1, 2, 3, 4, 5, 6)
+
+	err := backend.IndexProducts(context.Background(), []int64{1})
+	if !errors.Is(err, errIndexSyncUnsettled) {
+		t.Fatalf("IndexProducts() got error %v, want errIndexSyncUnsettled", err)
+	}
+
+	if len(engine.requests) != 1+maxIndexSyncAttempts {
+		t.Fatalf("requests = %+v, want the check and %d writes", engine.requests, maxIndexSyncAttempts)
+	}
+}
+
+func TestIndexProductsDuringReindex(t *testing.T) {
+	engine, backend := startFakeEngine(t, nil)
+
+	err := backend.IndexProducts(context.Background(), []int64{1})
+	if err != nil {
+		t.Fatalf("IndexProducts() got error %v", err)
+	}
+
+	assertRequests(t, engine,
+		"GET /indexes/products_reindex",
+		"POST /indexes/products/documents?primaryKey=id",
+		"POST /indexes/products_reindex/documents?primaryKey=id",
+	)
+}
+
+func TestIndexProductCategory(t *testing.T) {
+	engine, backend := startFakeEngine(t, withoutReindex)
+
+	err := backend.IndexProductCategory(context.Background(), 2)
+	if err != nil {
+		t.Fatalf("IndexProductCategory() got error %v", err)
+	}
+
+	assertRequests(t, engine, "GET /indexes/products_reindex", "POST /indexes/products/documents?primaryKey=id")
+}
+
+func TestReindex(t *testing.T) {
+	for _, firstDeleteStatus := range []int{http.StatusAccepted, http.StatusNotFound} {
+		t.Run(http.StatusText(firstDeleteStatus), func(t *testing.T) {
+			engine, backend := startFakeEngine(t, func(n int, _ engineRequest) (int, string) {
+				if n == 0 {
+					return firstDeleteStatus, `{}`
+				}
+
+				return http.StatusAccepted, `{}`
+			})
+
+			err := backend.Reindex(context.Background())
+			if err != nil {
+				t.Fatalf("Reindex() got error %v", err)
+			}
+
+			want := []struct {
+				method string
+				uri    string
+				body   string
+			}{
+				{http.MethodDelete, "/indexes/products_reindex", ""},
+				{http.MethodPost, "/indexes", `{"uid":"products","primaryKey":"id"}`},
+				{http.MethodPost, "/indexes", `{"uid":"products_reindex","primaryKey":"id"}`},
+				{http.MethodPatch, "/indexes/products_reindex/settings", `{
+					"searchableAttributes":["name","category","description"],
+					"filterableAttributes":["id","category","category_id","price","stock","created_at","updated_at"],
+					"sortableAttributes":["name","price","stock","popularity","created_at","updated_at"]
+				}`},
+				{http.MethodPost, "/indexes/products_reindex/documents?primaryKey=id", ""},
+				{http.MethodPost, "/swap-indexes", `[{"indexes":["products","products_reindex"]}]`},
+				{http.MethodDelete, "/indexes/products_reindex", ""},
+			}
+			if len(engine.requests) != len(want) {
+				t.Fatalf("requests = %+v, want %d", engine.requests, len(want))
+			}
+
+			for i, w := range want {
+				got := engine.requests[i]
+				if got.Method != w.method || got.URI != w.uri {
+					t.Fatalf("request %d = %s %s, want %s %s", i, got.Method, got.URI, w.method, w.uri)
+				}
+
+				if w.body != "" {
+					assertJSON(t, got.Body, w.body)
+				}
+			}
+		})
+	}
+}
+
+func TestReindexStopsOnFailure(t *testing.T) {
+	engine, backend := startFakeEngine(t, func(n int, _ engineRequest) (int, string) {
+		return http.StatusInternalServerError, `{"message":"internal"}`
+	})
+
+	err := backend.Reindex(context.Background())
+
+	var statusErr *indexStatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("Reindex() got error %v, want indexStatusError of 500", err)
+	}
+
+	if len(engine.requests) != 1 {
+		t.Fatalf("requests = %+v, want only the first delete", engine.requests)
+	}
+}
+
+func TestIndexStatusError(t *testing.T) {
+	tests := []struct {
+		status       int
+		wantNotFound bool
+	}{
+		{http.StatusBadRequest, false},
+		{http.StatusNotFound, true},
+		{http.StatusServiceUnavailable, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			_, backend := startFakeEngine(t, func(int, engineRequest) (int, string) {
+				return tt.status, `{"message":"nope"}`
+			})
+
+			_, err := backend.SearchProduct(context.Background(), models.SearchProductParameter{Query: "phone"})
+
+			var statusErr *indexStatusError
+			if !errors.As(err, &statusErr) {
+				t.Fatalf("SearchProduct() got error %v, want indexStatusError", err)
+			}
+
+			if statusErr.StatusCode != tt.status || statusErr.Body != `{"message":"nope"}` {
+				t.Fatalf("indexStatusError = %+v, want status %d with the answered body", statusErr, tt.status)
+			}
+
+			if isIndexNotFound(err) != tt.wantNotFound {
+				t.Fatalf("isIndexNotFound() = %v, want %v", isIndexNotFound(err), tt.wantNotFound)
+			}
+		})
+	}
+}
+
+// documentConnector is a database answering every query with one product document row, at the next of versions.
+type documentConnector struct {
+	versions *documentVersions
+}
+
+// documentVersions are the versions the product is answered at in turn.
+type documentVersions struct {
+	mu   sync.Mutex
+	next []int64
+}
+
+// take take.
+//
+// It returns int64 of the next version, the last one once there's no other, 1 when there's none.
+func (v *documentVersions) take() int64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if len(v.next) == 0 {
+		return 1
+	}
+
+	version := v.next[0]
+	if len(v.next) > 1 {
+		v.next = v.next[1:]
+	}
+
+	return version
+}
+
+func (c documentConnector) Connect(context.Context) (driver.Conn, error) {
+	return documentConn{versions: c.versions}, nil
+}
+
+func (documentConnector) Driver() driver.Driver {
+	return nil
+}
+
+type documentConn struct {
+	versions *documentVersions
+}
+
+func (documentConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("fake db doesn't prepare %q", query)
+}
+
+func (documentConn) Close() error {
+	return nil
+}
+
+func (documentConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("fake db doesn't begin transactions")
+}
+
+func (c documentConn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	version := c.versions.take()
+	if version == 0 {
+		return &documentRows{}, nil
+	}
+
+	return &documentRows{remaining: 1, version: version}, nil
+}
+
+type documentRows struct {
+	remaining int
+	version   int64
+}
+
+func (r *documentRows) Columns() []string {
+	return []string{"id", "name", "description", "price", "stock", "category_id", "popularity", "created_at", "updated_at", "version", "category"}
+}
+
+func (r *documentRows) Close() error {
+	return nil
+}
+
+func (r *documentRows) Next(dest []driver.Value) error {
+	if r.remaining == 0 {
+		return io.EOF
+	}
+
+	r.remaining--
+	copy(dest, []driver.Value{int64(1), "Phone", "A phone", 100.5, int64(5), int64(2), int64(3), documentTime, documentTime, r.version, "Gadgets"})
+
+	return nil
+}
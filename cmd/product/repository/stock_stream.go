@@ -0,0 +1,50 @@
+package repository
+
+import (
+	// golang package
+	"context"
+	"encoding/json"
+	"productfc/models"
+
+	// external package
+	"github.com/redis/go-redis/v9"
+)
+
+// channelProductStock is the pub/sub channel of models.ProductStockEvent, every instance subscribes to it.
+const channelProductStock = "product:stock"
+
+// PublishProductStockEvent publish product stock event by given event models.ProductStockEvent.
+//
+// It returns nil error when successful.
+// Otherwise, error will be returned.
+func (r *ProductRepository) PublishProductStockEvent(ctx context.Context, event models.ProductStockEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return r.Redis.Publish(ctx, channelProductStock, payload).Err()
+}
+
+// SubscribeProductStockEvents subscribe product stock events.
+//
+// The subscription is restored by the client when redis reconnects, events published in between are lost.
+//
+// It returns pointer of redis.PubSub, close it once done.
+func (r *ProductRepository) SubscribeProductStockEvents(ctx context.Context) *redis.PubSub {
+	return r.Redis.Subscribe(ctx, channelProductStock)
+}
+
+// ParseProductStockEvent parse product stock event by given message pointer of redis.Message.
+//
+// It returns models.ProductStockEvent, and nil error when successful.
+// Otherwise, empty models.ProductStockEvent, and error will be returned.
+func ParseProductStockEvent(message *redis.Message) (models.ProductStockEvent, error) {
+	var event models.ProductStockEvent
+	err := json.Unmarshal([]byte(message.Payload), &event)
+	if err != nil {
+		return models.ProductStockEvent{}, err
+	}
+
+	return event, nil
+}
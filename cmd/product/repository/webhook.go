@@ -0,0 +1,243 @@
+package repository
+
+import (
+	// golang package
+	"context"
+	"encoding/json"
+	"errors"
+	"productfc/models"
+	"time"
+
+	// external package
+	"gorm.io/gorm"
+)
+
+// InsertWebhookSubscription insert webhook subscription by given subscription pointer of models.WebhookSubscription.
+//
+// It returns int64, and nil error when successful.
+// Otherwise, empty int64, and error will be returned.
+func (r *ProductRepository) InsertWebhookSubscription(ctx context.Context, subscription *models.WebhookSubscription) (int64, error) {
+	err := r.Database.WithContext(ctx).Table("webhook_subscription").Create(subscription).Error
+	if err != nil {
+		return 0, err
+	}
+
+	return subscription.ID, nil
+}
+
+// FindWebhookSubscriptions find webhook subscriptions.
+//
+// It returns slice of models.WebhookSubscription ordered by id, and nil error when successful.
+// Otherwise, nil value of models.WebhookSubscription slice, and error will be returned.
+func (r *ProductRepository) FindWebhookSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	var subscriptions []models.WebhookSubscription
+	err := r.Database.WithContext(ctx).Table("webhook_subscription").Order("id").Find(&subscriptions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return subscriptions, nil
+}
+
+// FindWebhookSubscriptionByID find webhook subscription by id by given subscriptionID.
+//
+// It returns pointer of models.WebhookSubscription, and nil error when successful, with an empty one when it doesn't exist.
+// Otherwise, nil pointer of models.WebhookSubscription, and error will be returned.
+func (r *ProductRepository) FindWebhookSubscriptionByID(ctx context.Context, subscriptionID int64) (*models.WebhookSubscription, error) {
+	var subscription models.WebhookSubscription
+	err := r.Database.WithContext(ctx).Table("webhook_subscription").Where("id = ?", subscriptionID).Last(&subscription).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &models.WebhookSubscription{}, nil
+		}
+
+		return nil, err
+	}
+
+	return &subscription, nil
+}
+
+// FindWebhookSubscriptionsByIDs find webhook subscriptions by ids by given slice of subscriptionIDs.
+//
+// It returns slice of models.WebhookSubscription, and nil error when successful. Missing IDs are simply absent from the result.
+// Otherwise, nil value of models.WebhookSubscription slice, and error will be returned.
+func (r *ProductRepository) FindWebhookSubscriptionsByIDs(ctx context.Context, subscriptionIDs []int64) ([]models.WebhookSubscription, error) {
+	var subscriptions []models.WebhookSubscription
+	err := r.Database.WithContext(ctx).Table("webhook_subscription").Where("id IN ?", subscriptionIDs).Find(&subscriptions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return subscriptions, nil
+}
+
+// FindActiveWebhookSubscriptionsByEventType find active webhook subscriptions by event type by given eventType.
+//
+// It returns slice of models.WebhookSubscription, and nil error when successful.
+// Otherwise, nil value of models.WebhookSubscription slice, and error will be returned.
+func (r *ProductRepository) FindActiveWebhookSubscriptionsByEventType(ctx context.Context, eventType string) ([]models.WebhookSubscription, error) {
+	eventTypes, err := json.Marshal([]string{eventType})
+	if err != nil {
+		return nil, err
+	}
+
+	var subscriptions []models.WebhookSubscription
+	err = r.Database.WithContext(ctx).Table("webhook_subscription").
+		Where("active AND event_types @> ?::jsonb", string(eventTypes)).
+		Find(&subscriptions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return subscriptions, nil
+}
+
+// UpdateWebhookSubscription update webhook subscription by given subscription pointer of models.WebhookSubscription.
+//
+// It returns pointer of models.WebhookSubscription, and nil error when successful.
+// Otherwise, nil pointer of models.WebhookSubscription, and error will be returned.
+func (r *ProductRepository) UpdateWebhookSubscription(ctx context.Context, subscription *models.WebhookSubscription) (*models.WebhookSubscription, error) {
+	err := r.Database.WithContext(ctx).Table("webhook_subscription").Omit("created_at").Save(subscription).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return subscription, nil
+}
+
+// DeleteWebhookSubscription delete webhook subscription by given subscriptionID, its deliveries are deleted with it.
+//
+// It returns nil error when successful.
+// Otherwise, error will be returned.
+func (r *ProductRepository) DeleteWebhookSubscription(ctx context.Context, subscriptionID int64) error {
+	err := r.Database.WithContext(ctx).Table("webhook_subscription").Delete(&models.WebhookSubscription{}, subscriptionID).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RecordWebhookSubscriptionSuccess record webhook subscription success by given subscriptionID.
+//
+// It returns nil error when successful.
+// Otherwise, error will be returned.
+func (r *ProductRepository) RecordWebhookSubscriptionSuccess(ctx context.Context, subscriptionID int64) error {
+	err := r.Database.WithContext(ctx).Table("webhook_subscription").
+		Where("id = ? AND consecutive_failures > 0", subscriptionID).
+		Updates(map[string]interface{}{
+			"consecutive_failures": 0,
+			"updated_at":           time.Now(),
+		}).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RecordWebhookSubscriptionFailure record webhook subscription failure by given subscriptionID, and disableAfter failures.
+//
+// The subscription is disabled once it has failed disableAfter times in a row, counted in one statement
+// so concurrent deliveries can't miss the limit.
+//
+// It returns bool of whether it's still active, and nil error when successful.
+// Otherwise, false, and error will be returned.
+func (r *ProductRepository) RecordWebhookSubscriptionFailure(ctx context.Context, subscriptionID int64, disableAfter int) (bool, error) {
+	var subscriptions []models.WebhookSubscription
+	err := r.Database.WithContext(ctx).Raw(`
+		UPDATE webhook_subscription SET
+			consecutive_failures = consecutive_failures + 1,
+			active = active AND consecutive_failures + 1 < ?,
+			disabled_at = CASE WHEN active AND consecutive_failures + 1 >= ? THEN NOW() ELSE disabled_at END,
+			updated_at = NOW()
+		WHERE id = ?
+		RETURNING *`, disableAfter, disableAfter, subscriptionID).Scan(&subscriptions).Error
+	if err != nil {
+		return false, err
+	}
+
+	return len(subscriptions) > 0 && subscriptions[0].Active, nil
+}
+
+// InsertWebhookDeliveries insert webhook deliveries by given slice of models.WebhookDelivery.
+//
+// It returns nil error when successful.
+// Otherwise, error will be returned.
+func (r *ProductRepository) InsertWebhookDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error {
+	err := r.Database.WithContext(ctx).Table("webhook_delivery").Omit("id").Create(&deliveries).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ClaimDueWebhookDeliveries claim due webhook deliveries by given limit, and lease.
+//
+// The claimed deliveries are pushed lease into the future, so other instances skip them while they're sent,
+// and retry them once lease is over should this instance stop half way.
+//
+// It returns slice of models.WebhookDelivery, and nil error when successful.
+// Otherwise, nil value of models.WebhookDelivery slice, and error will be returned.
+func (r *ProductRepository) ClaimDueWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	err := r.Database.WithContext(ctx).Raw(`
+		UPDATE webhook_delivery SET next_attempt_at = NOW() + make_interval(secs => ?), updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM webhook_delivery
+			WHERE status = ? AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`, lease.Seconds(), models.WebhookDeliveryPending, limit).Scan(&deliveries).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// UpdateWebhookDelivery update webhook delivery by given delivery pointer of models.WebhookDelivery.
+//
+// Only the outcome of the attempt is written.
+//
+// It returns nil error when successful.
+// Otherwise, error will be returned.
+func (r *ProductRepository) UpdateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	err := r.Database.WithContext(ctx).Table("webhook_delivery").
+		Where("id = ?", delivery.ID).
+		Updates(map[string]interface{}{
+			"status":           delivery.Status,
+			"attempts":         delivery.Attempts,
+			"next_attempt_at":  delivery.NextAttemptAt,
+			"last_status_code": delivery.LastStatusCode,
+			"last_error":       delivery.LastError,
+			"delivered_at":     delivery.DeliveredAt,
+			"updated_at":       time.Now(),
+		}).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// FindWebhookDeliveries find webhook deliveries by given subscriptionID, and limit.
+//
+// It returns slice of models.WebhookDelivery, the latest first, and nil error when successful.
+// Otherwise, nil value of models.WebhookDelivery slice, and error will be returned.
+func (r *ProductRepository) FindWebhookDeliveries(ctx context.Context, subscriptionID int64, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	err := r.Database.WithContext(ctx).Table("webhook_delivery").
+		Where("subscription_id = ?", subscriptionID).
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Find(&deliveries).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
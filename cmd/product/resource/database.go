@@ -21,7 +21,8 @@ func InitDB(cfg *config.Config) *gorm.DB {
 		cfg.Database.Host, cfg.Database.Port, cfg.Database.User, cfg.Database.Password, cfg.Database.Name)
 
 	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+		Logger:         logger.Default.LogMode(logger.Info),
+		TranslateError: true, // constraint violations as gorm.ErrDuplicatedKey, gorm.ErrForeignKeyViolated
 	})
 
 	if err != nil {
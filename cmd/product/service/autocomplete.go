@@ -0,0 +1,157 @@
+package service
+
+import (
+	// golang package
+	"context"
+	"errors"
+	"productfc/infrastructure/circuitbreaker"
+	"productfc/infrastructure/log"
+	"productfc/models"
+	"sort"
+
+	// external package
+	"github.com/sirupsen/logrus"
+)
+
+// how many lexical matches are re-ranked by popularity for each returned suggestion
+//
+// Only these candidates are ranked, a popular name past the first limit*autocompleteCandidateFactor
+// matches of a short prefix is never suggested until the prefix is long enough to reach it.
+const autocompleteCandidateFactor = 5
+
+// Autocomplete autocomplete by given prefix, limit, and weighted.
+//
+// A limit of zero or less falls back to the configured autocomplete limit. When weighted, the first
+// limit*autocompleteCandidateFactor matches in lexical order are ranked by popularity, not every match.
+//
+// It returns slice of models.AutocompleteSuggestion, and nil error when successful.
+// Otherwise, nil value of models.AutocompleteSuggestion slice, and error will be returned.
+func (s *ProductService) Autocomplete(ctx context.Context, prefix string, limit int, weighted bool) ([]models.AutocompleteSuggestion, error) {
+	if limit <= 0 {
+		limit = s.SearchConfig.AutocompleteLimit
+	}
+
+	candidateLimit := limit
+	if weighted {
+		candidateLimit = limit * autocompleteCandidateFactor
+	}
+
+	var suggestions []models.AutocompleteSuggestion
+	err := s.RedisBreaker.Execute(func() error {
+		var errRedis error
+		suggestions, errRedis = s.ProductRepository.GetAutocompleteSuggestions(ctx, prefix, candidateLimit, weighted)
+		return errRedis
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if weighted {
+		sort.SliceStable(suggestions, func(i, j int) bool {
+			return suggestions[i].Popularity > suggestions[j].Popularity
+		})
+	}
+
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+
+	return suggestions, nil
+}
+
+// RebuildAutocompleteIndex rebuild autocomplete index.
+//
+// It returns nil error when successful.
+// Otherwise, error will be returned.
+func (s *ProductService) RebuildAutocompleteIndex(ctx context.Context) error {
+	suggestions, err := s.ProductRepository.FindAllProductSuggestions(ctx)
+	if err != nil {
+		return err
+	}
+
+	productCategories, err := s.ProductRepository.FindAllProductCategories(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, productCategory := range productCategories {
+		suggestions = append(suggestions, productCategorySuggestion(&productCategory))
+	}
+
+	err = s.ProductRepository.ReplaceAutocompleteSuggestions(ctx, suggestions)
+	if err != nil {
+		return err
+	}
+
+	log.Logger.WithFields(logrus.Fields{
+		"suggestions": len(suggestions),
+	}).Info("[AUTOCOMPLETE] Autocomplete index rebuilt")
+
+	return nil
+}
+
+// productSuggestion product suggestion by given product pointer of models.Product.
+//
+// It returns models.AutocompleteSuggestion.
+func productSuggestion(product *models.Product) models.AutocompleteSuggestion {
+	return models.AutocompleteSuggestion{
+		Type: models.AutocompleteTypeProduct,
+		ID:   product.ID,
+		Name: product.Name,
+	}
+}
+
+// productCategorySuggestion product category suggestion by given productCategory pointer of models.ProductCategory.
+//
+// It returns models.AutocompleteSuggestion.
+func productCategorySuggestion(productCategory *models.ProductCategory) models.AutocompleteSuggestion {
+	return models.AutocompleteSuggestion{
+		Type: models.AutocompleteTypeProductCategory,
+		ID:   int64(productCategory.ID),
+		Name: productCategory.Name,
+	}
+}
+
+// syncAutocompleteSuggestion sync autocomplete suggestion by given previous, and current suggestion.
+//
+// An empty previous means the entity was created, an empty current means it was deleted.
+func (s *ProductService) syncAutocompleteSuggestion(ctx context.Context, previous models.AutocompleteSuggestion, current models.AutocompleteSuggestion) {
+	err := s.RedisBreaker.Execute(func() error {
+		if previous.ID != 0 && previous.Name != current.Name {
+			// keep the popularity when the entity is only renamed
+			errRedis := s.ProductRepository.RemoveAutocompleteSuggestion(ctx, previous, current.ID == 0)
+			if errRedis != nil {
+				return errRedis
+			}
+		}
+
+		if current.ID == 0 {
+			return nil
+		}
+
+		return s.ProductRepository.AddAutocompleteSuggestion(ctx, current)
+	})
+	if err != nil && !errors.Is(err, circuitbreaker.ErrOpen) {
+		log.Logger.WithFields(logrus.Fields{
+			"previous": previous,
+			"current":  current,
+		}).Errorf("s.syncAutocompleteSuggestion() got error %v", err)
+	}
+}
+
+// incrAutocompletePopularity incr autocomplete popularity by given productID, and delta.
+func (s *ProductService) incrAutocompletePopularity(ctx context.Context, productID int64, delta int) {
+	suggestion := models.AutocompleteSuggestion{
+		Type: models.AutocompleteTypeProduct,
+		ID:   productID,
+	}
+
+	err := s.RedisBreaker.Execute(func() error {
+		return s.ProductRepository.IncrAutocompletePopularity(ctx, suggestion, float64(delta))
+	})
+	if err != nil && !errors.Is(err, circuitbreaker.ErrOpen) {
+		log.Logger.WithFields(logrus.Fields{
+			"productID": productID,
+		}).Errorf("s.ProductRepository.IncrAutocompletePopularity() got error %v", err)
+	}
+}
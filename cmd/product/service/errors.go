@@ -0,0 +1,100 @@
+package service
+
+import (
+	// golang package
+	"errors"
+	"strings"
+
+	// external package
+	"gorm.io/gorm"
+)
+
+// Domain error kinds, test them with errors.Is.
+var (
+	ErrNotFound          = errors.New("not found")
+	ErrConflict          = errors.New("conflict")
+	ErrValidation        = errors.New("validation failed")
+	ErrInsufficientStock = errors.New("insufficient stock")
+	ErrUnavailable       = errors.New("unavailable")
+)
+
+var (
+	ErrProductNotFound         = NewError(ErrNotFound, "product_not_found", "product not found")
+	ErrProductCategoryNotFound = NewError(ErrNotFound, "product_category_not_found", "product category not found")
+	ErrWebhookNotFound         = NewError(ErrNotFound, "webhook_not_found", "webhook not found")
+	ErrTooManyStockStreams     = NewError(ErrUnavailable, "too_many_streams", "too many stock streams are open, retry later")
+)
+
+// Error is a domain error of one of the kinds above, with a stable code clients can rely on.
+type Error struct {
+	Kind    error
+	Code    string
+	Message string
+	Fields  []FieldError
+}
+
+// FieldError is what's wrong with a single request field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// NewError new error by given kind, code, and message.
+//
+// It returns pointer of Error.
+func NewError(kind error, code string, message string) *Error {
+	return &Error{
+		Kind:    kind,
+		Code:    code,
+		Message: message,
+	}
+}
+
+// NewValidationError new validation error by given message, and field errors.
+//
+// It returns pointer of Error of kind ErrValidation.
+func NewValidationError(message string, fields ...FieldError) *Error {
+	return &Error{
+		Kind:    ErrValidation,
+		Code:    "validation_failed",
+		Message: message,
+		Fields:  fields,
+	}
+}
+
+// Error error.
+//
+// It returns string.
+func (e *Error) Error() string {
+	if len(e.Fields) == 0 {
+		return e.Message
+	}
+
+	fields := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		fields[i] = field.Field + ": " + field.Message
+	}
+
+	return e.Message + " (" + strings.Join(fields, ", ") + ")"
+}
+
+// Unwrap unwrap.
+//
+// It returns error of the kind, so errors.Is(err, ErrNotFound) matches.
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+// translateDBError translate db error by given err, field, and message used when the row referenced by field is missing.
+//
+// It returns domain error for constraint violations, otherwise err unchanged.
+func translateDBError(err error, field string, message string) error {
+	switch {
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return NewError(ErrConflict, "duplicated", "it already exists")
+	case errors.Is(err, gorm.ErrForeignKeyViolated):
+		return NewValidationError("invalid reference", FieldError{Field: field, Message: message})
+	default:
+		return err
+	}
+}
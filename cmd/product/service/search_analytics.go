@@ -0,0 +1,220 @@
+package service
+
+import (
+	// golang package
+	"context"
+	"encoding/json"
+	"productfc/cmd/product/repository"
+	"productfc/config"
+	"productfc/infrastructure/log"
+	"productfc/models"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	// external package
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultSearchReportLimit          = 20
+	defaultSearchAnalyticsBufferSize  = 10000
+	defaultSearchAnalyticsBatchSize   = 200
+	defaultSearchAnalyticsFlushPeriod = 5 * time.Second
+)
+
+// SearchAnalyticsSink buffers search events in memory and writes them to DB in batches,
+// so recording a search never waits on DB.
+type SearchAnalyticsSink struct {
+	ProductRepository repository.ProductRepository
+	Config            config.SearchAnalyticsConfig
+
+	events  chan models.SearchEvent
+	dropped atomic.Int64
+}
+
+// NewSearchAnalyticsSink new search analytics sink by given ProductRepository, and SearchAnalyticsConfig.
+//
+// It returns pointer of SearchAnalyticsSink, or nil pointer when analytics is disabled.
+func NewSearchAnalyticsSink(productRepository repository.ProductRepository, analyticsConfig config.SearchAnalyticsConfig) *SearchAnalyticsSink {
+	if !analyticsConfig.Enabled {
+		return nil
+	}
+
+	if analyticsConfig.BufferSize <= 0 {
+		analyticsConfig.BufferSize = defaultSearchAnalyticsBufferSize
+	}
+
+	if analyticsConfig.BatchSize <= 0 {
+		analyticsConfig.BatchSize = defaultSearchAnalyticsBatchSize
+	}
+
+	if analyticsConfig.FlushInterval <= 0 {
+		analyticsConfig.FlushInterval = defaultSearchAnalyticsFlushPeriod
+	}
+
+	return &SearchAnalyticsSink{
+		ProductRepository: productRepository,
+		Config:            analyticsConfig,
+		events:            make(chan models.SearchEvent, analyticsConfig.BufferSize),
+	}
+}
+
+// Record record by given event models.SearchEvent.
+//
+// The event is dropped when the buffer is full. It's safe to call on a nil sink.
+func (a *SearchAnalyticsSink) Record(event models.SearchEvent) {
+	if a == nil {
+		return
+	}
+
+	select {
+	case a.events <- event:
+	default:
+		a.dropped.Add(1)
+	}
+}
+
+// Run run by given ctx.
+//
+// It flushes whenever a batch is full or FlushInterval elapses, and once more when ctx is done.
+func (a *SearchAnalyticsSink) Run(ctx context.Context) {
+	if a == nil {
+		return
+	}
+
+	ticker := time.NewTicker(a.Config.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]models.SearchEvent, 0, a.Config.BatchSize)
+	for {
+		select {
+		case event := <-a.events:
+			batch = append(batch, event)
+			if len(batch) >= a.Config.BatchSize {
+				batch = a.flush(ctx, batch)
+			}
+		case <-ticker.C:
+			batch = a.flush(ctx, batch)
+		case <-ctx.Done():
+			// drain what's buffered, DB writes outlive ctx
+			for {
+				select {
+				case event := <-a.events:
+					batch = append(batch, event)
+				default:
+					a.flush(context.WithoutCancel(ctx), batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+// flush flush by given ctx, and slice of batch.
+//
+// It returns the emptied batch, ready for reuse.
+func (a *SearchAnalyticsSink) flush(ctx context.Context, batch []models.SearchEvent) []models.SearchEvent {
+	if dropped := a.dropped.Swap(0); dropped > 0 {
+		log.Logger.WithFields(logrus.Fields{
+			"dropped": dropped,
+		}).Warn("search analytics buffer is full, events were dropped")
+	}
+
+	if len(batch) == 0 {
+		return batch
+	}
+
+	err := a.ProductRepository.InsertSearchEvents(ctx, batch)
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"events": len(batch),
+		}).Errorf("a.ProductRepository.InsertSearchEvents() got error %v", err)
+	}
+
+	return batch[:0]
+}
+
+// recordSearch record search by given param SearchProductParameter, result pointer of models.SearchProductResult, and latency.
+func (s *ProductService) recordSearch(param models.SearchProductParameter, result *models.SearchProductResult, latency time.Duration) {
+	term := param.Query
+	if term == "" {
+		term = param.Name
+	}
+
+	// term is the text typed, everything else is a filter
+	filters := param
+	filters.SearchID = ""
+	filters.Query = ""
+	filters.Name = ""
+	filters.Page = 0
+	filters.PageSize = 0
+	filters.Cursor = ""
+	filters.SortFields = nil
+	filtersJSON, _ := json.Marshal(filters)
+
+	s.SearchAnalytics.Record(models.SearchEvent{
+		SearchID:    param.SearchID,
+		Term:        strings.Join(strings.Fields(strings.ToLower(term)), " "),
+		Filters:     string(filtersJSON),
+		ResultCount: result.TotalCount,
+		LatencyMs:   float64(latency.Microseconds()) / 1000,
+		CreatedAt:   time.Now(),
+	})
+}
+
+// RecordSearchClick record search click by given SearchClickParameter.
+//
+// It returns nil error when successful.
+// Otherwise, error will be returned.
+func (s *ProductService) RecordSearchClick(ctx context.Context, param models.SearchClickParameter) error {
+	return s.ProductRepository.InsertSearchClick(ctx, &models.SearchClick{
+		SearchID:  param.SearchID,
+		ProductID: param.ProductID,
+		Position:  param.Position,
+		CreatedAt: time.Now(),
+	})
+}
+
+// GetTopSearchQueries get top search queries by given SearchReportParameter.
+//
+// It returns slice of models.SearchQueryStat, and nil error when successful.
+// Otherwise, nil value of models.SearchQueryStat slice, and error will be returned.
+func (s *ProductService) GetTopSearchQueries(ctx context.Context, param models.SearchReportParameter) ([]models.SearchQueryStat, error) {
+	return s.ProductRepository.FindTopSearchQueries(ctx, withSearchReportDefaults(param))
+}
+
+// GetZeroResultSearchQueries get zero result search queries by given SearchReportParameter.
+//
+// It returns slice of models.SearchQueryStat, and nil error when successful.
+// Otherwise, nil value of models.SearchQueryStat slice, and error will be returned.
+func (s *ProductService) GetZeroResultSearchQueries(ctx context.Context, param models.SearchReportParameter) ([]models.SearchQueryStat, error) {
+	return s.ProductRepository.FindZeroResultSearchQueries(ctx, withSearchReportDefaults(param))
+}
+
+// GetSearchClickThroughRates get search click through rates by given SearchReportParameter.
+//
+// It returns slice of models.SearchClickThroughStat, and nil error when successful.
+// Otherwise, nil value of models.SearchClickThroughStat slice, and error will be returned.
+func (s *ProductService) GetSearchClickThroughRates(ctx context.Context, param models.SearchReportParameter) ([]models.SearchClickThroughStat, error) {
+	return s.ProductRepository.FindSearchClickThroughRates(ctx, withSearchReportDefaults(param))
+}
+
+// withSearchReportDefaults with search report defaults by given SearchReportParameter.
+//
+// It returns SearchReportParameter covering the last 7 days up to now when no range is given.
+func withSearchReportDefaults(param models.SearchReportParameter) models.SearchReportParameter {
+	if param.To.IsZero() {
+		param.To = time.Now()
+	}
+
+	if param.From.IsZero() {
+		param.From = param.To.AddDate(0, 0, -7)
+	}
+
+	if param.Limit <= 0 {
+		param.Limit = defaultSearchReportLimit
+	}
+
+	return param
+}
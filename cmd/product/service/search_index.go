@@ -0,0 +1,186 @@
+package service
+
+import (
+	// golang package
+	"context"
+	"maps"
+	"productfc/cmd/product/repository"
+	"productfc/config"
+	"productfc/infrastructure/log"
+	"slices"
+	"sync"
+	"time"
+
+	// external package
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	searchIndexerInitialBackoff = 1 * time.Second
+	searchIndexerMaxBackoff     = 1 * time.Minute
+)
+
+// SearchIndexer keeps the search backend in sync with the product table off the request path.
+//
+// Changed products and categories are queued by id and written by a single worker, so the writes of
+// this instance never race each other, and retried with backoff until the backend takes them. The queue
+// lives in memory, what's left of it when the process dies is restored by a reindex.
+type SearchIndexer struct {
+	SearchBackend repository.SearchBackend
+
+	mu                 sync.Mutex
+	productIDs         map[int64]struct{}
+	productCategoryIDs map[int]struct{}
+	wake               chan struct{}
+}
+
+// NewSearchIndexer new search indexer by given SearchBackend, and SearchConfig.
+//
+// It returns pointer of SearchIndexer, or nil pointer when the backend searches the product table itself.
+func NewSearchIndexer(searchBackend repository.SearchBackend, searchConfig config.SearchConfig) *SearchIndexer {
+	if searchConfig.Backend != repository.SearchBackendIndex {
+		return nil
+	}
+
+	return &SearchIndexer{
+		SearchBackend:      searchBackend,
+		productIDs:         make(map[int64]struct{}),
+		productCategoryIDs: make(map[int]struct{}),
+		wake:               make(chan struct{}, 1),
+	}
+}
+
+// EnqueueProducts enqueue products by given slice of productIDs, created, changed or deleted.
+//
+// It's safe to call on a nil indexer.
+func (i *SearchIndexer) EnqueueProducts(productIDs ...int64) {
+	if i == nil {
+		return
+	}
+
+	i.mu.Lock()
+	for _, productID := range productIDs {
+		i.productIDs[productID] = struct{}{}
+	}
+	i.mu.Unlock()
+
+	i.signal()
+}
+
+// EnqueueProductCategory enqueue product category by given productCategoryID, whose products must be reindexed.
+//
+// It's safe to call on a nil indexer.
+func (i *SearchIndexer) EnqueueProductCategory(productCategoryID int) {
+	if i == nil {
+		return
+	}
+
+	i.mu.Lock()
+	i.productCategoryIDs[productCategoryID] = struct{}{}
+	i.mu.Unlock()
+
+	i.signal()
+}
+
+// signal signal the worker that something is queued.
+func (i *SearchIndexer) signal() {
+	select {
+	case i.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run run by given ctx.
+//
+// It writes whatever is queued, backing off after a failure, and makes a last attempt once ctx is done.
+func (i *SearchIndexer) Run(ctx context.Context) {
+	if i == nil {
+		return
+	}
+
+	backoff := searchIndexerInitialBackoff
+	for {
+		select {
+		case <-i.wake:
+		case <-ctx.Done():
+			i.flush(context.WithoutCancel(ctx))
+			return
+		}
+
+		if i.flush(ctx) {
+			backoff = searchIndexerInitialBackoff
+			continue
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			i.flush(context.WithoutCancel(ctx))
+			return
+		}
+
+		backoff = min(2*backoff, searchIndexerMaxBackoff)
+		i.signal()
+	}
+}
+
+// flush flush by given ctx.
+//
+// What fails is queued again.
+//
+// It returns true when everything queued was written.
+func (i *SearchIndexer) flush(ctx context.Context) bool {
+	i.mu.Lock()
+	productIDs := slices.Sorted(maps.Keys(i.productIDs))
+	productCategoryIDs := slices.Sorted(maps.Keys(i.productCategoryIDs))
+	clear(i.productIDs)
+	clear(i.productCategoryIDs)
+	i.mu.Unlock()
+
+	ok := true
+	for _, productCategoryID := range productCategoryIDs {
+		err := i.SearchBackend.IndexProductCategory(ctx, productCategoryID)
+		if err != nil {
+			log.Logger.WithFields(logrus.Fields{
+				"productCategoryID": productCategoryID,
+			}).Errorf("i.SearchBackend.IndexProductCategory() got error %v", err)
+			i.EnqueueProductCategory(productCategoryID)
+			ok = false
+		}
+	}
+
+	if len(productIDs) == 0 {
+		return ok
+	}
+
+	err := i.SearchBackend.IndexProducts(ctx, productIDs)
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"productIDs": productIDs,
+		}).Errorf("i.SearchBackend.IndexProducts() got error %v", err)
+		i.EnqueueProducts(productIDs...)
+		ok = false
+	}
+
+	return ok
+}
+
+// ReindexSearch reindex search.
+//
+// The search backend is rebuilt from the product table, e.g. after it was wiped or fell behind.
+//
+// It returns nil error when successful.
+// Otherwise, error will be returned.
+func (s *ProductService) ReindexSearch(ctx context.Context) error {
+	start := time.Now()
+	log.Logger.Info("[REINDEX] rebuilding search index")
+
+	err := s.SearchBackend.Reindex(ctx)
+	if err != nil {
+		return err
+	}
+
+	log.Logger.Infof("[REINDEX] search index rebuilt in %s", time.Since(start))
+
+	return nil
+}
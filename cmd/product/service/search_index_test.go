@@ -0,0 +1,137 @@
+package service
+
+import (
+	// golang package
+	"context"
+	"errors"
+	"io"
+	"os"
+	"productfc/cmd/product/repository"
+	"productfc/config"
+	"productfc/infrastructure/log"
+	"productfc/models"
+	"reflect"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	log.SetupLogger()
+	log.Logger.SetOutput(io.Discard)
+
+	os.Exit(m.Run())
+}
+
+// fakeSearchBackend records what it's asked to index, failing while fail is set.
+type fakeSearchBackend struct {
+	fail               bool
+	productIDs         [][]int64
+	productCategoryIDs []int
+}
+
+func (b *fakeSearchBackend) SearchProduct(context.Context, models.SearchProductParameter) (*models.SearchProductResult, error) {
+	return &models.SearchProductResult{}, nil
+}
+
+func (b *fakeSearchBackend) IndexProducts(_ context.Context, productIDs []int64) error {
+	b.productIDs = append(b.productIDs, productIDs)
+	if b.fail {
+		return errors.New("engine is down")
+	}
+
+	return nil
+}
+
+func (b *fakeSearchBackend) IndexProductCategory(_ context.Context, productCategoryID int) error {
+	b.productCategoryIDs = append(b.productCategoryIDs, productCategoryID)
+	if b.fail {
+		return errors.New("engine is down")
+	}
+
+	return nil
+}
+
+func (b *fakeSearchBackend) Reindex(context.Context) error {
+	return nil
+}
+
+func TestNewSearchIndexer(t *testing.T) {
+	if indexer := NewSearchIndexer(&fakeSearchBackend{}, config.SearchConfig{Backend: repository.SearchBackendSQL}); indexer != nil {
+		t.Fatalf("NewSearchIndexer() of the sql backend = %+v, want nil", indexer)
+	}
+
+	// a nil indexer takes and drops everything
+	var indexer *SearchIndexer
+	indexer.EnqueueProducts(1)
+	indexer.EnqueueProductCategory(1)
+	indexer.Run(context.Background())
+}
+
+func TestSearchIndexerFlush(t *testing.T) {
+	backend := &fakeSearchBackend{}
+	indexer := NewSearchIndexer(backend, config.SearchConfig{Backend: repository.SearchBackendIndex})
+
+	indexer.EnqueueProducts(3, 1)
+	indexer.EnqueueProducts(1, 2)
+	indexer.EnqueueProductCategory(7)
+	indexer.EnqueueProductCategory(7)
+
+	if !indexer.flush(context.Background()) {
+		t.Fatal("flush() = false, want true")
+	}
+
+	// queued ids are written once each, in id order
+	if want := [][]int64{{1, 2, 3}}; !reflect.DeepEqual(backend.productIDs, want) {
+		t.Fatalf("indexed products = %v, want %v", backend.productIDs, want)
+	}
+
+	if want := []int{7}; !reflect.DeepEqual(backend.productCategoryIDs, want) {
+		t.Fatalf("indexed categories = %v, want %v", backend.productCategoryIDs, want)
+	}
+
+	if !indexer.flush(context.Background()) || len(backend.productIDs) != 1 {
+		t.Fatalf("flush() of an empty queue indexed %v, want nothing more", backend.productIDs)
+	}
+}
+
+func TestSearchIndexerRetriesFailures(t *testing.T) {
+	backend := &fakeSearchBackend{fail: true}
+	indexer := NewSearchIndexer(backend, config.SearchConfig{Backend: repository.SearchBackendIndex})
+
+	indexer.EnqueueProducts(1)
+	indexer.EnqueueProductCategory(7)
+
+	if indexer.flush(context.Background()) {
+		t.Fatal("flush() = true while the backend fails, want false")
+	}
+
+	// changed again while the backend was down
+	indexer.EnqueueProducts(2)
+
+	backend.fail = false
+	if !indexer.flush(context.Background()) {
+		t.Fatal("flush() = false once the backend is back, want true")
+	}
+
+	if want := [][]int64{{1}, {1, 2}}; !reflect.DeepEqual(backend.productIDs, want) {
+		t.Fatalf("indexed products = %v, want %v", backend.productIDs, want)
+	}
+
+	if want := []int{7, 7}; !reflect.DeepEqual(backend.productCategoryIDs, want) {
+		t.Fatalf("indexed categories = %v, want %v", backend.productCategoryIDs, want)
+	}
+}
+
+func TestSearchIndexerRunFlushesOnShutdown(t *testing.T) {
+	backend := &fakeSearchBackend{}
+	indexer := NewSearchIndexer(backend, config.SearchConfig{Backend: repository.SearchBackendIndex})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	indexer.EnqueueProducts(1)
+	indexer.Run(ctx)
+
+	if want := [][]int64{{1}}; !reflect.DeepEqual(backend.productIDs, want) {
+		t.Fatalf("indexed products = %v, want %v", backend.productIDs, want)
+	}
+}
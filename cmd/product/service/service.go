@@ -3,51 +3,116 @@ package service
 import (
 	// golang package
 	"context"
+	"errors"
+	"fmt"
 	"productfc/cmd/product/repository"
+	"productfc/config"
+	"productfc/infrastructure/circuitbreaker"
 	"productfc/infrastructure/log"
 	"productfc/models"
+	"strconv"
+	"time"
 
 	// external package
+	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/singleflight"
+	"gorm.io/gorm"
 )
 
 type ProductService struct {
 	ProductRepository repository.ProductRepository
+	SearchBackend     repository.SearchBackend
+	SearchIndexer     *SearchIndexer
+	CacheConfig       config.CacheConfig
+	SearchConfig      config.SearchConfig
+	WebhookConfig     config.WebhookConfig
+	RedisBreaker      *circuitbreaker.CircuitBreaker
+	SearchAnalytics   *SearchAnalyticsSink
+	StockStream       *ProductStockStream
+	Webhooks          *WebhookDispatcher
+
+	revalidations *singleflight.Group // one DB read per stale product at a time
 }
 
-// NewProductService new product service by given ProductRepository.
+// NewProductService new product service by given ProductRepository, SearchBackend, CacheConfig, SearchConfig, StockStreamConfig, and WebhookConfig.
 //
 // It returns pointer of ProductService when successful.
 // Otherwise, nil pointer of ProductService will be returned.
-func NewProductService(productRepository repository.ProductRepository) *ProductService {
+func NewProductService(productRepository repository.ProductRepository, searchBackend repository.SearchBackend, cacheConfig config.CacheConfig, searchConfig config.SearchConfig, stockStreamConfig config.StockStreamConfig, webhookConfig config.WebhookConfig) *ProductService {
 	return &ProductService{
 		ProductRepository: productRepository,
+		SearchBackend:     searchBackend,
+		SearchIndexer:     NewSearchIndexer(searchBackend, searchConfig),
+		CacheConfig:       cacheConfig,
+		SearchConfig:      searchConfig,
+		WebhookConfig:     webhookConfig,
+		RedisBreaker:      circuitbreaker.New("redis", cacheConfig.RedisBreaker.FailureThreshold, cacheConfig.RedisBreaker.OpenTimeout),
+		SearchAnalytics:   NewSearchAnalyticsSink(productRepository, searchConfig.Analytics),
+		StockStream:       NewProductStockStream(productRepository, stockStreamConfig),
+		Webhooks:          NewWebhookDispatcher(productRepository, webhookConfig),
+		revalidations:     &singleflight.Group{},
 	}
 }
 
 // DeductProductStockByProductID deduct product stock by product id by given productID, and qty.
 //
 // It returns nil error when successful.
-// Otherwise, error will be returned.
+// Otherwise, error will be returned, ErrProductNotFound or ErrInsufficientStock when nothing was deducted.
 func (s *ProductService) DeductProductStockByProductID(ctx context.Context, productID int64, qty int) error {
-	err := s.ProductRepository.DeductProductStockByProductID(ctx, productID, qty)
+	if qty <= 0 {
+		return NewValidationError("invalid quantity", FieldError{Field: "qty", Message: "must be positive"})
+	}
+
+	updatedProduct, err := s.ProductRepository.DeductProductStockByProductID(ctx, productID, qty)
 	if err != nil {
 		return err
 	}
 
+	if updatedProduct.ID == 0 {
+		product, err := s.ProductRepository.FindProductByID(ctx, productID)
+		if err != nil {
+			return err
+		}
+
+		if product.ID == 0 {
+			return ErrProductNotFound
+		}
+
+		return NewError(ErrInsufficientStock, "insufficient_stock", fmt.Sprintf("product %d has %d in stock, %d requested", productID, product.Stock, qty))
+	}
+
+	s.incrAutocompletePopularity(ctx, productID, qty)
+	s.bumpProductGeneration(ctx)
+	s.SearchIndexer.EnqueueProducts(productID)
+	s.syncProductStock(ctx, updatedProduct)
+
 	return nil
 }
 
 // AddProductStockByProductID add product stock by product id by given productID, and qty.
 //
 // It returns nil error when successful.
-// Otherwise, error will be returned.
+// Otherwise, error will be returned, ErrProductNotFound when nothing was added.
 func (s *ProductService) AddProductStockByProductID(ctx context.Context, productID int64, qty int) error {
-	err := s.ProductRepository.AddProductStockByProductID(ctx, productID, qty)
+	if qty <= 0 {
+		return NewValidationError("invalid quantity", FieldError{Field: "qty", Message: "must be positive"})
+	}
+
+	updatedProduct, err := s.ProductRepository.AddProductStockByProductID(ctx, productID, qty)
 	if err != nil {
 		return err
 	}
 
+	if updatedProduct.ID == 0 {
+		return ErrProductNotFound
+	}
+
+	s.incrAutocompletePopularity(ctx, productID, -qty)
+	s.bumpProductGeneration(ctx)
+	s.SearchIndexer.EnqueueProducts(productID)
+	s.syncProductStock(ctx, updatedProduct)
+
 	return nil
 }
 
@@ -55,16 +120,33 @@ func (s *ProductService) AddProductStockByProductID(ctx context.Context, product
 // kita akan tentukan mau menggunakan resource yg mana
 // db or redis
 
+// GetProductByID get product by id by given productID.
+//
+// Redis is read through a circuit breaker, so DB is read directly while redis is down,
+// and expired entries are served stale when DB is slow to answer.
+//
+// It returns pointer of models.Product, and nil error when successful.
+// Otherwise, nil pointer of models.Product, and error will be returned.
 func (s *ProductService) GetProductByID(ctx context.Context, productID int64) (*models.Product, error) {
-	// get from Redis
-	product, err := s.ProductRepository.GetProductByIDFromRedis(ctx, productID)
-	if err != nil {
+	// get from Redis, skipped while the breaker is open
+	var product *models.Product
+	var isStale bool
+	err := s.RedisBreaker.Execute(func() error {
+		var errRedis error
+		product, isStale, errRedis = s.ProductRepository.GetProductByIDFromRedis(ctx, productID)
+		return errRedis
+	})
+	if err != nil && !errors.Is(err, circuitbreaker.ErrOpen) {
 		log.Logger.WithFields(logrus.Fields{
 			"productID": productID,
 		}).Errorf("s.ProductRepository.GetProductByIDFromRedis() got error %v", err)
 	}
 
-	if product.ID != 0 {
+	if err == nil && product.ID != 0 {
+		if isStale {
+			return s.revalidateProductByID(ctx, product), nil
+		}
+
 		return product, nil
 	}
 
@@ -74,17 +156,149 @@ func (s *ProductService) GetProductByID(ctx context.Context, productID int64) (*
 		return nil, err
 	}
 
-	ctxConcurrent := context.WithValue(ctx, context.Background(), ctx.Value("request_id"))
-	go func(ctx context.Context, product *models.Product, productID int64) {
-		errConcurrent := s.ProductRepository.SetProductByID(ctx, product, productID)
-		if errConcurrent != nil {
+	if product.ID != 0 {
+		go s.setProductCache(context.WithoutCancel(ctx), product)
+	}
+
+	return product, nil
+}
+
+// revalidateProductByID revalidate product by id by given staleProduct pointer of models.Product.
+//
+// The product is reloaded from DB in the background, concurrent reads of the same stale product share a single
+// reload bounded by RevalidateTimeout. If DB answers within StaleReadTimeout the fresh product is returned,
+// otherwise the stale one is served and the cache is refreshed once DB responds.
+//
+// It returns pointer of models.Product.
+func (s *ProductService) revalidateProductByID(ctx context.Context, staleProduct *models.Product) *models.Product {
+	freshProduct := s.revalidations.DoChan(strconv.FormatInt(staleProduct.ID, 10), func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.CacheConfig.RevalidateTimeout)
+		defer cancel()
+
+		product, err := s.ProductRepository.FindProductByID(ctx, staleProduct.ID)
+		if err != nil {
 			log.Logger.WithFields(logrus.Fields{
-				"product": product,
-			}).Errorf("s.ProductRepository.SetProductByID() got error %v", errConcurrent)
+				"productID": staleProduct.ID,
+			}).Errorf("s.ProductRepository.FindProductByID() got error %v", err)
+
+			return nil, err
 		}
-	}(ctxConcurrent, product, productID)
 
-	return product, nil
+		if product.ID != 0 {
+			s.setProductCache(ctx, product)
+		}
+
+		return product, nil
+	})
+
+	select {
+	case result := <-freshProduct:
+		if result.Err != nil {
+			return staleProduct
+		}
+
+		return result.Val.(*models.Product)
+	case <-time.After(s.CacheConfig.StaleReadTimeout):
+		log.Logger.WithFields(logrus.Fields{
+			"productID": staleProduct.ID,
+		}).Warn("DB is slow, serving stale product from cache")
+
+		return staleProduct
+	}
+}
+
+// setProductCache set product cache by given product pointer of models.Product.
+func (s *ProductService) setProductCache(ctx context.Context, product *models.Product) {
+	err := s.RedisBreaker.Execute(func() error {
+		return s.ProductRepository.SetProductByID(ctx, product, product.ID)
+	})
+	if err != nil && !errors.Is(err, circuitbreaker.ErrOpen) {
+		log.Logger.WithFields(logrus.Fields{
+			"product": product,
+		}).Errorf("s.ProductRepository.SetProductByID() got error %v", err)
+	}
+}
+
+// syncProductStock sync product stock by given product pointer of models.Product, as returned by the stock update.
+//
+// It's written through to redis versioned by its row version, and published to the stock streams, whose
+// subscribers drop what's older than the last event they got, since publishing isn't ordered.
+func (s *ProductService) syncProductStock(ctx context.Context, product *models.Product) {
+	s.setProductCache(ctx, product)
+	go s.publishProductStock(context.WithoutCancel(ctx), product)
+}
+
+// GetProductsByIDs get products by ids by given slice of productIDs.
+//
+// Cached products are resolved with a single MGET, the misses are loaded with one DB query
+// and written back to redis in a pipeline.
+//
+// It returns slice of models.BatchProductResult in request order, and nil error when successful.
+// Otherwise, nil value of models.BatchProductResult slice, and error will be returned.
+func (s *ProductService) GetProductsByIDs(ctx context.Context, productIDs []int64) ([]models.BatchProductResult, error) {
+	// get from Redis
+	var products map[int64]models.Product
+	err := s.RedisBreaker.Execute(func() error {
+		var errRedis error
+		products, errRedis = s.ProductRepository.GetProductsByIDsFromRedis(ctx, productIDs)
+		return errRedis
+	})
+	if err != nil {
+		if !errors.Is(err, circuitbreaker.ErrOpen) {
+			log.Logger.WithFields(logrus.Fields{
+				"productIDs": productIDs,
+			}).Errorf("s.ProductRepository.GetProductsByIDsFromRedis() got error %v", err)
+		}
+
+		products = make(map[int64]models.Product, len(productIDs))
+	}
+
+	missedProductIDs := make([]int64, 0, len(productIDs))
+	for _, productID := range productIDs {
+		if _, ok := products[productID]; !ok {
+			missedProductIDs = append(missedProductIDs, productID)
+		}
+	}
+
+	// get misses from DB
+	if len(missedProductIDs) > 0 {
+		dbProducts, err := s.ProductRepository.FindProductsByIDs(ctx, missedProductIDs)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, product := range dbProducts {
+			products[product.ID] = product
+		}
+
+		if len(dbProducts) > 0 {
+			go func(ctx context.Context) {
+				errConcurrent := s.RedisBreaker.Execute(func() error {
+					return s.ProductRepository.SetProducts(ctx, dbProducts)
+				})
+				if errConcurrent != nil && !errors.Is(errConcurrent, circuitbreaker.ErrOpen) {
+					log.Logger.WithFields(logrus.Fields{
+						"productIDs": missedProductIDs,
+					}).Errorf("s.ProductRepository.SetProducts() got error %v", errConcurrent)
+				}
+			}(context.WithoutCancel(ctx))
+		}
+	}
+
+	results := make([]models.BatchProductResult, len(productIDs))
+	for i, productID := range productIDs {
+		results[i].ID = productID
+
+		product, ok := products[productID]
+		if !ok {
+			continue
+		}
+
+		results[i].Found = true
+		results[i].Product = &product
+	}
+
+	return results, nil
 }
 
 // GetProductCategoryByID get product category by id by given productCategoryID.
@@ -92,101 +306,499 @@ func (s *ProductService) GetProductByID(ctx context.Context, productID int64) (*
 // It returns pointer of models.ProductCategory, and nil error when successful.
 // Otherwise, nil pointer of models.ProductCategory, and error will be returned.
 func (s *ProductService) GetProductCategoryByID(ctx context.Context, productCategoryID int) (*models.ProductCategory, error) {
-	productCategory, err := s.ProductRepository.FindProductCategoryByID(ctx, productCategoryID)
+	// get from Redis
+	var productCategory *models.ProductCategory
+	err := s.RedisBreaker.Execute(func() error {
+		var errRedis error
+		productCategory, errRedis = s.ProductRepository.GetProductCategoryByIDFromRedis(ctx, productCategoryID)
+		return errRedis
+	})
+	if err != nil && !errors.Is(err, circuitbreaker.ErrOpen) {
+		log.Logger.WithFields(logrus.Fields{
+			"productCategoryID": productCategoryID,
+		}).Errorf("s.ProductRepository.GetProductCategoryByIDFromRedis() got error %v", err)
+	}
+
+	if err == nil && productCategory.ID != 0 {
+		return productCategory, nil
+	}
+
+	// get from DB
+	productCategory, err = s.ProductRepository.FindProductCategoryByID(ctx, productCategoryID)
 	if err != nil {
 		return nil, err
 	}
 
+	if productCategory.ID != 0 {
+		go s.setProductCategoryCache(context.WithoutCancel(ctx), productCategory)
+	}
+
 	return productCategory, nil
 }
 
+// GetProductCategoriesByIDs get product categories by ids by given slice of productCategoryIDs.
+//
+// It reads the database in one query, it backs the GraphQL category loader.
+//
+// It returns map of models.ProductCategory by id, and nil error when successful. Missing IDs are simply absent from the map.
+// Otherwise, nil map, and error will be returned.
+func (s *ProductService) GetProductCategoriesByIDs(ctx context.Context, productCategoryIDs []int) (map[int]models.ProductCategory, error) {
+	productCategories, err := s.ProductRepository.FindProductCategoriesByIDs(ctx, productCategoryIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	productCategoryByID := make(map[int]models.ProductCategory, len(productCategories))
+	for _, productCategory := range productCategories {
+		productCategoryByID[productCategory.ID] = productCategory
+	}
+
+	return productCategoryByID, nil
+}
+
+// setProductCategoryCache set product category cache by given productCategory pointer of models.ProductCategory.
+func (s *ProductService) setProductCategoryCache(ctx context.Context, productCategory *models.ProductCategory) {
+	err := s.RedisBreaker.Execute(func() error {
+		return s.ProductRepository.SetProductCategoryByID(ctx, productCategory, productCategory.ID)
+	})
+	if err != nil && !errors.Is(err, circuitbreaker.ErrOpen) {
+		log.Logger.WithFields(logrus.Fields{
+			"productCategory": productCategory,
+		}).Errorf("s.ProductRepository.SetProductCategoryByID() got error %v", err)
+	}
+}
+
 // CreateNewProduct create new product by given param pointer of models.Product.
 //
 // It returns int64, and nil error when successful.
-// Otherwise, empty int64, and error will be returned.
+// Otherwise, empty int64, and error will be returned, of kind ErrValidation when param is invalid.
 func (s *ProductService) CreateNewProduct(ctx context.Context, param *models.Product) (int64, error) {
-	productID, err := s.ProductRepository.InsertNewProduct(ctx, param)
+	err := s.validateProduct(ctx, param)
 	if err != nil {
 		return 0, err
 	}
 
+	productID, err := s.ProductRepository.InsertNewProduct(ctx, param)
+	if err != nil {
+		return 0, translateDBError(err, "category_id", "product category doesn't exist")
+	}
+
+	s.syncAutocompleteSuggestion(ctx, models.AutocompleteSuggestion{}, productSuggestion(param))
+	s.bumpProductGeneration(ctx)
+	s.SearchIndexer.EnqueueProducts(productID)
+
+	product := *param
+	s.notifyWebhooks(ctx, models.WebhookEventProductCreated, &product)
+
 	return productID, nil
 }
 
 // CreateNewProductCategory create new product category by given param pointer of models.ProductCategory.
 //
 // It returns int, and nil error when successful.
-// Otherwise, empty int, and error will be returned.
+// Otherwise, empty int, and error will be returned, of kind ErrValidation when param is invalid.
 func (s *ProductService) CreateNewProductCategory(ctx context.Context, param *models.ProductCategory) (int, error) {
-	productCategoryID, err := s.ProductRepository.InsertNewProductCategory(ctx, param)
+	err := validateProductCategory(param)
 	if err != nil {
 		return 0, err
 	}
 
+	productCategoryID, err := s.ProductRepository.InsertNewProductCategory(ctx, param)
+	if err != nil {
+		return 0, translateDBError(err, "", "")
+	}
+
+	s.syncAutocompleteSuggestion(ctx, models.AutocompleteSuggestion{}, productCategorySuggestion(param))
+	s.bumpProductCategoryGeneration(ctx)
+
+	productCategory := *param
+	s.notifyWebhooks(ctx, models.WebhookEventCategoryCreated, &productCategory)
+
 	return productCategoryID, nil
 }
 
 // EditProdut edit produt by given product pointer of models.Product.
 //
+// The stored product is written through to redis, versioned by its row version.
+//
 // It returns pointer of models.Product, and nil error when successful.
-// Otherwise, nil pointer of models.Product, and error will be returned.
+// Otherwise, nil pointer of models.Product, and error will be returned, ErrProductNotFound when it doesn't exist, of kind ErrValidation when product is invalid.
 func (s *ProductService) EditProdut(ctx context.Context, product *models.Product) (*models.Product, error) {
-	product, err := s.ProductRepository.UpdateProduct(ctx, product)
+	previousProduct, err := s.ProductRepository.FindProductByID(ctx, product.ID)
 	if err != nil {
 		return nil, err
 	}
 
-	return product, nil
+	if previousProduct.ID == 0 {
+		return nil, ErrProductNotFound
+	}
+
+	err = s.validateProduct(ctx, product)
+	if err != nil {
+		return nil, err
+	}
+
+	updatedProduct, err := s.ProductRepository.UpdateProduct(ctx, product)
+	if err != nil {
+		return nil, translateDBError(err, "category_id", "product category doesn't exist")
+	}
+
+	// deleted in between
+	if updatedProduct.ID == 0 {
+		return nil, ErrProductNotFound
+	}
+
+	s.syncEditedProduct(ctx, previousProduct, updatedProduct)
+
+	return updatedProduct, nil
+}
+
+// PatchProduct patch product by given productID, and fields.
+//
+// Only the given columns are written, the rest of the product is left untouched.
+//
+// It returns pointer of models.Product, and nil error when successful.
+// Otherwise, nil pointer of models.Product, and error will be returned, ErrProductNotFound when it doesn't exist, of kind ErrValidation when the patched product is invalid.
+func (s *ProductService) PatchProduct(ctx context.Context, productID int64, fields map[string]interface{}) (*models.Product, error) {
+	previousProduct, err := s.ProductRepository.FindProductByID(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	if previousProduct.ID == 0 {
+		return nil, ErrProductNotFound
+	}
+
+	// an empty patch changes nothing
+	if len(fields) == 0 {
+		return previousProduct, nil
+	}
+
+	// validated as the product the patch would produce
+	patchedProduct := *previousProduct
+	applyProductFields(&patchedProduct, fields)
+	err = s.validateProduct(ctx, &patchedProduct)
+	if err != nil {
+		return nil, err
+	}
+
+	updatedProduct, err := s.ProductRepository.UpdateProductFields(ctx, productID, fields)
+	if err != nil {
+		return nil, translateDBError(err, "category_id", "product category doesn't exist")
+	}
+
+	// deleted in between
+	if updatedProduct.ID == 0 {
+		return nil, ErrProductNotFound
+	}
+
+	s.syncEditedProduct(ctx, previousProduct, updatedProduct)
+
+	return updatedProduct, nil
+}
+
+// syncEditedProduct sync edited product by given previousProduct, and product pointer of models.Product.
+//
+// The product is the row returned by the update, so it is written through to redis under its own version,
+// then to autocomplete, the search backend and webhooks. A stock or price change is published to the stock streams.
+func (s *ProductService) syncEditedProduct(ctx context.Context, previousProduct *models.Product, product *models.Product) {
+	s.setProductCache(ctx, product)
+	s.syncAutocompleteSuggestion(ctx, productSuggestion(previousProduct), productSuggestion(product))
+	s.bumpProductGeneration(ctx)
+	s.SearchIndexer.EnqueueProducts(product.ID)
+	s.notifyWebhooks(ctx, models.WebhookEventProductUpdated, product)
+
+	if product.Stock != previousProduct.Stock || product.Price != previousProduct.Price {
+		go s.publishProductStock(context.WithoutCancel(ctx), product)
+	}
 }
 
 // EditProductCategory edit product category by given productCategory pointer of models.ProductCategory.
 //
+// The stored product category is written through to redis, versioned by its row version.
+//
 // It returns pointer of models.ProductCategory, and nil error when successful.
-// Otherwise, nil pointer of models.ProductCategory, and error will be returned.
+// Otherwise, nil pointer of models.ProductCategory, and error will be returned, ErrProductCategoryNotFound when it doesn't exist, of kind ErrValidation when productCategory is invalid.
 func (s *ProductService) EditProductCategory(ctx context.Context, productCategory *models.ProductCategory) (*models.ProductCategory, error) {
-	productCategory, err := s.ProductRepository.UpdateProductCategory(ctx, productCategory)
+	err := validateProductCategory(productCategory)
+	if err != nil {
+		return nil, err
+	}
+
+	previousProductCategory, err := s.ProductRepository.FindProductCategoryByID(ctx, productCategory.ID)
 	if err != nil {
 		return nil, err
 	}
 
+	if previousProductCategory.ID == 0 {
+		return nil, ErrProductCategoryNotFound
+	}
+
+	productCategory, err = s.ProductRepository.UpdateProductCategory(ctx, productCategory)
+	if err != nil {
+		return nil, translateDBError(err, "", "")
+	}
+
+	// deleted in between
+	if productCategory.ID == 0 {
+		return nil, ErrProductCategoryNotFound
+	}
+
+	s.setProductCategoryCache(ctx, productCategory)
+	s.syncAutocompleteSuggestion(ctx, productCategorySuggestion(previousProductCategory), productCategorySuggestion(productCategory))
+	s.bumpProductCategoryGeneration(ctx)
+
+	if previousProductCategory.Name != productCategory.Name {
+		s.SearchIndexer.EnqueueProductCategory(productCategory.ID)
+	}
+
+	s.notifyWebhooks(ctx, models.WebhookEventCategoryUpdated, productCategory)
+
 	return productCategory, nil
 }
 
 // DeleteProduct delete product by given productID.
 //
 // It returns nil error when successful.
-// Otherwise, error will be returned.
+// Otherwise, error will be returned, ErrProductNotFound when it doesn't exist.
 func (s *ProductService) DeleteProduct(ctx context.Context, productID int64) error {
-	err := s.ProductRepository.DeleteProduct(ctx, productID)
+	product, err := s.ProductRepository.FindProductByID(ctx, productID)
 	if err != nil {
 		return err
 	}
 
+	if product.ID == 0 {
+		return ErrProductNotFound
+	}
+
+	version, err := s.ProductRepository.DeleteProduct(ctx, productID)
+	if err != nil {
+		return err
+	}
+
+	// deleted in between
+	if version == 0 {
+		return ErrProductNotFound
+	}
+
+	err = s.RedisBreaker.Execute(func() error {
+		return s.ProductRepository.DeleteProductByIDFromRedis(ctx, productID, version)
+	})
+	if err != nil && !errors.Is(err, circuitbreaker.ErrOpen) {
+		log.Logger.WithFields(logrus.Fields{
+			"productID": productID,
+		}).Errorf("s.ProductRepository.DeleteProductByIDFromRedis() got error %v", err)
+	}
+
+	s.syncAutocompleteSuggestion(ctx, productSuggestion(product), models.AutocompleteSuggestion{})
+	s.bumpProductGeneration(ctx)
+	s.SearchIndexer.EnqueueProducts(productID)
+	s.notifyWebhooks(ctx, models.WebhookEventProductDeleted, product)
+
 	return nil
 }
 
 // DeleteProductCategory delete product category by given productCategoryID.
 //
 // It returns nil error when successful.
-// Otherwise, error will be returned.
+// Otherwise, error will be returned, ErrProductCategoryNotFound when it doesn't exist.
 func (s *ProductService) DeleteProductCategory(ctx context.Context, productCategoryID int) error {
-	err := s.ProductRepository.DeleteProductCategory(ctx, productCategoryID)
+	productCategory, err := s.ProductRepository.FindProductCategoryByID(ctx, productCategoryID)
+	if err != nil {
+		return err
+	}
+
+	if productCategory.ID == 0 {
+		return ErrProductCategoryNotFound
+	}
+
+	version, err := s.ProductRepository.DeleteProductCategory(ctx, productCategoryID)
+	if errors.Is(err, gorm.ErrForeignKeyViolated) {
+		return NewError(ErrConflict, "product_category_in_use", "product category still has products")
+	}
+
 	if err != nil {
 		return err
 	}
 
+	// deleted in between
+	if version == 0 {
+		return ErrProductCategoryNotFound
+	}
+
+	err = s.RedisBreaker.Execute(func() error {
+		return s.ProductRepository.DeleteProductCategoryByIDFromRedis(ctx, productCategoryID, version)
+	})
+	if err != nil && !errors.Is(err, circuitbreaker.ErrOpen) {
+		log.Logger.WithFields(logrus.Fields{
+			"productCategoryID": productCategoryID,
+		}).Errorf("s.ProductRepository.DeleteProductCategoryByIDFromRedis() got error %v", err)
+	}
+
+	s.syncAutocompleteSuggestion(ctx, productCategorySuggestion(productCategory), models.AutocompleteSuggestion{})
+	s.bumpProductCategoryGeneration(ctx)
+	s.notifyWebhooks(ctx, models.WebhookEventCategoryDeleted, productCategory)
+
 	return nil
 }
 
 // SearchProduct search product by given SearchProductParameter.
 //
-// It returns slice of models.Product, int, and nil error when successful.
-// Otherwise, nil value of models.Product slice, empty int, and error will be returned.
-func (s *ProductService) SearchProduct(ctx context.Context, param models.SearchProductParameter) ([]models.Product, int, error) {
-	products, totalCount, err := s.ProductRepository.SearchProduct(ctx, param)
+// Every search is recorded for analytics, whether it was answered by cache or not,
+// under a new SearchID when the caller didn't give one.
+//
+// It returns pointer of models.SearchProductResult carrying the SearchID, and nil error when successful.
+// Otherwise, nil pointer of models.SearchProductResult, and error will be returned.
+func (s *ProductService) SearchProduct(ctx context.Context, param models.SearchProductParameter) (*models.SearchProductResult, error) {
+	if param.SearchID == "" {
+		param.SearchID = uuid.New().String()
+	}
+
+	start := time.Now()
+	result, err := s.searchProduct(ctx, param)
+	if errors.Is(err, repository.ErrInvalidCursor) {
+		return nil, NewValidationError("invalid cursor", FieldError{Field: "cursor", Message: err.Error()})
+	}
+
+	if errors.Is(err, repository.ErrInvalidSort) {
+		return nil, NewValidationError("invalid sort", FieldError{Field: "sort", Message: "relevance needs q, or name with mode=fuzzy"})
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.recordSearch(param, result, time.Since(start))
+
+	// a copy, the result may still be being written to the search cache
+	searchResult := *result
+	searchResult.SearchID = param.SearchID
+
+	return &searchResult, nil
+}
+
+// searchProduct search product by given SearchProductParameter.
+//
+// It returns pointer of models.SearchProductResult, and nil error when successful.
+// Otherwise, nil pointer of models.SearchProductResult, and error will be returned.
+func (s *ProductService) searchProduct(ctx context.Context, param models.SearchProductParameter) (*models.SearchProductResult, error) {
+	param.SimilarityThreshold = s.SearchConfig.SimilarityThreshold
+
+	// search cache disabled
+	if s.CacheConfig.SearchTTL <= 0 {
+		return s.SearchBackend.SearchProduct(ctx, param)
+	}
+
+	// get from Redis, keyed by the current generations so any write invalidates it
+	var productGeneration, productCategoryGeneration int64
+	err := s.RedisBreaker.Execute(func() error {
+		var errRedis error
+		productGeneration, productCategoryGeneration, errRedis = s.ProductRepository.GetSearchGenerations(ctx)
+		return errRedis
+	})
+	if err != nil {
+		if !errors.Is(err, circuitbreaker.ErrOpen) {
+			log.Logger.WithFields(logrus.Fields{
+				"param": param,
+			}).Errorf("s.ProductRepository.GetSearchGenerations() got error %v", err)
+		}
+
+		return s.SearchBackend.SearchProduct(ctx, param)
+	}
+
+	var result *models.SearchProductResult
+	var found bool
+	err = s.RedisBreaker.Execute(func() error {
+		var errRedis error
+		result, found, errRedis = s.ProductRepository.GetSearchProductFromRedis(ctx, productGeneration, productCategoryGeneration, param)
+		return errRedis
+	})
+	if err != nil && !errors.Is(err, circuitbreaker.ErrOpen) {
+		log.Logger.WithFields(logrus.Fields{
+			"param": param,
+		}).Errorf("s.ProductRepository.GetSearchProductFromRedis() got error %v", err)
+	}
+
+	if found {
+		return result, nil
+	}
+
+	// get from DB
+	result, err = s.SearchBackend.SearchProduct(ctx, param)
+	if err != nil {
+		return nil, err
+	}
+
+	go func(ctx context.Context) {
+		errConcurrent := s.ProductRepository.SetSearchProduct(ctx, productGeneration, productCategoryGeneration, param, result, s.CacheConfig.SearchTTL)
+		if errConcurrent != nil {
+			log.Logger.WithFields(logrus.Fields{
+				"param": param,
+			}).Errorf("s.ProductRepository.SetSearchProduct() got error %v", errConcurrent)
+		}
+	}(context.WithoutCancel(ctx))
+
+	return result, nil
+}
+
+// SearchProductFacets search product facets by given SearchProductParameter, and slice of facets.
+//
+// Facets are counted over the same filters as SearchProduct, ignoring pagination and ordering.
+//
+// It returns pointer of models.SearchProductFacets, and nil error when successful.
+// Otherwise, nil pointer of models.SearchProductFacets, and error will be returned.
+func (s *ProductService) SearchProductFacets(ctx context.Context, param models.SearchProductParameter, facets []string) (*models.SearchProductFacets, error) {
+	param.SimilarityThreshold = s.SearchConfig.SimilarityThreshold
+
+	var err error
+	var searchProductFacets models.SearchProductFacets
+	for _, facet := range facets {
+		switch facet {
+		case models.SearchFacetCategory:
+			searchProductFacets.Categories, err = s.ProductRepository.SearchProductCategoryFacets(ctx, param)
+		case models.SearchFacetPrice:
+			searchProductFacets.PriceRanges, err = s.ProductRepository.SearchProductPriceFacets(ctx, param, s.SearchConfig.PriceBuckets)
+		case models.SearchFacetStock:
+			searchProductFacets.Stock, err = s.ProductRepository.SearchProductStockFacet(ctx, param)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &searchProductFacets, nil
+}
+
+// SuggestProductNames suggest product names by given term.
+//
+// It is used for "did you mean" hints when a search comes back empty.
+//
+// It returns slice of string, and nil error when successful.
+// Otherwise, nil value of string slice, and error will be returned.
+func (s *ProductService) SuggestProductNames(ctx context.Context, term string) ([]string, error) {
+	names, err := s.ProductRepository.FindSimilarProductNames(ctx, term, s.SearchConfig.SimilarityThreshold, s.SearchConfig.SuggestionLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	return names, nil
+}
+
+// bumpProductGeneration bump product generation by given ctx.
+//
+// Cached search results are keyed by generation, so bumping it makes every edit visible to the next search.
+func (s *ProductService) bumpProductGeneration(ctx context.Context) {
+	err := s.ProductRepository.IncrProductGeneration(ctx)
 	if err != nil {
-		return nil, 0, err
+		log.Logger.Errorf("s.ProductRepository.IncrProductGeneration() got error %v", err)
 	}
+}
 
-	return products, totalCount, nil
+// bumpProductCategoryGeneration bump product category generation by given ctx.
+func (s *ProductService) bumpProductCategoryGeneration(ctx context.Context) {
+	err := s.ProductRepository.IncrProductCategoryGeneration(ctx)
+	if err != nil {
+		log.Logger.Errorf("s.ProductRepository.IncrProductCategoryGeneration() got error %v", err)
+	}
 }
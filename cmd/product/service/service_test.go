@@ -0,0 +1,178 @@
+package service
+
+import (
+	// golang package
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"productfc/cmd/product/repository"
+	"productfc/config"
+	"productfc/infrastructure/circuitbreaker"
+	"productfc/models"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	// external package
+	"github.com/redis/go-redis/v9"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// productConnector is a database answering every query with one product row named name, once release is closed.
+type productConnector struct {
+	name    string
+	release chan struct{}
+	queries *atomic.Int32
+}
+
+func (c productConnector) Connect(context.Context) (driver.Conn, error) {
+	return productConn(c), nil
+}
+
+func (productConnector) Driver() driver.Driver {
+	return nil
+}
+
+type productConn productConnector
+
+func (productConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("fake db doesn't prepare %q", query)
+}
+
+func (productConn) Close() error {
+	return nil
+}
+
+func (productConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("fake db doesn't begin transactions")
+}
+
+func (c productConn) QueryContext(ctx context.Context, _ string, _ []driver.NamedValue) (driver.Rows, error) {
+	c.queries.Add(1)
+
+	select {
+	case <-c.release:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	return &productRows{name: c.name, remaining: 1}, nil
+}
+
+type productRows struct {
+	name      string
+	remaining int
+}
+
+func (r *productRows) Columns() []string {
+	return []string{"id", "name", "stock", "version"}
+}
+
+func (r *productRows) Close() error {
+	return nil
+}
+
+func (r *productRows) Next(dest []driver.Value) error {
+	if r.remaining == 0 {
+		return io.EOF
+	}
+
+	r.remaining--
+	copy(dest, []driver.Value{int64(1), r.name, int64(5), int64(2)})
+
+	return nil
+}
+
+// newRevalidatingService new revalidating service by given productConnector, whose redis can't be reached.
+func newRevalidatingService(t *testing.T, connector productConnector) *ProductService {
+	t.Helper()
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sql.OpenDB(connector)}), &gorm.Config{
+		Logger: logger.Discard,
+	})
+	if err != nil {
+		t.Fatalf("gorm.Open() got error %v", err)
+	}
+
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1, DialTimeout: 10 * time.Millisecond})
+	t.Cleanup(func() {
+		client.Close()
+	})
+
+	service := NewProductService(repository.ProductRepository{Database: db, Redis: client}, nil, config.CacheConfig{
+		StaleReadTimeout:  20 * time.Millisecond,
+		RevalidateTimeout: time.Second,
+	}, config.SearchConfig{}, config.StockStreamConfig{}, config.WebhookConfig{})
+	service.RedisBreaker = circuitbreaker.New("redis", 1, time.Minute)
+
+	return service
+}
+
+func TestRevalidateProductByIDServesStale(t *testing.T) {
+	connector := productConnector{name: "fresh", release: make(chan struct{}), queries: &atomic.Int32{}}
+	service := newRevalidatingService(t, connector)
+	staleProduct := &models.Product{ID: 1, Name: "stale", Version: 1}
+
+	// DB is slow, every concurrent read is served the stale product and they share a single reload
+	var wg sync.WaitGroup
+	products := make([]*models.Product, 10)
+	for i := range products {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			products[i] = service.revalidateProductByID(context.Background(), staleProduct)
+		}()
+	}
+
+	wg.Wait()
+
+	for _, product := range products {
+		if product.Name != "stale" {
+			t.Fatalf("revalidateProductByID() = %+v, want the stale product", product)
+		}
+	}
+
+	if queries := connector.queries.Load(); queries != 1 {
+		t.Fatalf("DB was read %d times, want once", queries)
+	}
+
+	// the reload goes on after the readers gave up on it
+	close(connector.release)
+
+	product := service.revalidateProductByID(context.Background(), staleProduct)
+	if product.Name != "fresh" || product.Version != 2 {
+		t.Fatalf("revalidateProductByID() = %+v, want the fresh product", product)
+	}
+}
+
+func TestRevalidateProductByIDServesFresh(t *testing.T) {
+	release := make(chan struct{})
+	close(release)
+
+	connector := productConnector{name: "fresh", release: release, queries: &atomic.Int32{}}
+	service := newRevalidatingService(t, connector)
+
+	product := service.revalidateProductByID(context.Background(), &models.Product{ID: 1, Name: "stale", Version: 1})
+	if product.Name != "fresh" {
+		t.Fatalf("revalidateProductByID() = %+v, want the fresh product when DB answers in time", product)
+	}
+}
+
+func TestRevalidateProductByIDKeepsStaleOnError(t *testing.T) {
+	connector := productConnector{name: "fresh", release: make(chan struct{}), queries: &atomic.Int32{}}
+	service := newRevalidatingService(t, connector)
+	service.CacheConfig.StaleReadTimeout = time.Second
+	service.CacheConfig.RevalidateTimeout = 10 * time.Millisecond
+
+	// the reload times out before DB answers
+	product := service.revalidateProductByID(context.Background(), &models.Product{ID: 1, Name: "stale", Version: 1})
+	if product.Name != "stale" {
+		t.Fatalf("revalidateProductByID() = %+v, want the stale product when the reload fails", product)
+	}
+}
@@ -0,0 +1,219 @@
+package service
+
+import (
+	// golang package
+	"context"
+	"errors"
+	"productfc/cmd/product/repository"
+	"productfc/config"
+	"productfc/infrastructure/circuitbreaker"
+	"productfc/infrastructure/log"
+	"productfc/models"
+	"sync"
+
+	// external package
+	"github.com/sirupsen/logrus"
+)
+
+// ProductStockStream fans the product stock events published to redis out to the streams open on this instance,
+// so a change made through any instance reaches every stream.
+type ProductStockStream struct {
+	ProductRepository repository.ProductRepository
+	Config            config.StockStreamConfig
+
+	mu            sync.Mutex
+	subscriptions map[*ProductStockSubscription]struct{}
+	byProductID   map[int64]map[*ProductStockSubscription]struct{}
+}
+
+// ProductStockSubscription is an open stream, Events is closed when it's unsubscribed, falls behind, or Run returns.
+type ProductStockSubscription struct {
+	Events <-chan models.ProductStockEvent
+
+	events     chan models.ProductStockEvent
+	productIDs []int64
+}
+
+// NewProductStockStream new product stock stream by given ProductRepository, and StockStreamConfig.
+//
+// It returns pointer of ProductStockStream.
+func NewProductStockStream(productRepository repository.ProductRepository, stockStreamConfig config.StockStreamConfig) *ProductStockStream {
+	return &ProductStockStream{
+		ProductRepository: productRepository,
+		Config:            stockStreamConfig,
+		subscriptions:     make(map[*ProductStockSubscription]struct{}),
+		byProductID:       make(map[int64]map[*ProductStockSubscription]struct{}),
+	}
+}
+
+// Subscribe subscribe by given slice of productIDs.
+//
+// It returns pointer of ProductStockSubscription, and nil error when successful.
+// Otherwise, nil pointer of ProductStockSubscription, and ErrTooManyStockStreams will be returned.
+func (st *ProductStockStream) Subscribe(productIDs []int64) (*ProductStockSubscription, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if len(st.subscriptions) >= st.Config.MaxConnections {
+		return nil, ErrTooManyStockStreams
+	}
+
+	events := make(chan models.ProductStockEvent, st.Config.BufferSize)
+	subscription := &ProductStockSubscription{
+		Events:     events,
+		events:     events,
+		productIDs: productIDs,
+	}
+
+	st.subscriptions[subscription] = struct{}{}
+	for _, productID := range productIDs {
+		if st.byProductID[productID] == nil {
+			st.byProductID[productID] = make(map[*ProductStockSubscription]struct{})
+		}
+
+		st.byProductID[productID][subscription] = struct{}{}
+	}
+
+	return subscription, nil
+}
+
+// Unsubscribe unsubscribe by given subscription pointer of ProductStockSubscription.
+//
+// It's safe to call more than once.
+func (st *ProductStockStream) Unsubscribe(subscription *ProductStockSubscription) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.unsubscribe(subscription)
+}
+
+// unsubscribe unsubscribe by given subscription pointer of ProductStockSubscription, st.mu must be held.
+func (st *ProductStockStream) unsubscribe(subscription *ProductStockSubscription) {
+	if _, ok := st.subscriptions[subscription]; !ok {
+		return
+	}
+
+	delete(st.subscriptions, subscription)
+	for _, productID := range subscription.productIDs {
+		delete(st.byProductID[productID], subscription)
+		if len(st.byProductID[productID]) == 0 {
+			delete(st.byProductID, productID)
+		}
+	}
+
+	close(subscription.events)
+}
+
+// closeAll close all, every open stream ends and its client reconnects.
+func (st *ProductStockStream) closeAll() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	for subscription := range st.subscriptions {
+		st.unsubscribe(subscription)
+	}
+}
+
+// Run run by given ctx.
+//
+// It dispatches the events of redis to the subscriptions until ctx is done.
+func (st *ProductStockStream) Run(ctx context.Context) {
+	pubSub := st.ProductRepository.SubscribeProductStockEvents(ctx)
+	defer pubSub.Close()
+	defer st.closeAll()
+
+	messages := pubSub.Channel()
+	for {
+		select {
+		case message, ok := <-messages:
+			if !ok {
+				return
+			}
+
+			event, err := repository.ParseProductStockEvent(message)
+			if err != nil {
+				log.Logger.WithFields(logrus.Fields{
+					"payload": message.Payload,
+				}).Errorf("repository.ParseProductStockEvent() got error %v", err)
+
+				continue
+			}
+
+			st.dispatch(event)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// dispatch dispatch by given event models.ProductStockEvent.
+//
+// A subscription whose buffer is full is closed rather than blocking the others, its client reconnects and
+// starts over from the current stock.
+func (st *ProductStockStream) dispatch(event models.ProductStockEvent) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	for subscription := range st.byProductID[event.ProductID] {
+		select {
+		case subscription.events <- event:
+		default:
+			log.Logger.WithFields(logrus.Fields{
+				"productIDs": subscription.productIDs,
+			}).Warn("stock stream fell behind, it was closed")
+
+			st.unsubscribe(subscription)
+		}
+	}
+}
+
+// NewProductStockEvent new product stock event by given product pointer of models.Product.
+//
+// It returns models.ProductStockEvent.
+func NewProductStockEvent(product *models.Product) models.ProductStockEvent {
+	return models.ProductStockEvent{
+		ProductID: product.ID,
+		Stock:     product.Stock,
+		InStock:   product.Stock > 0,
+		Price:     product.Price,
+		UpdatedAt: product.UpdatedAt,
+		Version:   product.Version,
+	}
+}
+
+// GetProductStockSnapshot get product stock snapshot by given slice of productIDs.
+//
+// It's read from DB, a cached product could be older than the events a stream sends after it.
+//
+// It returns slice of models.ProductStockEvent of the products found, and nil error when successful.
+// Otherwise, nil value of models.ProductStockEvent slice, and error will be returned.
+func (s *ProductService) GetProductStockSnapshot(ctx context.Context, productIDs []int64) ([]models.ProductStockEvent, error) {
+	products, err := s.ProductRepository.FindProductsByIDs(ctx, productIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]models.ProductStockEvent, len(products))
+	for i := range products {
+		events[i] = NewProductStockEvent(&products[i])
+	}
+
+	return events, nil
+}
+
+// publishProductStock publish product stock by given product pointer of models.Product.
+//
+// It's published to the stock streams, and to the webhooks subscribed to stock.changed.
+func (s *ProductService) publishProductStock(ctx context.Context, product *models.Product) {
+	event := NewProductStockEvent(product)
+	s.notifyWebhooks(ctx, models.WebhookEventStockChanged, event)
+
+	err := s.RedisBreaker.Execute(func() error {
+		return s.ProductRepository.PublishProductStockEvent(ctx, event)
+	})
+	if err != nil && !errors.Is(err, circuitbreaker.ErrOpen) {
+		log.Logger.WithFields(logrus.Fields{
+			"productID": product.ID,
+		}).Errorf("s.ProductRepository.PublishProductStockEvent() got error %v", err)
+	}
+}
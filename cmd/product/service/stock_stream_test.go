@@ -0,0 +1,63 @@
+package service
+
+import (
+	// golang package
+	"errors"
+	"productfc/cmd/product/repository"
+	"productfc/config"
+	"productfc/models"
+	"testing"
+)
+
+func TestProductStockStreamDispatch(t *testing.T) {
+	stream := NewProductStockStream(repository.ProductRepository{}, config.StockStreamConfig{MaxConnections: 2, BufferSize: 1})
+
+	phones, err := stream.Subscribe([]int64{1, 2})
+	if err != nil {
+		t.Fatalf("Subscribe() got error %v", err)
+	}
+
+	tablets, err := stream.Subscribe([]int64{3})
+	if err != nil {
+		t.Fatalf("Subscribe() got error %v", err)
+	}
+
+	if _, err := stream.Subscribe([]int64{1}); !errors.Is(err, ErrTooManyStockStreams) {
+		t.Fatalf("Subscribe() past MaxConnections got error %v, want ErrTooManyStockStreams", err)
+	}
+
+	// only the subscribers of a product get its events
+	stream.dispatch(models.ProductStockEvent{ProductID: 2, Version: 1})
+	if event := <-phones.Events; event.ProductID != 2 {
+		t.Fatalf("phones got %+v, want product 2", event)
+	}
+
+	if len(tablets.Events) != 0 {
+		t.Fatalf("tablets got %d events, want none", len(tablets.Events))
+	}
+
+	// a full buffer closes the subscription instead of blocking the others
+	stream.dispatch(models.ProductStockEvent{ProductID: 1, Version: 1})
+	stream.dispatch(models.ProductStockEvent{ProductID: 1, Version: 2})
+
+	<-phones.Events
+	if _, ok := <-phones.Events; ok {
+		t.Fatal("phones fell behind but is still open")
+	}
+
+	// its slot is free again
+	if _, err := stream.Subscribe([]int64{1}); err != nil {
+		t.Fatalf("Subscribe() after a subscription was closed got error %v", err)
+	}
+
+	stream.Unsubscribe(tablets)
+	stream.Unsubscribe(tablets)
+	if _, ok := <-tablets.Events; ok {
+		t.Fatal("tablets was unsubscribed but is still open")
+	}
+
+	stream.closeAll()
+	if len(stream.subscriptions) != 0 || len(stream.byProductID) != 0 {
+		t.Fatalf("closeAll() left %d subscriptions, %d products", len(stream.subscriptions), len(stream.byProductID))
+	}
+}
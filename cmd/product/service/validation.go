@@ -0,0 +1,199 @@
+package service
+
+import (
+	// golang package
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"productfc/models"
+	"reflect"
+	"strings"
+
+	// external package
+	"github.com/go-playground/validator/v10"
+)
+
+// validate checks the validate tags of the models, field errors are named by their json name.
+var validate = newValidator()
+
+// newValidator new validator.
+//
+// It returns pointer of validator.Validate.
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+
+		return name
+	})
+
+	return v
+}
+
+// structFieldErrors struct field errors by given value.
+//
+// Every violated rule is reported, not only the first one.
+//
+// It returns slice of FieldError, empty when value is valid.
+func structFieldErrors(value interface{}) []FieldError {
+	var validationErrs validator.ValidationErrors
+	if !errors.As(validate.Struct(value), &validationErrs) {
+		return nil
+	}
+
+	fields := make([]FieldError, len(validationErrs))
+	for i, validationErr := range validationErrs {
+		fields[i] = FieldError{
+			Field:   validationErr.Field(),
+			Message: ruleMessage(validationErr),
+		}
+	}
+
+	return fields
+}
+
+// ruleMessage rule message by given validationErr validator.FieldError.
+//
+// It returns string.
+func ruleMessage(validationErr validator.FieldError) string {
+	switch validationErr.Tag() {
+	case "required":
+		return "is required"
+	case "min":
+		if validationErr.Kind() == reflect.Slice {
+			return fmt.Sprintf("must contain at least %s items", validationErr.Param())
+		}
+
+		return fmt.Sprintf("must be at least %s characters", validationErr.Param())
+	case "max":
+		return fmt.Sprintf("must be at most %s characters", validationErr.Param())
+	case "oneof":
+		return fmt.Sprintf("must be one of %s", strings.ReplaceAll(validationErr.Param(), " ", ", "))
+	case "http_url":
+		return "must be an http or https URL"
+	case "gte":
+		return fmt.Sprintf("must be greater than or equal to %s", validationErr.Param())
+	case "gt":
+		return fmt.Sprintf("must be greater than %s", validationErr.Param())
+	default:
+		return fmt.Sprintf("must satisfy %s", validationErr.Tag())
+	}
+}
+
+// hasFieldError has field error by given fields, and field.
+//
+// It returns bool.
+func hasFieldError(fields []FieldError, field string) bool {
+	for _, fieldErr := range fields {
+		if fieldErr.Field == field {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validateProduct validate product by given product pointer of models.Product.
+//
+// Besides the field rules, the category must exist and the name must be unique within it.
+// All violations are reported together.
+//
+// It returns nil error when product is valid.
+// Otherwise, error of kind ErrValidation, or the error of checking it, will be returned.
+func (s *ProductService) validateProduct(ctx context.Context, product *models.Product) error {
+	fields := structFieldErrors(product)
+
+	if !hasFieldError(fields, "category_id") {
+		productCategory, err := s.GetProductCategoryByID(ctx, product.CategoryID)
+		if err != nil {
+			return err
+		}
+
+		if productCategory.ID == 0 {
+			fields = append(fields, FieldError{Field: "category_id", Message: "product category doesn't exist"})
+		}
+	}
+
+	if !hasFieldError(fields, "name") && !hasFieldError(fields, "category_id") {
+		exists, err := s.ProductRepository.ExistsProductNameInCategory(ctx, product.Name, product.CategoryID, product.ID)
+		if err != nil {
+			return err
+		}
+
+		if exists {
+			fields = append(fields, FieldError{Field: "name", Message: "is already used by another product in the category"})
+		}
+	}
+
+	if len(fields) > 0 {
+		return NewValidationError("invalid product", fields...)
+	}
+
+	return nil
+}
+
+// validateProductCategory validate product category by given productCategory pointer of models.ProductCategory.
+//
+// It returns nil error when productCategory is valid.
+// Otherwise, error of kind ErrValidation will be returned.
+func validateProductCategory(productCategory *models.ProductCategory) error {
+	fields := structFieldErrors(productCategory)
+	if len(fields) > 0 {
+		return NewValidationError("invalid product category", fields...)
+	}
+
+	return nil
+}
+
+// applyProductFields apply product fields by given product pointer of models.Product, and fields of column to value.
+//
+// It lets a partial update be validated as the product it would produce.
+func applyProductFields(product *models.Product, fields map[string]interface{}) {
+	for column, value := range fields {
+		switch column {
+		case "name":
+			product.Name, _ = value.(string)
+		case "description":
+			product.Description, _ = value.(string)
+		case "price":
+			product.Price, _ = value.(float64)
+		case "stock":
+			product.Stock, _ = value.(int)
+		case "category_id":
+			product.CategoryID, _ = value.(int)
+		}
+	}
+}
+
+// validateWebhookSubscription validate webhook subscription by given subscription pointer of models.WebhookSubscription, and allowInsecureURLs.
+//
+// Unless allowInsecureURLs, the URL must be https, and must not be an address webhooks are kept from,
+// a hostname is checked once resolved, when a delivery connects.
+//
+// It returns nil error when subscription is valid.
+// Otherwise, error of kind ErrValidation will be returned.
+func validateWebhookSubscription(subscription *models.WebhookSubscription, allowInsecureURLs bool) error {
+	fields := structFieldErrors(subscription)
+	if !allowInsecureURLs && !hasFieldError(fields, "url") {
+		webhookURL, _ := url.Parse(subscription.URL)
+		host := webhookURL.Hostname()
+		addr, errAddr := netip.ParseAddr(host)
+		switch {
+		case webhookURL.Scheme != "https":
+			fields = append(fields, FieldError{Field: "url", Message: "must be an https URL"})
+		case strings.EqualFold(host, "localhost"), errAddr == nil && !isPublicWebhookAddr(addr):
+			fields = append(fields, FieldError{Field: "url", Message: "must not point to a loopback, link-local, private, or other special purpose address"})
+		}
+	}
+
+	if len(fields) > 0 {
+		return NewValidationError("invalid webhook", fields...)
+	}
+
+	return nil
+}
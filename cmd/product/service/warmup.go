@@ -0,0 +1,76 @@
+package service
+
+import (
+	// golang package
+	"context"
+	"productfc/infrastructure/log"
+	"time"
+
+	// external package
+	"github.com/sirupsen/logrus"
+)
+
+// WarmUpCache warm up cache.
+//
+// It loads the latest updated products and every product category into redis and rebuilds
+// the autocomplete index, so the first requests after a deploy or a redis flush don't all miss.
+//
+// It returns nil error when successful.
+// Otherwise, error will be returned.
+func (s *ProductService) WarmUpCache(ctx context.Context) error {
+	startTime := time.Now()
+	productLimit := s.CacheConfig.WarmUp.ProductLimit
+	batchSize := s.CacheConfig.WarmUp.BatchSize
+	if batchSize <= 0 {
+		batchSize = productLimit
+	}
+
+	productCategories, err := s.ProductRepository.FindAllProductCategories(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = s.ProductRepository.SetProductCategories(ctx, productCategories)
+	if err != nil {
+		return err
+	}
+
+	log.Logger.WithFields(logrus.Fields{
+		"productCategories": len(productCategories),
+	}).Info("[WARMUP] Product categories loaded into cache")
+
+	products, err := s.ProductRepository.FindLatestUpdatedProducts(ctx, productLimit)
+	if err != nil {
+		return err
+	}
+
+	for start := 0; start < len(products); start += batchSize {
+		end := start + batchSize
+		if end > len(products) {
+			end = len(products)
+		}
+
+		err = s.ProductRepository.SetProducts(ctx, products[start:end])
+		if err != nil {
+			return err
+		}
+
+		log.Logger.WithFields(logrus.Fields{
+			"loaded": end,
+			"total":  len(products),
+		}).Info("[WARMUP] Products loaded into cache")
+	}
+
+	err = s.RebuildAutocompleteIndex(ctx)
+	if err != nil {
+		return err
+	}
+
+	log.Logger.WithFields(logrus.Fields{
+		"products":          len(products),
+		"productCategories": len(productCategories),
+		"latency":           time.Since(startTime),
+	}).Info("[WARMUP] Cache warm-up finished")
+
+	return nil
+}
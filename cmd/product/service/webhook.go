@@ -0,0 +1,697 @@
+package service
+
+import (
+	// golang package
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"productfc/cmd/product/repository"
+	"productfc/config"
+	"productfc/infrastructure/log"
+	"productfc/models"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	// external package
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultWebhookPollInterval         = 5 * time.Second
+	defaultWebhookBatchSize            = 50
+	defaultWebhookConcurrency          = 8
+	defaultWebhookTimeout              = 10 * time.Second
+	defaultWebhookLeaseTimeout         = time.Minute
+	defaultWebhookMaxAttempts          = 8
+	defaultWebhookInitialBackoff       = 30 * time.Second
+	defaultWebhookMaxBackoff           = time.Hour
+	defaultWebhookDisableAfterFailures = 20
+	defaultWebhookDeliveryLimit        = 50
+
+	webhookSecretBytes    = 32
+	webhookErrorBodyLimit = 512 // bytes of a failed response kept in the delivery log
+)
+
+// errWebhookAddressNotAllowed is a webhook host resolving to an address it must not reach.
+var errWebhookAddressNotAllowed = errors.New("webhook address is not allowed")
+
+// Webhook request headers, the signature is the hex HMAC-SHA256 of timestamp + "." + body keyed by the secret.
+const (
+	WebhookHeaderID        = "X-Webhook-Id"
+	WebhookHeaderEvent     = "X-Webhook-Event"
+	WebhookHeaderTimestamp = "X-Webhook-Timestamp"
+	WebhookHeaderSignature = "X-Webhook-Signature"
+)
+
+// WebhookDispatcher queues an event for every subscribed webhook in DB and delivers them in the background,
+// retrying failed deliveries with exponential backoff. Instances share the queue, a delivery is sent by one of them.
+type WebhookDispatcher struct {
+	ProductRepository repository.ProductRepository
+	Config            config.WebhookConfig
+	HTTPClient        *http.Client
+
+	wake chan struct{}
+}
+
+// NewWebhookDispatcher new webhook dispatcher by given ProductRepository, and WebhookConfig.
+//
+// It returns pointer of WebhookDispatcher, or nil pointer when webhooks are disabled.
+func NewWebhookDispatcher(productRepository repository.ProductRepository, webhookConfig config.WebhookConfig) *WebhookDispatcher {
+	if !webhookConfig.Enabled {
+		return nil
+	}
+
+	if webhookConfig.PollInterval <= 0 {
+		webhookConfig.PollInterval = defaultWebhookPollInterval
+	}
+
+	if webhookConfig.BatchSize <= 0 {
+		webhookConfig.BatchSize = defaultWebhookBatchSize
+	}
+
+	if webhookConfig.Concurrency <= 0 {
+		webhookConfig.Concurrency = defaultWebhookConcurrency
+	}
+
+	if webhookConfig.Timeout <= 0 {
+		webhookConfig.Timeout = defaultWebhookTimeout
+	}
+
+	if webhookConfig.LeaseTimeout <= 0 {
+		webhookConfig.LeaseTimeout = defaultWebhookLeaseTimeout
+	}
+
+	// a delivery must not be claimed again while it's still being sent
+	webhookConfig.LeaseTimeout = max(webhookConfig.LeaseTimeout, 2*webhookConfig.Timeout)
+
+	if webhookConfig.MaxAttempts <= 0 {
+		webhookConfig.MaxAttempts = defaultWebhookMaxAttempts
+	}
+
+	if webhookConfig.InitialBackoff <= 0 {
+		webhookConfig.InitialBackoff = defaultWebhookInitialBackoff
+	}
+
+	if webhookConfig.MaxBackoff <= 0 {
+		webhookConfig.MaxBackoff = defaultWebhookMaxBackoff
+	}
+
+	if webhookConfig.DisableAfterFailures <= 0 {
+		webhookConfig.DisableAfterFailures = defaultWebhookDisableAfterFailures
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   webhookConfig.Timeout,
+		KeepAlive: 30 * time.Second,
+	}
+	if !webhookConfig.AllowInsecureURLs {
+		dialer.Control = webhookDialControl
+	}
+
+	// no proxy, the address dialed must be the one of the webhook
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &WebhookDispatcher{
+		ProductRepository: productRepository,
+		Config:            webhookConfig,
+		HTTPClient: &http.Client{
+			Transport: transport,
+			Timeout:   webhookConfig.Timeout,
+			// a redirect is answered as is, it counts as a failure
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		wake: make(chan struct{}, 1),
+	}
+}
+
+// webhookDialControl webhook dial control by given network, address, and c syscall.RawConn.
+//
+// It runs once the host is resolved, so a hostname pointing to a private network is caught as well.
+//
+// It returns nil error when address is public.
+// Otherwise, errWebhookAddressNotAllowed will be returned.
+func webhookDialControl(network string, address string, c syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+
+	if !isPublicWebhookAddr(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", errWebhookAddressNotAllowed, addrPort.Addr())
+	}
+
+	return nil
+}
+
+// nonPublicWebhookPrefixes are the special purpose ranges of the IANA IPv4 and IPv6 registries a webhook is never
+// sent to, listed explicitly since netip.Addr predicates miss shared, benchmarking and translation ranges.
+// Cloud metadata endpoints fall in them, e.g. 169.254.169.254, 100.100.100.200 and fd00:ec2::254.
+var nonPublicWebhookPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // this network
+	netip.MustParsePrefix("10.0.0.0/8"),      // private
+	netip.MustParsePrefix("100.64.0.0/10"),   // shared address space, carrier-grade NAT
+	netip.MustParsePrefix("127.0.0.0/8"),     // loopback
+	netip.MustParsePrefix("169.254.0.0/16"),  // link-local
+	netip.MustParsePrefix("172.16.0.0/12"),   // private
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("192.0.2.0/24"),    // documentation
+	netip.MustParsePrefix("192.88.99.0/24"),  // 6to4 relay anycast
+	netip.MustParsePrefix("192.168.0.0/16"),  // private
+	netip.MustParsePrefix("198.18.0.0/15"),   // benchmarking
+	netip.MustParsePrefix("198.51.100.0/24"), // documentation
+	netip.MustParsePrefix("203.0.113.0/24"),  // documentation
+	netip.MustParsePrefix("224.0.0.0/4"),     // multicast
+	netip.MustParsePrefix("240.0.0.0/4"),     // reserved, and broadcast
+	netip.MustParsePrefix("::/128"),          // unspecified
+	netip.MustParsePrefix("::1/128"),         // loopback
+	netip.MustParsePrefix("::ffff:0:0/96"),   // IPv4-mapped, only reached when Unmap didn't apply
+	netip.MustParsePrefix("64:ff9b::/96"),    // IPv4/IPv6 translation
+	netip.MustParsePrefix("64:ff9b:1::/48"),  // local IPv4/IPv6 translation
+	netip.MustParsePrefix("100::/64"),        // discard only
+	netip.MustParsePrefix("2001::/23"),       // IETF protocol assignments, Teredo among them
+	netip.MustParsePrefix("2001:db8::/32"),   // documentation
+	netip.MustParsePrefix("2002::/16"),       // 6to4, embeds any IPv4 address
+	netip.MustParsePrefix("fc00::/7"),        // unique local
+	netip.MustParsePrefix("fe80::/10"),       // link-local
+	netip.MustParsePrefix("ff00::/8"),        // multicast
+}
+
+// isPublicWebhookAddr is public webhook addr by given addr netip.Addr.
+//
+// It returns false for invalid addresses, and the ones in nonPublicWebhookPrefixes.
+func isPublicWebhookAddr(addr netip.Addr) bool {
+	if !addr.IsValid() {
+		return false
+	}
+
+	addr = addr.Unmap().WithZone("")
+	for _, prefix := range nonPublicWebhookPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// allowsInsecureURLs allows insecure urls.
+//
+// It's safe to call on a nil dispatcher, subscriptions are held to https and public hosts then.
+//
+// It returns bool.
+func (d *WebhookDispatcher) allowsInsecureURLs() bool {
+	return d != nil && d.Config.AllowInsecureURLs
+}
+
+// Enqueue enqueue by given eventType, and data of the event.
+//
+// A delivery is queued for every active webhook subscribed to eventType. It's safe to call on a nil dispatcher.
+//
+// It returns nil error when successful.
+// Otherwise, error will be returned.
+func (d *WebhookDispatcher) Enqueue(ctx context.Context, eventType string, data interface{}) error {
+	if d == nil {
+		return nil
+	}
+
+	subscriptions, err := d.ProductRepository.FindActiveWebhookSubscriptionsByEventType(ctx, eventType)
+	if err != nil {
+		return err
+	}
+
+	if len(subscriptions) == 0 {
+		return nil
+	}
+
+	event := models.WebhookEvent{
+		ID:         uuid.New().String(),
+		Type:       eventType,
+		OccurredAt: time.Now().UTC(),
+		Data:       data,
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	deliveries := make([]models.WebhookDelivery, len(subscriptions))
+	for i, subscription := range subscriptions {
+		deliveries[i] = models.WebhookDelivery{
+			SubscriptionID: subscription.ID,
+			EventID:        event.ID,
+			EventType:      eventType,
+			Payload:        string(payload),
+			Status:         models.WebhookDeliveryPending,
+			NextAttemptAt:  now,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		}
+	}
+
+	err = d.ProductRepository.InsertWebhookDeliveries(ctx, deliveries)
+	if err != nil {
+		return err
+	}
+
+	// the other instances pick it up on their next poll
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// Run run by given ctx.
+//
+// It sends the due deliveries every PollInterval, or as soon as one is queued on this instance, until ctx is done.
+// The deliveries in flight are finished before it returns.
+func (d *WebhookDispatcher) Run(ctx context.Context) {
+	if d == nil {
+		return
+	}
+
+	ticker := time.NewTicker(d.Config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-d.wake:
+		case <-ctx.Done():
+			return
+		}
+
+		// a full batch means more may be due
+		for ctx.Err() == nil {
+			if d.deliverDue(ctx) < d.Config.BatchSize {
+				break
+			}
+		}
+	}
+}
+
+// deliverDue deliver due by given ctx.
+//
+// It returns int of the deliveries claimed.
+func (d *WebhookDispatcher) deliverDue(ctx context.Context) int {
+	deliveries, err := d.ProductRepository.ClaimDueWebhookDeliveries(ctx, d.Config.BatchSize, d.Config.LeaseTimeout)
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"batchSize": d.Config.BatchSize,
+		}).Errorf("d.ProductRepository.ClaimDueWebhookDeliveries() got error %v", err)
+
+		return 0
+	}
+
+	if len(deliveries) == 0 {
+		return 0
+	}
+
+	// claimed deliveries are sent even when ctx is done, they'd wait for the lease otherwise
+	ctx = context.WithoutCancel(ctx)
+
+	subscriptionIDs := make([]int64, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		subscriptionIDs = append(subscriptionIDs, delivery.SubscriptionID)
+	}
+
+	subscriptions, err := d.ProductRepository.FindWebhookSubscriptionsByIDs(ctx, subscriptionIDs)
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"subscriptionIDs": subscriptionIDs,
+		}).Errorf("d.ProductRepository.FindWebhookSubscriptionsByIDs() got error %v", err)
+
+		return len(deliveries)
+	}
+
+	subscriptionByID := make(map[int64]models.WebhookSubscription, len(subscriptions))
+	for _, subscription := range subscriptions {
+		subscriptionByID[subscription.ID] = subscription
+	}
+
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, d.Config.Concurrency)
+	for i := range deliveries {
+		subscription, ok := subscriptionByID[deliveries[i].SubscriptionID]
+		if !ok || !subscription.Active {
+			d.finishDelivery(ctx, &deliveries[i], models.WebhookDeliveryFailed, 0, "webhook is disabled")
+
+			continue
+		}
+
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(delivery *models.WebhookDelivery) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			d.deliver(ctx, delivery, subscription)
+		}(&deliveries[i])
+	}
+
+	wg.Wait()
+
+	return len(deliveries)
+}
+
+// deliver deliver by given delivery pointer of models.WebhookDelivery, and subscription models.WebhookSubscription.
+//
+// A failed attempt is retried after a backoff until MaxAttempts, and counts towards disabling the subscription.
+func (d *WebhookDispatcher) deliver(ctx context.Context, delivery *models.WebhookDelivery, subscription models.WebhookSubscription) {
+	delivery.Attempts++
+	statusCode, err := d.send(ctx, delivery, subscription)
+	if err == nil {
+		d.finishDelivery(ctx, delivery, models.WebhookDeliverySucceeded, statusCode, "")
+
+		err = d.ProductRepository.RecordWebhookSubscriptionSuccess(ctx, subscription.ID)
+		if err != nil {
+			log.Logger.WithFields(logrus.Fields{
+				"subscriptionID": subscription.ID,
+			}).Errorf("d.ProductRepository.RecordWebhookSubscriptionSuccess() got error %v", err)
+		}
+
+		return
+	}
+
+	if delivery.Attempts >= d.Config.MaxAttempts {
+		d.finishDelivery(ctx, delivery, models.WebhookDeliveryFailed, statusCode, err.Error())
+	} else {
+		delivery.NextAttemptAt = time.Now().Add(d.backoff(delivery.Attempts))
+		d.finishDelivery(ctx, delivery, models.WebhookDeliveryPending, statusCode, err.Error())
+	}
+
+	active, err := d.ProductRepository.RecordWebhookSubscriptionFailure(ctx, subscription.ID, d.Config.DisableAfterFailures)
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"subscriptionID": subscription.ID,
+		}).Errorf("d.ProductRepository.RecordWebhookSubscriptionFailure() got error %v", err)
+
+		return
+	}
+
+	if !active {
+		log.Logger.WithFields(logrus.Fields{
+			"subscriptionID": subscription.ID,
+			"url":            subscription.URL,
+		}).Warn("webhook kept failing, it was disabled")
+	}
+}
+
+// send send by given delivery pointer of models.WebhookDelivery, and subscription models.WebhookSubscription.
+//
+// It returns int of the response status code, and nil error when it's 2xx.
+// Otherwise, int of the status code, zero when there was no response, and error will be returned.
+func (d *WebhookDispatcher) send(ctx context.Context, delivery *models.WebhookDelivery, subscription models.WebhookSubscription) (int, error) {
+	secret, err := openWebhookSecret(d.Config.SecretKey, subscription.Secret)
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewBufferString(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "productfc-webhook")
+	request.Header.Set(WebhookHeaderID, delivery.EventID)
+	request.Header.Set(WebhookHeaderEvent, delivery.EventType)
+	request.Header.Set(WebhookHeaderTimestamp, timestamp)
+	request.Header.Set(WebhookHeaderSignature, "sha256="+SignWebhookPayload(secret, timestamp, []byte(delivery.Payload)))
+
+	response, err := d.HTTPClient.Do(request)
+	if err != nil {
+		return 0, err
+	}
+
+	defer response.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(response.Body, webhookErrorBodyLimit))
+	if response.StatusCode < http.StatusOK || response.StatusCode >= http.StatusMultipleChoices {
+		return response.StatusCode, fmt.Errorf("webhook answered %d: %s", response.StatusCode, body)
+	}
+
+	return response.StatusCode, nil
+}
+
+// finishDelivery finish delivery by given delivery pointer of models.WebhookDelivery, status, statusCode, and lastError.
+func (d *WebhookDispatcher) finishDelivery(ctx context.Context, delivery *models.WebhookDelivery, status string, statusCode int, lastError string) {
+	delivery.Status = status
+	delivery.LastStatusCode = statusCode
+	delivery.LastError = lastError
+	if status == models.WebhookDeliverySucceeded {
+		now := time.Now()
+		delivery.DeliveredAt = &now
+	}
+
+	err := d.ProductRepository.UpdateWebhookDelivery(ctx, delivery)
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"deliveryID": delivery.ID,
+			"status":     status,
+		}).Errorf("d.ProductRepository.UpdateWebhookDelivery() got error %v", err)
+	}
+}
+
+// backoff backoff by given attempts made.
+//
+// It returns time.Duration of InitialBackoff doubled on every attempt after the first, capped at MaxBackoff.
+func (d *WebhookDispatcher) backoff(attempts int) time.Duration {
+	backoff := d.Config.InitialBackoff
+	for i := 1; i < attempts && backoff < d.Config.MaxBackoff; i++ {
+		backoff *= 2
+	}
+
+	return min(backoff, d.Config.MaxBackoff)
+}
+
+// SignWebhookPayload sign webhook payload by given secret, timestamp, and payload.
+//
+// Receivers recompute it to check the request came from us, and reject stale timestamps against replays.
+//
+// It returns string of the hex HMAC-SHA256.
+func SignWebhookPayload(secret string, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// notifyWebhooks notify webhooks by given eventType, and data of the event.
+//
+// The deliveries are queued in the background, a change never waits on them.
+func (s *ProductService) notifyWebhooks(ctx context.Context, eventType string, data interface{}) {
+	if s.Webhooks == nil {
+		return
+	}
+
+	go func() {
+		err := s.Webhooks.Enqueue(context.WithoutCancel(ctx), eventType, data)
+		if err != nil {
+			log.Logger.WithFields(logrus.Fields{
+				"eventType": eventType,
+			}).Errorf("s.Webhooks.Enqueue() got error %v", err)
+		}
+	}()
+}
+
+// CreateWebhookSubscription create webhook subscription by given param pointer of models.WebhookSubscription.
+//
+// A secret is generated when none is given, it's only returned here.
+//
+// It returns pointer of models.WebhookSubscription, and nil error when successful.
+// Otherwise, nil pointer of models.WebhookSubscription, and error will be returned, of kind ErrValidation when param is invalid.
+func (s *ProductService) CreateWebhookSubscription(ctx context.Context, param *models.WebhookSubscription) (*models.WebhookSubscription, error) {
+	err := validateWebhookSubscription(param, s.Webhooks.allowsInsecureURLs())
+	if err != nil {
+		return nil, err
+	}
+
+	if param.Secret == "" {
+		param.Secret, err = generateWebhookSecret()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	param.Active = true
+	param.ConsecutiveFailures = 0
+	param.DisabledAt = nil
+
+	subscription := *param
+	subscription.Secret, err = sealWebhookSecret(s.WebhookConfig.SecretKey, param.Secret)
+	if err != nil {
+		return nil, err
+	}
+
+	param.ID, err = s.ProductRepository.InsertWebhookSubscription(ctx, &subscription)
+	if err != nil {
+		return nil, translateDBError(err, "", "")
+	}
+
+	param.CreatedAt = subscription.CreatedAt
+	param.UpdatedAt = subscription.UpdatedAt
+
+	return param, nil
+}
+
+// GetWebhookSubscriptions get webhook subscriptions.
+//
+// It returns slice of models.WebhookSubscription without their secret, and nil error when successful.
+// Otherwise, nil value of models.WebhookSubscription slice, and error will be returned.
+func (s *ProductService) GetWebhookSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	subscriptions, err := s.ProductRepository.FindWebhookSubscriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range subscriptions {
+		subscriptions[i].Secret = ""
+	}
+
+	return subscriptions, nil
+}
+
+// GetWebhookSubscriptionByID get webhook subscription by id by given subscriptionID.
+//
+// It returns pointer of models.WebhookSubscription without its secret, and nil error when successful.
+// Otherwise, nil pointer of models.WebhookSubscription, and error will be returned, ErrWebhookNotFound when it doesn't exist.
+func (s *ProductService) GetWebhookSubscriptionByID(ctx context.Context, subscriptionID int64) (*models.WebhookSubscription, error) {
+	subscription, err := s.ProductRepository.FindWebhookSubscriptionByID(ctx, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if subscription.ID == 0 {
+		return nil, ErrWebhookNotFound
+	}
+
+	subscription.Secret = ""
+
+	return subscription, nil
+}
+
+// EditWebhookSubscription edit webhook subscription by given subscription pointer of models.WebhookSubscription.
+//
+// The secret is kept when none is given. Activating a disabled subscription starts its failure count over.
+//
+// It returns pointer of models.WebhookSubscription without its secret, and nil error when successful.
+// Otherwise, nil pointer of models.WebhookSubscription, and error will be returned, ErrWebhookNotFound when it doesn't exist, of kind ErrValidation when subscription is invalid.
+func (s *ProductService) EditWebhookSubscription(ctx context.Context, subscription *models.WebhookSubscription) (*models.WebhookSubscription, error) {
+	err := validateWebhookSubscription(subscription, s.Webhooks.allowsInsecureURLs())
+	if err != nil {
+		return nil, err
+	}
+
+	previousSubscription, err := s.ProductRepository.FindWebhookSubscriptionByID(ctx, subscription.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Save would insert it
+	if previousSubscription.ID == 0 {
+		return nil, ErrWebhookNotFound
+	}
+
+	if subscription.Secret == "" {
+		subscription.Secret = previousSubscription.Secret
+	} else {
+		subscription.Secret, err = sealWebhookSecret(s.WebhookConfig.SecretKey, subscription.Secret)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// the failures are only ever counted by the dispatcher
+	subscription.ConsecutiveFailures = previousSubscription.ConsecutiveFailures
+	subscription.DisabledAt = previousSubscription.DisabledAt
+	if subscription.Active && !previousSubscription.Active {
+		subscription.ConsecutiveFailures = 0
+		subscription.DisabledAt = nil
+	}
+
+	_, err = s.ProductRepository.UpdateWebhookSubscription(ctx, subscription)
+	if err != nil {
+		return nil, translateDBError(err, "", "")
+	}
+
+	return s.GetWebhookSubscriptionByID(ctx, subscription.ID)
+}
+
+// DeleteWebhookSubscription delete webhook subscription by given subscriptionID.
+//
+// Its pending deliveries and delivery log are deleted with it.
+//
+// It returns nil error when successful.
+// Otherwise, error will be returned, ErrWebhookNotFound when it doesn't exist.
+func (s *ProductService) DeleteWebhookSubscription(ctx context.Context, subscriptionID int64) error {
+	subscription, err := s.ProductRepository.FindWebhookSubscriptionByID(ctx, subscriptionID)
+	if err != nil {
+		return err
+	}
+
+	if subscription.ID == 0 {
+		return ErrWebhookNotFound
+	}
+
+	return s.ProductRepository.DeleteWebhookSubscription(ctx, subscriptionID)
+}
+
+// GetWebhookDeliveries get webhook deliveries by given subscriptionID, and limit, zero falls back to the default.
+//
+// It returns slice of models.WebhookDelivery, the latest first, and nil error when successful.
+// Otherwise, nil value of models.WebhookDelivery slice, and error will be returned, ErrWebhookNotFound when the subscription doesn't exist.
+func (s *ProductService) GetWebhookDeliveries(ctx context.Context, subscriptionID int64, limit int) ([]models.WebhookDelivery, error) {
+	subscription, err := s.ProductRepository.FindWebhookSubscriptionByID(ctx, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if subscription.ID == 0 {
+		return nil, ErrWebhookNotFound
+	}
+
+	if limit <= 0 {
+		limit = defaultWebhookDeliveryLimit
+	}
+
+	return s.ProductRepository.FindWebhookDeliveries(ctx, subscriptionID, limit)
+}
+
+// generateWebhookSecret generate webhook secret.
+//
+// It returns string of random hex, and nil error when successful.
+// Otherwise, empty string, and error will be returned.
+func generateWebhookSecret() (string, error) {
+	secret := make([]byte, webhookSecretBytes)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(secret), nil
+}
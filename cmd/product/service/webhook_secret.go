@@ -0,0 +1,101 @@
+package service
+
+import (
+	// golang package
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// webhookSecretPrefix marks a secret sealed by sealWebhookSecret, a stored secret without it is kept as given.
+const webhookSecretPrefix = "enc:v1:"
+
+var errWebhookSecretKeyMissing = errors.New("webhook secret is encrypted but no secret key is configured")
+
+// sealWebhookSecret seal webhook secret by given key, and secret.
+//
+// The secret is encrypted with AES-256-GCM under key, the hex WebhookConfig.SecretKey, so the secrets in DB
+// and its backups can't sign payloads on their own. With no key configured the secret is stored as given.
+//
+// It returns string of the secret to store, and nil error when successful.
+// Otherwise, empty string, and error will be returned.
+func sealWebhookSecret(key string, secret string) (string, error) {
+	if key == "" {
+		return secret, nil
+	}
+
+	aead, err := newWebhookSecretAEAD(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return "", err
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(secret), nil)
+
+	return webhookSecretPrefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// openWebhookSecret open webhook secret by given key, and stored secret.
+//
+// A secret stored before a key was configured is returned as is.
+//
+// It returns string of the secret, and nil error when successful.
+// Otherwise, empty string, and error will be returned.
+func openWebhookSecret(key string, stored string) (string, error) {
+	encoded, ok := strings.CutPrefix(stored, webhookSecretPrefix)
+	if !ok {
+		return stored, nil
+	}
+
+	if key == "" {
+		return "", errWebhookSecretKeyMissing
+	}
+
+	aead, err := newWebhookSecretAEAD(key)
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", err
+	}
+
+	if len(sealed) < aead.NonceSize() {
+		return "", errors.New("webhook secret is too short")
+	}
+
+	secret, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+
+	return string(secret), nil
+}
+
+// newWebhookSecretAEAD new webhook secret aead by given key.
+//
+// It returns cipher.AEAD of AES-GCM, and nil error when successful.
+// Otherwise, nil cipher.AEAD, and error will be returned.
+func newWebhookSecretAEAD(key string) (cipher.AEAD, error) {
+	keyBytes, err := hex.DecodeString(key)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(keyBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
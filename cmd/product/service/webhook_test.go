@@ -0,0 +1,468 @@
+package service
+
+import (
+	// golang package
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"productfc/cmd/product/repository"
+	"productfc/config"
+	"productfc/models"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	// external package
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const testWebhookSecretKey = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+
+func TestIsPublicWebhookAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"8.8.8.8", true},
+		{"2606:4700:4700::1111", true},
+		{"::ffff:93.184.216.34", true},
+
+		{"0.0.0.0", false},
+		{"0.1.2.3", false},
+		{"10.1.2.3", false},
+		{"100.64.0.1", false},
+		{"100.100.100.200", false}, // alibaba cloud metadata
+		{"127.0.0.1", false},
+		{"169.254.169.254", false}, // aws, gcp and azure metadata
+		{"172.16.0.1", false},
+		{"192.0.0.170", false},
+		{"192.0.2.1", false},
+		{"192.168.1.1", false},
+		{"198.18.0.1", false},
+		{"198.19.255.255", false},
+		{"224.0.0.1", false},
+		{"240.0.0.1", false},
+		{"255.255.255.255", false},
+		{"::", false},
+		{"::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:169.254.169.254", false},
+		{"64:ff9b::a9fe:a9fe", false}, // 169.254.169.254 through NAT64
+		{"2001:db8::1", false},
+		{"2002:a9fe:a9fe::1", false}, // 169.254.169.254 through 6to4
+		{"fd00:ec2::254", false},     // aws metadata over IPv6
+		{"fe80::1%eth0", false},
+		{"ff02::1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			if got := isPublicWebhookAddr(netip.MustParseAddr(tt.addr)); got != tt.want {
+				t.Fatalf("isPublicWebhookAddr(%s) = %v, want %v", tt.addr, got, tt.want)
+			}
+		})
+	}
+
+	if isPublicWebhookAddr(netip.Addr{}) {
+		t.Fatal("isPublicWebhookAddr() of the zero addr = true, want false")
+	}
+}
+
+func TestSealWebhookSecret(t *testing.T) {
+	sealed, err := sealWebhookSecret(testWebhookSecretKey, "topsecretvalue16")
+	if err != nil {
+		t.Fatalf("sealWebhookSecret() got error %v", err)
+	}
+
+	if !strings.HasPrefix(sealed, webhookSecretPrefix) || strings.Contains(sealed, "topsecretvalue16") {
+		t.Fatalf("sealWebhookSecret() = %q, want the secret encrypted", sealed)
+	}
+
+	// a fresh nonce every time
+	if again, _ := sealWebhookSecret(testWebhookSecretKey, "topsecretvalue16"); again == sealed {
+		t.Fatalf("sealWebhookSecret() twice = %q, want different ciphertexts", sealed)
+	}
+
+	secret, err := openWebhookSecret(testWebhookSecretKey, sealed)
+	if err != nil || secret != "topsecretvalue16" {
+		t.Fatalf("openWebhookSecret() = %q, %v, want the secret", secret, err)
+	}
+
+	_, err = openWebhookSecret(strings.Repeat("ff", 32), sealed)
+	if err == nil {
+		t.Fatal("openWebhookSecret() under another key got no error")
+	}
+
+	_, err = openWebhookSecret("", sealed)
+	if !errors.Is(err, errWebhookSecretKeyMissing) {
+		t.Fatalf("openWebhookSecret() without a key got error %v, want errWebhookSecretKeyMissing", err)
+	}
+
+	_, err = openWebhookSecret(testWebhookSecretKey, sealed[:len(webhookSecretPrefix)+4])
+	if err == nil {
+		t.Fatal("openWebhookSecret() of a truncated secret got no error")
+	}
+}
+
+func TestSealWebhookSecretWithoutKey(t *testing.T) {
+	sealed, err := sealWebhookSecret("", "topsecretvalue16")
+	if err != nil || sealed != "topsecretvalue16" {
+		t.Fatalf("sealWebhookSecret() without a key = %q, %v, want the secret as given", sealed, err)
+	}
+
+	// stored before a key was configured
+	secret, err := openWebhookSecret(testWebhookSecretKey, "topsecretvalue16")
+	if err != nil || secret != "topsecretvalue16" {
+		t.Fatalf("openWebhookSecret() of a plain secret = %q, %v, want it as stored", secret, err)
+	}
+}
+
+func TestWebhookDispatcherBackoff(t *testing.T) {
+	dispatcher := NewWebhookDispatcher(repository.ProductRepository{}, config.WebhookConfig{
+		Enabled:        true,
+		InitialBackoff: 30 * time.Second,
+		MaxBackoff:     time.Hour,
+	})
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{7, 32 * time.Minute},
+		{8, time.Hour},
+		{100, time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := dispatcher.backoff(tt.attempts); got != tt.want {
+			t.Fatalf("backoff(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestNewWebhookDispatcherLease(t *testing.T) {
+	dispatcher := NewWebhookDispatcher(repository.ProductRepository{}, config.WebhookConfig{
+		Enabled:      true,
+		Timeout:      time.Minute,
+		LeaseTimeout: time.Minute,
+	})
+
+	// a delivery must not be claimed again while it's still being sent
+	if dispatcher.Config.LeaseTimeout != 2*time.Minute {
+		t.Fatalf("LeaseTimeout = %s, want twice the request timeout", dispatcher.Config.LeaseTimeout)
+	}
+
+	if NewWebhookDispatcher(repository.ProductRepository{}, config.WebhookConfig{}) != nil {
+		t.Fatal("NewWebhookDispatcher() of disabled webhooks isn't nil")
+	}
+}
+
+// webhookStatement is a statement the fake webhook database ran, with the value of every column it sets.
+type webhookStatement struct {
+	query  string
+	values map[string]driver.Value
+}
+
+// webhookDB is a database of one webhook subscription, handing its pending deliveries to the first claim.
+type webhookDB struct {
+	mu           sync.Mutex
+	subscription []driver.Value
+	deliveries   [][]driver.Value
+	statements   []webhookStatement
+}
+
+var (
+	webhookSubscriptionColumns = []string{"id", "url", "event_types", "secret", "active", "consecutive_failures", "disabled_at", "created_at", "updated_at"}
+	webhookDeliveryColumns     = []string{"id", "subscription_id", "event_id", "event_type", "payload", "status", "attempts", "next_attempt_at", "last_status_code", "last_error", "delivered_at", "created_at", "updated_at"}
+
+	// setColumn matches a column set by gorm, e.g. "status"=$1
+	setColumn = regexp.MustCompile(`"(\w+)"=\$(\d+)`)
+)
+
+// newWebhookDispatcherWithDB new webhook dispatcher with db by given webhookDB pointer, and WebhookConfig.
+func newWebhookDispatcherWithDB(t *testing.T, db *webhookDB, webhookConfig config.WebhookConfig) *WebhookDispatcher {
+	t.Helper()
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: sql.OpenDB(webhookConnector{db: db})}), &gorm.Config{
+		Logger: logger.Discard,
+	})
+	if err != nil {
+		t.Fatalf("gorm.Open() got error %v", err)
+	}
+
+	webhookConfig.Enabled = true
+	webhookConfig.AllowInsecureURLs = true // the test server listens on loopback
+	webhookConfig.SecretKey = testWebhookSecretKey
+
+	return NewWebhookDispatcher(repository.ProductRepository{Database: gormDB}, webhookConfig)
+}
+
+// newWebhookDB new webhook db by given url, active of the subscription, and attempts already made of its one pending delivery.
+func newWebhookDB(url string, active bool, attempts int) *webhookDB {
+	now := time.Now()
+
+	return &webhookDB{
+		subscription: []driver.Value{int64(1), url, `["product.created"]`, "topsecretvalue16", active, int64(0), nil, now, now},
+		deliveries: [][]driver.Value{
+			{int64(10), int64(1), "event-1", models.WebhookEventProductCreated, `{"id":"event-1"}`, models.WebhookDeliveryPending, int64(attempts), now, int64(0), "", nil, now, now},
+		},
+	}
+}
+
+// statement statement by given prefix of the query.
+//
+// It returns pointer of webhookStatement of the last statement starting with prefix, or nil when there's none.
+func (db *webhookDB) statement(prefix string) *webhookStatement {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for i := len(db.statements) - 1; i >= 0; i-- {
+		if strings.HasPrefix(strings.TrimSpace(db.statements[i].query), prefix) {
+			return &db.statements[i]
+		}
+	}
+
+	return nil
+}
+
+// record record by given query, and args, and answers it.
+func (db *webhookDB) record(query string, args []driver.NamedValue) (*webhookRows, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	statement := webhookStatement{query: query, values: map[string]driver.Value{}}
+	for _, match := range setColumn.FindAllStringSubmatch(query, -1) {
+		n, _ := strconv.Atoi(match[2])
+		statement.values[match[1]] = args[n-1].Value
+	}
+
+	db.statements = append(db.statements, statement)
+
+	query = strings.TrimSpace(query)
+	switch {
+	case strings.HasPrefix(query, "UPDATE webhook_delivery"):
+		rows := &webhookRows{columns: webhookDeliveryColumns, rows: db.deliveries}
+		db.deliveries = nil
+		return rows, nil
+	case strings.HasPrefix(query, "UPDATE webhook_subscription"), strings.HasPrefix(query, `SELECT * FROM "webhook_subscription"`):
+		return &webhookRows{columns: webhookSubscriptionColumns, rows: [][]driver.Value{db.subscription}}, nil
+	default:
+		return &webhookRows{}, nil
+	}
+}
+
+type webhookConnector struct {
+	db *webhookDB
+}
+
+func (c webhookConnector) Connect(context.Context) (driver.Conn, error) {
+	return webhookConn(c), nil
+}
+
+func (webhookConnector) Driver() driver.Driver {
+	return nil
+}
+
+type webhookConn webhookConnector
+
+func (webhookConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("fake db doesn't prepare %q", query)
+}
+
+func (webhookConn) Close() error {
+	return nil
+}
+
+func (webhookConn) Begin() (driver.Tx, error) {
+	return webhookTx{}, nil
+}
+
+func (c webhookConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.db.record(query, args)
+}
+
+func (c webhookConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	_, err := c.db.record(query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return driver.RowsAffected(1), nil
+}
+
+type webhookTx struct{}
+
+func (webhookTx) Commit() error {
+	return nil
+}
+
+func (webhookTx) Rollback() error {
+	return nil
+}
+
+type webhookRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *webhookRows) Columns() []string {
+	return r.columns
+}
+
+func (r *webhookRows) Close() error {
+	return nil
+}
+
+func (r *webhookRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+
+	return nil
+}
+
+func TestWebhookDispatcherDelivers(t *testing.T) {
+	var got *http.Request
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		got, body = r, string(raw)
+	}))
+	defer server.Close()
+
+	db := newWebhookDB(server.URL, true, 0)
+	dispatcher := newWebhookDispatcherWithDB(t, db, config.WebhookConfig{BatchSize: 5, LeaseTimeout: time.Minute, Timeout: time.Second})
+
+	if claimed := dispatcher.deliverDue(context.Background()); claimed != 1 {
+		t.Fatalf("deliverDue() = %d, want 1", claimed)
+	}
+
+	if got == nil || body != `{"id":"event-1"}` {
+		t.Fatalf("webhook got %q, want the payload", body)
+	}
+
+	// signed with the secret as given, not as stored
+	timestamp := got.Header.Get(WebhookHeaderTimestamp)
+	if want := "sha256=" + SignWebhookPayload("topsecretvalue16", timestamp, []byte(body)); got.Header.Get(WebhookHeaderSignature) != want {
+		t.Fatalf("signature = %s, want %s", got.Header.Get(WebhookHeaderSignature), want)
+	}
+
+	if got.Header.Get(WebhookHeaderID) != "event-1" || got.Header.Get(WebhookHeaderEvent) != models.WebhookEventProductCreated {
+		t.Fatalf("webhook headers = %v, want the event id and type", got.Header)
+	}
+
+	update := db.statement(`UPDATE "webhook_delivery"`)
+	if update == nil || update.values["status"] != models.WebhookDeliverySucceeded || update.values["attempts"] != int64(1) || update.values["delivered_at"] == nil {
+		t.Fatalf("delivery update = %+v, want succeeded after 1 attempt", update)
+	}
+
+	if db.statement(`UPDATE "webhook_subscription"`) == nil {
+		t.Fatal("the failures of the subscription weren't reset")
+	}
+}
+
+func TestWebhookDispatcherClaims(t *testing.T) {
+	db := newWebhookDB("http://127.0.0.1:1", true, 0)
+	db.deliveries = nil
+	dispatcher := newWebhookDispatcherWithDB(t, db, config.WebhookConfig{BatchSize: 5, LeaseTimeout: time.Minute, Timeout: time.Second})
+
+	if claimed := dispatcher.deliverDue(context.Background()); claimed != 0 {
+		t.Fatalf("deliverDue() = %d, want 0", claimed)
+	}
+
+	// the due deliveries are leased, other instances skip the locked ones
+	claim := db.statement("UPDATE webhook_delivery")
+	if claim == nil || !strings.Contains(claim.query, "FOR UPDATE SKIP LOCKED") {
+		t.Fatalf("claim = %+v, want the due deliveries skipping locked ones", claim)
+	}
+}
+
+func TestWebhookDispatcherRetries(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down for maintenance", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		attempts int
+		status   string
+	}{
+		{"retried", 0, models.WebhookDeliveryPending},
+		{"out of attempts", 2, models.WebhookDeliveryFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newWebhookDB(server.URL, true, tt.attempts)
+			dispatcher := newWebhookDispatcherWithDB(t, db, config.WebhookConfig{
+				BatchSize:            5,
+				Timeout:              time.Second,
+				MaxAttempts:          3,
+				InitialBackoff:       time.Minute,
+				DisableAfterFailures: 4,
+			})
+
+			start := time.Now()
+			dispatcher.deliverDue(context.Background())
+
+			update := db.statement(`UPDATE "webhook_delivery"`)
+			if update == nil || update.values["status"] != tt.status || update.values["attempts"] != int64(tt.attempts+1) || update.values["last_status_code"] != int64(http.StatusServiceUnavailable) {
+				t.Fatalf("delivery update = %+v, want %s after %d attempts", update, tt.status, tt.attempts+1)
+			}
+
+			if lastError, _ := update.values["last_error"].(string); !strings.Contains(lastError, "down for maintenance") {
+				t.Fatalf("last_error = %q, want the response", lastError)
+			}
+
+			if nextAttemptAt, _ := update.values["next_attempt_at"].(time.Time); tt.status == models.WebhookDeliveryPending && nextAttemptAt.Before(start.Add(time.Minute)) {
+				t.Fatalf("next_attempt_at = %s, want a minute from now", nextAttemptAt)
+			}
+
+			// counted towards disabling the subscription
+			failure := db.statement("UPDATE webhook_subscription")
+			if failure == nil || !strings.Contains(failure.query, "consecutive_failures + 1") {
+				t.Fatalf("failure = %+v, want the failures of the subscription counted", failure)
+			}
+		})
+	}
+}
+
+func TestWebhookDispatcherSkipsDisabled(t *testing.T) {
+	sent := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent = true
+	}))
+	defer server.Close()
+
+	db := newWebhookDB(server.URL, false, 0)
+	dispatcher := newWebhookDispatcherWithDB(t, db, config.WebhookConfig{BatchSize: 5, Timeout: time.Second})
+	dispatcher.deliverDue(context.Background())
+
+	if sent {
+		t.Fatal("a disabled webhook was sent a delivery")
+	}
+
+	update := db.statement(`UPDATE "webhook_delivery"`)
+	if update == nil || update.values["status"] != models.WebhookDeliveryFailed || update.values["last_error"] != "webhook is disabled" {
+		t.Fatalf("delivery update = %+v, want failed as disabled", update)
+	}
+}
@@ -38,6 +38,76 @@ func (uc *ProductUsecase) GetProductByID(ctx context.Context, productID int64) (
 	return product, nil
 }
 
+// GetProductsByIDs get products by ids by given slice of productIDs.
+//
+// It returns slice of models.BatchProductResult, and nil error when successful.
+// Otherwise, nil value of models.BatchProductResult slice, and error will be returned.
+func (uc *ProductUsecase) GetProductsByIDs(ctx context.Context, productIDs []int64) ([]models.BatchProductResult, error) {
+	results, err := uc.ProductService.GetProductsByIDs(ctx, productIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// DeductProductStockByProductID deduct product stock by product id by given productID, and qty.
+//
+// It returns nil error when successful.
+// Otherwise, error will be returned.
+func (uc *ProductUsecase) DeductProductStockByProductID(ctx context.Context, productID int64, qty int) error {
+	err := uc.ProductService.DeductProductStockByProductID(ctx, productID, qty)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// AddProductStockByProductID add product stock by product id by given productID, and qty.
+//
+// It returns nil error when successful.
+// Otherwise, error will be returned.
+func (uc *ProductUsecase) AddProductStockByProductID(ctx context.Context, productID int64, qty int) error {
+	err := uc.ProductService.AddProductStockByProductID(ctx, productID, qty)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SubscribeProductStock subscribe product stock by given slice of productIDs.
+//
+// It returns pointer of service.ProductStockSubscription, and nil error when successful.
+// Otherwise, nil pointer of service.ProductStockSubscription, and error will be returned.
+func (uc *ProductUsecase) SubscribeProductStock(productIDs []int64) (*service.ProductStockSubscription, error) {
+	subscription, err := uc.ProductService.StockStream.Subscribe(productIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return subscription, nil
+}
+
+// GetProductStockSnapshot get product stock snapshot by given slice of productIDs.
+//
+// It returns slice of models.ProductStockEvent, and nil error when successful.
+// Otherwise, nil value of models.ProductStockEvent slice, and error will be returned.
+func (uc *ProductUsecase) GetProductStockSnapshot(ctx context.Context, productIDs []int64) ([]models.ProductStockEvent, error) {
+	events, err := uc.ProductService.GetProductStockSnapshot(ctx, productIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// UnsubscribeProductStock unsubscribe product stock by given subscription pointer of service.ProductStockSubscription.
+func (uc *ProductUsecase) UnsubscribeProductStock(subscription *service.ProductStockSubscription) {
+	uc.ProductService.StockStream.Unsubscribe(subscription)
+}
+
 // GetProductCategoryByID get product category by id by given productCategoryID.
 //
 // It returns pointer of models.ProductCategory, and nil error when successful.
@@ -51,6 +121,19 @@ func (uc *ProductUsecase) GetProductCategoryByID(ctx context.Context, productCat
 	return productCategory, nil
 }
 
+// GetProductCategoriesByIDs get product categories by ids by given slice of productCategoryIDs.
+//
+// It returns map of models.ProductCategory by id, and nil error when successful.
+// Otherwise, nil map, and error will be returned.
+func (uc *ProductUsecase) GetProductCategoriesByIDs(ctx context.Context, productCategoryIDs []int) (map[int]models.ProductCategory, error) {
+	productCategories, err := uc.ProductService.GetProductCategoriesByIDs(ctx, productCategoryIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return productCategories, nil
+}
+
 // CreateNewProduct create new product by given param pointer of models.Product.
 //
 // It returns int64, and nil error when successful.
@@ -97,6 +180,19 @@ func (uc *ProductUsecase) EditProduct(ctx context.Context, param *models.Product
 	return product, nil
 }
 
+// PatchProduct patch product by given productID, and fields.
+//
+// It returns pointer of models.Product, and nil error when successful.
+// Otherwise, nil pointer of models.Product, and error will be returned.
+func (uc *ProductUsecase) PatchProduct(ctx context.Context, productID int64, fields map[string]interface{}) (*models.Product, error) {
+	product, err := uc.ProductService.PatchProduct(ctx, productID, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	return product, nil
+}
+
 // EditProductCategory edit product category by given param pointer of models.ProductCategory.
 //
 // It returns pointer of models.ProductCategory, and nil error when successful.
@@ -138,13 +234,182 @@ func (uc *ProductUsecase) DeleteProductCategory(ctx context.Context, productCate
 
 // SearchProduct search product by given SearchProductParameter.
 //
-// It returns slice of models.Product, int, and nil error when successful.
-// Otherwise, nil value of models.Product slice, empty int, and error will be returned.
-func (uc *ProductUsecase) SearchProduct(ctx context.Context, param models.SearchProductParameter) ([]models.Product, int, error) {
-	products, totalCount, err := uc.ProductService.SearchProduct(ctx, param)
+// It returns pointer of models.SearchProductResult, and nil error when successful.
+// Otherwise, nil pointer of models.SearchProductResult, and error will be returned.
+func (uc *ProductUsecase) SearchProduct(ctx context.Context, param models.SearchProductParameter) (*models.SearchProductResult, error) {
+	result, err := uc.ProductService.SearchProduct(ctx, param)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// SuggestProductNames suggest product names by given term.
+//
+// It returns slice of string, and nil error when successful.
+// Otherwise, nil value of string slice, and error will be returned.
+func (uc *ProductUsecase) SuggestProductNames(ctx context.Context, term string) ([]string, error) {
+	names, err := uc.ProductService.SuggestProductNames(ctx, term)
+	if err != nil {
+		return nil, err
+	}
+
+	return names, nil
+}
+
+// Autocomplete autocomplete by given prefix, limit, and weighted.
+//
+// It returns slice of models.AutocompleteSuggestion, and nil error when successful.
+// Otherwise, nil value of models.AutocompleteSuggestion slice, and error will be returned.
+func (uc *ProductUsecase) Autocomplete(ctx context.Context, prefix string, limit int, weighted bool) ([]models.AutocompleteSuggestion, error) {
+	suggestions, err := uc.ProductService.Autocomplete(ctx, prefix, limit, weighted)
+	if err != nil {
+		return nil, err
+	}
+
+	return suggestions, nil
+}
+
+// SearchProductFacets search product facets by given SearchProductParameter, and slice of facets.
+//
+// It returns pointer of models.SearchProductFacets, and nil error when successful.
+// Otherwise, nil pointer of models.SearchProductFacets, and error will be returned.
+func (uc *ProductUsecase) SearchProductFacets(ctx context.Context, param models.SearchProductParameter, facets []string) (*models.SearchProductFacets, error) {
+	searchProductFacets, err := uc.ProductService.SearchProductFacets(ctx, param, facets)
+	if err != nil {
+		return nil, err
+	}
+
+	return searchProductFacets, nil
+}
+
+// RecordSearchClick record search click by given SearchClickParameter.
+//
+// It returns nil error when successful.
+// Otherwise, error will be returned.
+func (uc *ProductUsecase) RecordSearchClick(ctx context.Context, param models.SearchClickParameter) error {
+	err := uc.ProductService.RecordSearchClick(ctx, param)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetTopSearchQueries get top search queries by given SearchReportParameter.
+//
+// It returns slice of models.SearchQueryStat, and nil error when successful.
+// Otherwise, nil value of models.SearchQueryStat slice, and error will be returned.
+func (uc *ProductUsecase) GetTopSearchQueries(ctx context.Context, param models.SearchReportParameter) ([]models.SearchQueryStat, error) {
+	stats, err := uc.ProductService.GetTopSearchQueries(ctx, param)
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// GetZeroResultSearchQueries get zero result search queries by given SearchReportParameter.
+//
+// It returns slice of models.SearchQueryStat, and nil error when successful.
+// Otherwise, nil value of models.SearchQueryStat slice, and error will be returned.
+func (uc *ProductUsecase) GetZeroResultSearchQueries(ctx context.Context, param models.SearchReportParameter) ([]models.SearchQueryStat, error) {
+	stats, err := uc.ProductService.GetZeroResultSearchQueries(ctx, param)
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// GetSearchClickThroughRates get search click through rates by given SearchReportParameter.
+//
+// It returns slice of models.SearchClickThroughStat, and nil error when successful.
+// Otherwise, nil value of models.SearchClickThroughStat slice, and error will be returned.
+func (uc *ProductUsecase) GetSearchClickThroughRates(ctx context.Context, param models.SearchReportParameter) ([]models.SearchClickThroughStat, error) {
+	stats, err := uc.ProductService.GetSearchClickThroughRates(ctx, param)
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// CreateWebhookSubscription create webhook subscription by given param pointer of models.WebhookSubscription.
+//
+// It returns pointer of models.WebhookSubscription including its secret, and nil error when successful.
+// Otherwise, nil pointer of models.WebhookSubscription, and error will be returned.
+func (uc *ProductUsecase) CreateWebhookSubscription(ctx context.Context, param *models.WebhookSubscription) (*models.WebhookSubscription, error) {
+	subscription, err := uc.ProductService.CreateWebhookSubscription(ctx, param)
 	if err != nil {
-		return nil, 0, err
+		return nil, err
+	}
+
+	return subscription, nil
+}
+
+// GetWebhookSubscriptions get webhook subscriptions.
+//
+// It returns slice of models.WebhookSubscription, and nil error when successful.
+// Otherwise, nil value of models.WebhookSubscription slice, and error will be returned.
+func (uc *ProductUsecase) GetWebhookSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	subscriptions, err := uc.ProductService.GetWebhookSubscriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return subscriptions, nil
+}
+
+// GetWebhookSubscriptionByID get webhook subscription by id by given subscriptionID.
+//
+// It returns pointer of models.WebhookSubscription, and nil error when successful.
+// Otherwise, nil pointer of models.WebhookSubscription, and error will be returned.
+func (uc *ProductUsecase) GetWebhookSubscriptionByID(ctx context.Context, subscriptionID int64) (*models.WebhookSubscription, error) {
+	subscription, err := uc.ProductService.GetWebhookSubscriptionByID(ctx, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return subscription, nil
+}
+
+// EditWebhookSubscription edit webhook subscription by given subscription pointer of models.WebhookSubscription.
+//
+// It returns pointer of models.WebhookSubscription, and nil error when successful.
+// Otherwise, nil pointer of models.WebhookSubscription, and error will be returned.
+func (uc *ProductUsecase) EditWebhookSubscription(ctx context.Context, subscription *models.WebhookSubscription) (*models.WebhookSubscription, error) {
+	updatedSubscription, err := uc.ProductService.EditWebhookSubscription(ctx, subscription)
+	if err != nil {
+		return nil, err
+	}
+
+	return updatedSubscription, nil
+}
+
+// DeleteWebhookSubscription delete webhook subscription by given subscriptionID.
+//
+// It returns nil error when successful.
+// Otherwise, error will be returned.
+func (uc *ProductUsecase) DeleteWebhookSubscription(ctx context.Context, subscriptionID int64) error {
+	err := uc.ProductService.DeleteWebhookSubscription(ctx, subscriptionID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetWebhookDeliveries get webhook deliveries by given subscriptionID, and limit.
+//
+// It returns slice of models.WebhookDelivery, and nil error when successful.
+// Otherwise, nil value of models.WebhookDelivery slice, and error will be returned.
+func (uc *ProductUsecase) GetWebhookDeliveries(ctx context.Context, subscriptionID int64, limit int) ([]models.WebhookDelivery, error) {
+	deliveries, err := uc.ProductService.GetWebhookDeliveries(ctx, subscriptionID, limit)
+	if err != nil {
+		return nil, err
 	}
 
-	return products, totalCount, nil
+	return deliveries, nil
 }
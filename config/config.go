@@ -3,15 +3,17 @@ package config
 import (
 	// golang package
 	"log"
+	"reflect"
 
 	// external package
+	"github.com/go-playground/validator/v10"
 	"github.com/spf13/viper"
 )
 
 // LoadConfig load config.
 //
 // It returns Config when successful.
-// Otherwise, empty Config will be returned.
+// Otherwise, it exits when the config can't be read or is invalid.
 func LoadConfig() Config {
 	var cfg Config
 
@@ -28,5 +30,41 @@ func LoadConfig() Config {
 		log.Fatalf("error unmarshal config: %v", err)
 	}
 
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	err = validate.RegisterValidation("strictly_ascending", strictlyAscending)
+	if err != nil {
+		log.Fatalf("error register config validation: %v", err)
+	}
+
+	// every violated rule is reported at once
+	err = validate.Struct(cfg)
+	if err != nil {
+		log.Fatalf("error validate config: %v", err)
+	}
+
 	return cfg
 }
+
+// strictlyAscending strictly ascending by given fl validator.FieldLevel.
+//
+// It returns true when every element of the slice is greater than the one before it, i.e. sorted and unique.
+func strictlyAscending(fl validator.FieldLevel) bool {
+	field := fl.Field()
+	if field.Kind() != reflect.Slice {
+		return false
+	}
+
+	switch field.Type().Elem().Kind() {
+	case reflect.Float32, reflect.Float64:
+	default:
+		return false
+	}
+
+	for i := 1; i < field.Len(); i++ {
+		if field.Index(i-1).Float() >= field.Index(i).Float() {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	// golang package
+	"testing"
+
+	// external package
+	"github.com/go-playground/validator/v10"
+)
+
+func TestStrictlyAscending(t *testing.T) {
+	validate := validator.New()
+	err := validate.RegisterValidation("strictly_ascending", strictlyAscending)
+	if err != nil {
+		t.Fatalf("RegisterValidation() got error %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		buckets []float64
+		valid   bool
+	}{
+		{"none", nil, true},
+		{"one", []float64{100}, true},
+		{"ascending", []float64{100, 500, 1000}, true},
+		{"descending", []float64{1000, 500}, false},
+		{"unsorted", []float64{100, 1000, 500}, false},
+		{"duplicated", []float64{100, 500, 500}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validate.Var(tt.buckets, "strictly_ascending")
+			if valid := err == nil; valid != tt.valid {
+				t.Fatalf("validate %v = %v, want %v", tt.buckets, err, tt.valid)
+			}
+		})
+	}
+
+	if validate.Var([]int{1, 2}, "strictly_ascending") == nil {
+		t.Fatal("validate of an int slice got no error, want one")
+	}
+}
@@ -1,15 +1,29 @@
 package config
 
+import "time"
+
 type Config struct {
 	App      AppConfig      `yaml:"app" validate:"required"`
+	GRPC     GRPCConfig     `yaml:"grpc" validate:"required"`
 	Database DatabaseConfig `yaml:"database" validate:"required"`
 	Redis    RedisConfig    `yaml:"redis" validate:"required"`
+	Cache    CacheConfig    `yaml:"cache"`
+	Search   SearchConfig   `yaml:"search"`
+
+	Idempotency IdempotencyConfig `yaml:"idempotency"`
+	GraphQL     GraphQLConfig     `yaml:"graphql"`
+	StockStream StockStreamConfig `yaml:"stockStream"`
+	Webhook     WebhookConfig     `yaml:"webhook"`
 }
 
 type AppConfig struct {
 	Port string `yaml:"port" validate:"required"`
 }
 
+type GRPCConfig struct {
+	Port string `yaml:"port" validate:"required"`
+}
+
 type DatabaseConfig struct {
 	Host     string `yaml:"host" validate:"required"`
 	User     string `yaml:"user" validate:"required"`
@@ -21,5 +35,92 @@ type DatabaseConfig struct {
 type RedisConfig struct {
 	Host     string `yaml:"host" validate:"required"`
 	Port     string `yaml:"port" validate:"required"`
-	Password string `yaml:"password" validate:"required"`
+	Password string `yaml:"password"` // empty when redis has no auth
+}
+
+type CacheConfig struct {
+	WarmUp            WarmUpConfig         `yaml:"warmUp"`
+	SearchTTL         time.Duration        `yaml:"searchTTL" validate:"gte=0"` // zero disables the search cache
+	StaleReadTimeout  time.Duration        `yaml:"staleReadTimeout" validate:"gt=0"`
+	RevalidateTimeout time.Duration        `yaml:"revalidateTimeout" validate:"gt=0"` // bounds the background reload of a stale product
+	RedisBreaker      CircuitBreakerConfig `yaml:"redisBreaker"`
+}
+
+type WarmUpConfig struct {
+	Enabled      bool `yaml:"enabled"`
+	ProductLimit int  `yaml:"productLimit" validate:"required_if=Enabled true,gte=0"`
+	BatchSize    int  `yaml:"batchSize" validate:"required_if=Enabled true,gte=0"`
+}
+
+type CircuitBreakerConfig struct {
+	FailureThreshold int           `yaml:"failureThreshold" validate:"gte=0"`
+	OpenTimeout      time.Duration `yaml:"openTimeout" validate:"gte=0"`
+}
+
+type SearchConfig struct {
+	SimilarityThreshold float64   `yaml:"similarityThreshold" validate:"gte=0,lte=1"`
+	SuggestionLimit     int       `yaml:"suggestionLimit" validate:"gte=0"`
+	AutocompleteLimit   int       `yaml:"autocompleteLimit" validate:"gte=0"`
+	PriceBuckets        []float64 `yaml:"priceBuckets" validate:"strictly_ascending,dive,gt=0"` // thresholds of width_bucket, ascending and unique
+	DefaultPageSize     int       `yaml:"defaultPageSize" validate:"gt=0"`
+	MaxPageSize         int       `yaml:"maxPageSize" validate:"gt=0,gtefield=DefaultPageSize"`
+	MaxBatchProductIDs  int       `yaml:"maxBatchProductIds" validate:"gt=0"`           // per batch lookup, and per ids filter of a search
+	Backend             string    `yaml:"backend" validate:"omitempty,oneof=sql index"` // "sql" or "index", facets and suggestions are always from sql
+
+	Index     SearchIndexConfig     `yaml:"index"`
+	Analytics SearchAnalyticsConfig `yaml:"analytics"`
+}
+
+type SearchAnalyticsConfig struct {
+	Enabled       bool          `yaml:"enabled"`
+	BufferSize    int           `yaml:"bufferSize" validate:"gte=0"` // events beyond it are dropped rather than slowing searches down
+	BatchSize     int           `yaml:"batchSize" validate:"gte=0"`
+	FlushInterval time.Duration `yaml:"flushInterval" validate:"gte=0"`
+}
+
+// SearchIndexConfig is a Meilisearch compatible search engine.
+type SearchIndexConfig struct {
+	BaseURL   string        `yaml:"baseUrl" validate:"omitempty,url"`
+	APIKey    string        `yaml:"apiKey"`
+	Name      string        `yaml:"name"`
+	Timeout   time.Duration `yaml:"timeout" validate:"gte=0"`
+	BatchSize int           `yaml:"batchSize" validate:"gte=0"`
+}
+
+// IdempotencyConfig is how long an Idempotency-Key and its response are kept.
+type IdempotencyConfig struct {
+	Enabled     bool          `yaml:"enabled"`
+	TTL         time.Duration `yaml:"ttl" validate:"required_if=Enabled true,gte=0"`
+	LockTimeout time.Duration `yaml:"lockTimeout" validate:"required_if=Enabled true,gte=0"` // how long a request in progress holds its key
+}
+
+// GraphQLConfig bounds the queries /graphql accepts.
+type GraphQLConfig struct {
+	MaxDepth      int `yaml:"maxDepth" validate:"gt=0"`
+	MaxComplexity int `yaml:"maxComplexity" validate:"gt=0"` // every field costs 1, multiplied by the page size under a list
+}
+
+// StockStreamConfig bounds GET /v1/product/stream.
+type StockStreamConfig struct {
+	MaxConnections    int           `yaml:"maxConnections" validate:"gt=0"` // per instance
+	MaxProductIDs     int           `yaml:"maxProductIds" validate:"gt=0"`  // per connection
+	BufferSize        int           `yaml:"bufferSize" validate:"gt=0"`     // events a connection may lag behind before it's closed
+	HeartbeatInterval time.Duration `yaml:"heartbeatInterval" validate:"gt=0"`
+	MaxDuration       time.Duration `yaml:"maxDuration" validate:"gt=0"` // the client reconnects once it's over
+}
+
+// WebhookConfig is how webhook deliveries are sent and retried.
+type WebhookConfig struct {
+	Enabled              bool          `yaml:"enabled"`
+	PollInterval         time.Duration `yaml:"pollInterval" validate:"gte=0"` // deliveries due for a retry are picked up this often
+	BatchSize            int           `yaml:"batchSize" validate:"gte=0"`
+	Concurrency          int           `yaml:"concurrency" validate:"gte=0"`
+	Timeout              time.Duration `yaml:"timeout" validate:"gte=0"`      // per request
+	LeaseTimeout         time.Duration `yaml:"leaseTimeout" validate:"gte=0"` // a claimed delivery is retried by another instance once it's over
+	MaxAttempts          int           `yaml:"maxAttempts" validate:"gte=0"`
+	InitialBackoff       time.Duration `yaml:"initialBackoff" validate:"gte=0"` // doubled on every attempt
+	MaxBackoff           time.Duration `yaml:"maxBackoff" validate:"gte=0"`
+	DisableAfterFailures int           `yaml:"disableAfterFailures" validate:"gte=0"`                                      // consecutive failed attempts before a webhook is disabled
+	AllowInsecureURLs    bool          `yaml:"allowInsecureUrls"`                                                          // development only, http URLs and private addresses are allowed
+	SecretKey            string        `yaml:"secretKey" validate:"required_if=Enabled true,omitempty,hexadecimal,len=64"` // AES-256 key the webhook secrets are encrypted with in DB
 }
@@ -0,0 +1,5 @@
+ALTER TABLE product ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+ALTER TABLE product ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+-- used by the cache warm-up to pick the most recently updated products
+CREATE INDEX IF NOT EXISTS idx_product_updated_at ON product (updated_at DESC);
@@ -0,0 +1,22 @@
+ALTER TABLE product ADD COLUMN IF NOT EXISTS search_vector TSVECTOR;
+
+CREATE OR REPLACE FUNCTION product_search_vector_update() RETURNS TRIGGER AS $$
+BEGIN
+    NEW.search_vector :=
+        setweight(to_tsvector('simple', COALESCE(NEW.name, '')), 'A') ||
+        setweight(to_tsvector('simple', COALESCE(NEW.description, '')), 'B');
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_product_search_vector ON product;
+CREATE TRIGGER trg_product_search_vector
+    BEFORE INSERT OR UPDATE OF name, description ON product
+    FOR EACH ROW EXECUTE FUNCTION product_search_vector_update();
+
+-- backfill existing rows
+UPDATE product SET search_vector =
+    setweight(to_tsvector('simple', COALESCE(name, '')), 'A') ||
+    setweight(to_tsvector('simple', COALESCE(description, '')), 'B');
+
+CREATE INDEX IF NOT EXISTS idx_product_search_vector ON product USING GIN (search_vector);
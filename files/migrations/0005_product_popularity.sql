@@ -0,0 +1,2 @@
+-- units sold, bumped by the stock.update consumer and reverted by stock.rollback
+ALTER TABLE product ADD COLUMN IF NOT EXISTS popularity BIGINT NOT NULL DEFAULT 0;
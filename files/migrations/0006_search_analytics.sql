@@ -0,0 +1,25 @@
+-- one row per search request, written in batches by the search analytics sink
+CREATE TABLE IF NOT EXISTS search_event (
+    id BIGSERIAL PRIMARY KEY,
+    search_id UUID NOT NULL,
+    term TEXT NOT NULL DEFAULT '',
+    filters JSONB NOT NULL DEFAULT '{}',
+    result_count INT NOT NULL DEFAULT 0,
+    latency_ms DOUBLE PRECISION NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_search_event_search_id ON search_event (search_id);
+CREATE INDEX IF NOT EXISTS idx_search_event_created_at ON search_event (created_at);
+
+-- a product opened from a search result page
+CREATE TABLE IF NOT EXISTS search_click (
+    id BIGSERIAL PRIMARY KEY,
+    search_id UUID NOT NULL,
+    product_id BIGINT NOT NULL,
+    position INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_search_click_search_id ON search_click (search_id);
+CREATE INDEX IF NOT EXISTS idx_search_click_created_at ON search_click (created_at);
@@ -0,0 +1,3 @@
+-- bumped by every write, orders cache and search index writes of the same row
+ALTER TABLE product ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE product_category ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
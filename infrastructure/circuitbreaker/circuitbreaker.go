@@ -0,0 +1,201 @@
+package circuitbreaker
+
+import (
+	// golang package
+	"context"
+	"errors"
+	"productfc/infrastructure/log"
+	"sync"
+	"time"
+
+	// external package
+	"github.com/sirupsen/logrus"
+)
+
+var ErrOpen = errors.New("circuit breaker is open")
+
+type State int
+
+const (
+	StateClosed State = iota
+	StateOpen
+	StateHalfOpen
+)
+
+// String string.
+//
+// It returns string of State.
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+type CircuitBreaker struct {
+	name             string
+	failureThreshold int
+	openTimeout      time.Duration
+
+	mu         sync.Mutex
+	state      State
+	generation uint64 // bumped on every state change, outcomes of calls allowed in an earlier one are ignored
+	failures   int
+	openedAt   time.Time
+	probing    bool // a half-open trial call is in flight
+}
+
+// New new circuit breaker by given name, failureThreshold, and openTimeout.
+//
+// The breaker opens after failureThreshold consecutive failures and lets a single
+// trial call through once openTimeout has passed.
+//
+// It returns pointer of CircuitBreaker when successful.
+// Otherwise, nil pointer of CircuitBreaker will be returned.
+func New(name string, failureThreshold int, openTimeout time.Duration) *CircuitBreaker {
+	if failureThreshold <= 0 {
+		failureThreshold = 1
+	}
+
+	return &CircuitBreaker{
+		name:             name,
+		failureThreshold: failureThreshold,
+		openTimeout:      openTimeout,
+		state:            StateClosed,
+	}
+}
+
+// State state.
+//
+// It returns current State of the breaker.
+func (cb *CircuitBreaker) State() State {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	return cb.state
+}
+
+// Execute execute by given fn.
+//
+// A context error of fn, e.g. a client that went away, counts as neither a success nor a failure.
+//
+// It returns ErrOpen without calling fn while the breaker is open.
+// Otherwise, error returned by fn will be returned.
+func (cb *CircuitBreaker) Execute(fn func() error) error {
+	generation, ok := cb.allow()
+	if !ok {
+		return ErrOpen
+	}
+
+	err := fn()
+	cb.record(generation, err)
+
+	return err
+}
+
+// allow allow.
+//
+// It returns uint64 of the generation the call is allowed in, and true when a call may go through.
+func (cb *CircuitBreaker) allow() (uint64, bool) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.state {
+	case StateOpen:
+		if time.Now().Sub(cb.openedAt) < cb.openTimeout {
+			return 0, false
+		}
+
+		cb.setState(StateHalfOpen)
+		cb.probing = true
+
+		return cb.generation, true
+	case StateHalfOpen:
+		if cb.probing {
+			return 0, false
+		}
+
+		cb.probing = true
+
+		return cb.generation, true
+	default:
+		return cb.generation, true
+	}
+}
+
+// record record by given generation the call was allowed in, and err.
+//
+// A call allowed before the last state change says nothing about the current state, e.g. a slow call
+// allowed while closed doesn't close a half-open breaker, only its trial call does.
+func (cb *CircuitBreaker) record(generation uint64, err error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if generation != cb.generation {
+		return
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		// the trial is given to the next call
+		if cb.state == StateHalfOpen {
+			cb.probing = false
+		}
+
+		return
+	}
+
+	cb.probing = false
+
+	if err == nil {
+		cb.failures = 0
+		if cb.state != StateClosed {
+			cb.setState(StateClosed)
+		}
+
+		return
+	}
+
+	cb.failures++
+	if cb.state == StateHalfOpen || cb.failures >= cb.failureThreshold {
+		cb.openedAt = time.Now()
+		cb.setState(StateOpen)
+	}
+}
+
+// setState set state by given state. The caller must hold cb.mu.
+func (cb *CircuitBreaker) setState(state State) {
+	from := cb.state
+	cb.state = state
+	cb.generation++
+	if state != StateHalfOpen {
+		cb.failures = 0
+	}
+
+	logStateChange(cb.name, from, state)
+}
+
+// logStateChange log state change by given name, from, and to.
+func logStateChange(name string, from State, to State) {
+	if log.Logger == nil {
+		return
+	}
+
+	entry := log.Logger.WithFields(logrus.Fields{
+		"breaker": name,
+		"from":    from.String(),
+		"to":      to.String(),
+	})
+
+	if to == StateOpen {
+		entry.Warn("[CIRCUIT BREAKER] Circuit opened, falling back")
+		return
+	}
+
+	entry.Info("[CIRCUIT BREAKER] Circuit state changed")
+}
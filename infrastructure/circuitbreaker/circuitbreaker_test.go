@@ -0,0 +1,135 @@
+package circuitbreaker
+
+import (
+	// golang package
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var errRedis = errors.New("redis is down")
+
+// openBreaker open breaker by given openTimeout, a breaker of threshold 2 that failed twice.
+func openBreaker(t *testing.T, openTimeout time.Duration) *CircuitBreaker {
+	t.Helper()
+
+	cb := New("test", 2, openTimeout)
+	for i := 0; i < 2; i++ {
+		cb.Execute(func() error { return errRedis })
+	}
+
+	if cb.State() != StateOpen {
+		t.Fatalf("State() = %v after 2 failures, want open", cb.State())
+	}
+
+	return cb
+}
+
+func TestClosedUntilFailureThreshold(t *testing.T) {
+	cb := New("test", 3, time.Minute)
+
+	cb.Execute(func() error { return errRedis })
+	cb.Execute(func() error { return errRedis })
+	cb.Execute(func() error { return nil })
+	cb.Execute(func() error { return errRedis })
+	cb.Execute(func() error { return errRedis })
+	if cb.State() != StateClosed {
+		t.Fatalf("State() = %v, a success resets the count, want closed", cb.State())
+	}
+
+	cb.Execute(func() error { return errRedis })
+	if cb.State() != StateOpen {
+		t.Fatalf("State() = %v after 3 failures in a row, want open", cb.State())
+	}
+}
+
+func TestOpenRejectsCalls(t *testing.T) {
+	cb := openBreaker(t, time.Minute)
+
+	called := false
+	err := cb.Execute(func() error {
+		called = true
+		return nil
+	})
+	if !errors.Is(err, ErrOpen) || called {
+		t.Fatalf("Execute() = %v, called %v, want ErrOpen without calling", err, called)
+	}
+}
+
+func TestHalfOpenLetsOneTrialThrough(t *testing.T) {
+	cb := openBreaker(t, 0)
+
+	generation, ok := cb.allow()
+	if !ok || cb.State() != StateHalfOpen {
+		t.Fatalf("allow() = %v in %v, want the trial call in half-open", ok, cb.State())
+	}
+
+	if _, ok := cb.allow(); ok {
+		t.Fatal("allow() = true while the trial call is in flight, want false")
+	}
+
+	cb.record(generation, nil)
+	if cb.State() != StateClosed {
+		t.Fatalf("State() = %v after the trial succeeded, want closed", cb.State())
+	}
+}
+
+func TestHalfOpenTrialFailureReopens(t *testing.T) {
+	cb := openBreaker(t, 0)
+
+	generation, _ := cb.allow()
+	cb.record(generation, errRedis)
+	if cb.State() != StateOpen {
+		t.Fatalf("State() = %v after the trial failed, want open", cb.State())
+	}
+}
+
+func TestOutcomeOfEarlierStateIsIgnored(t *testing.T) {
+	cb := New("test", 2, 0)
+
+	// allowed while closed, it finishes once the breaker is half-open
+	slowGeneration, _ := cb.allow()
+	cb.Execute(func() error { return errRedis })
+	cb.Execute(func() error { return errRedis })
+
+	trialGeneration, ok := cb.allow()
+	if !ok || cb.State() != StateHalfOpen {
+		t.Fatalf("allow() = %v in %v, want the trial call in half-open", ok, cb.State())
+	}
+
+	cb.record(slowGeneration, nil)
+	if cb.State() != StateHalfOpen {
+		t.Fatalf("State() = %v after a call of the closed state succeeded, want half-open", cb.State())
+	}
+
+	if _, ok := cb.allow(); ok {
+		t.Fatal("allow() = true after a call of the closed state finished, want a single trial")
+	}
+
+	cb.record(trialGeneration, nil)
+	if cb.State() != StateClosed {
+		t.Fatalf("State() = %v after the trial succeeded, want closed", cb.State())
+	}
+}
+
+func TestContextErrorsAreNotFailures(t *testing.T) {
+	cb := New("test", 1, 0)
+
+	cb.Execute(func() error { return context.Canceled })
+	cb.Execute(func() error { return context.DeadlineExceeded })
+	if cb.State() != StateClosed {
+		t.Fatalf("State() = %v after context errors, want closed", cb.State())
+	}
+
+	cb.Execute(func() error { return errRedis })
+	generation, _ := cb.allow()
+	cb.record(generation, context.Canceled)
+	if cb.State() != StateHalfOpen {
+		t.Fatalf("State() = %v after the trial was canceled, want half-open", cb.State())
+	}
+
+	if _, ok := cb.allow(); !ok {
+		t.Fatal("allow() = false after the trial was canceled, want the next call to be the trial")
+	}
+}
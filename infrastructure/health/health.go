@@ -0,0 +1,47 @@
+package health
+
+import (
+	// golang package
+	"net/http"
+	"sync/atomic"
+
+	// external package
+	"github.com/gin-gonic/gin"
+)
+
+var ready atomic.Bool
+
+// SetReady set readiness of the service by given isReady.
+func SetReady(isReady bool) {
+	ready.Store(isReady)
+}
+
+// IsReady is ready.
+//
+// It returns true when the service is ready to receive traffic.
+// Otherwise, false will be returned.
+func IsReady() bool {
+	return ready.Load()
+}
+
+// Liveness liveness by given c pointer of gin.Context.
+func Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status": "alive",
+	})
+}
+
+// Readiness readiness by given c pointer of gin.Context.
+func Readiness(c *gin.Context) {
+	if !IsReady() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status": "not ready",
+		})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "ready",
+	})
+}
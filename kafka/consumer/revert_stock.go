@@ -34,13 +34,24 @@ func NewProductRollbackStockConsumer(brokers []string, topic string, productServ
 	}
 }
 
-// Start start.
+// Start start by given ctx.
+//
+// It reads messages until ctx is done.
 func (c *ProductRollbackStockConsumer) Start(ctx context.Context) {
 	log.Println("[Kafka] Listening to topic 'stock.rollback'")
 
+	defer c.Reader.Close()
+
+	// a message that was read is processed to the end, even during shutdown
+	processCtx := context.WithoutCancel(ctx)
 	for {
 		message, err := c.Reader.ReadMessage(ctx)
 		if err != nil {
+			// shutting down
+			if ctx.Err() != nil {
+				return
+			}
+
 			continue
 		}
 
@@ -53,7 +64,7 @@ func (c *ProductRollbackStockConsumer) Start(ctx context.Context) {
 		// looping based on product stock update event
 		for _, product := range event.Products {
 			// rollback stock
-			err = c.ProductService.AddProductStockByProductID(ctx, product.ProductID, product.Qty)
+			err = c.ProductService.AddProductStockByProductID(processCtx, product.ProductID, product.Qty)
 			if err != nil {
 				continue
 			}
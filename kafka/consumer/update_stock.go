@@ -34,13 +34,24 @@ func NewProductUpdateStockConsumer(brokers []string, topic string, productServic
 	}
 }
 
-// Start start.
+// Start start by given ctx.
+//
+// It reads messages until ctx is done.
 func (c *ProductUpdateStockConsumer) Start(ctx context.Context) {
 	log.Logger.Println("[KAFKA] Listening to topic stock.update")
 
+	defer c.Reader.Close()
+
+	// a message that was read is processed to the end, even during shutdown
+	processCtx := context.WithoutCancel(ctx)
 	for {
 		message, err := c.Reader.ReadMessage(ctx)
 		if err != nil {
+			// shutting down
+			if ctx.Err() != nil {
+				return
+			}
+
 			log.Logger.Println("[KAFKA] Error ReadMessage: ", err)
 			continue
 		}
@@ -55,7 +66,7 @@ func (c *ProductUpdateStockConsumer) Start(ctx context.Context) {
 
 		// update stock
 		for _, product := range event.Products {
-			err = c.productService.DeductProductStockByProductID(ctx, product.ProductID, product.Qty)
+			err = c.productService.DeductProductStockByProductID(processCtx, product.ProductID, product.Qty)
 			if err != nil {
 				log.Logger.Printf("[KAFKA] Error Update Product Stock Product ID #%d", product.ProductID)
 				continue
@@ -3,20 +3,34 @@ package main
 import (
 	// golang package
 	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
 	"productfc/cmd/product/handler"
 	"productfc/cmd/product/repository"
 	"productfc/cmd/product/resource"
 	"productfc/cmd/product/service"
 	"productfc/cmd/product/usecase"
 	"productfc/config"
+	"productfc/infrastructure/health"
 	"productfc/infrastructure/log"
 	"productfc/kafka/consumer"
+	"productfc/middleware"
 	"productfc/routes"
+	"sync"
+	"syscall"
+	"time"
 
 	// external package
 	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/health/grpc_health_v1"
 )
 
+// shutdownTimeout is how long in-flight requests are given to finish once a shutdown is signaled.
+const shutdownTimeout = 10 * time.Second
+
 // main main.
 func main() {
 	cfg := config.LoadConfig()
@@ -26,17 +40,66 @@ func main() {
 	log.SetupLogger()
 
 	productRepository := repository.NewProductRepository(db, redis)
-	productService := service.NewProductService(*productRepository)
+	searchBackend, err := repository.NewSearchBackend(cfg.Search, *productRepository)
+	if err != nil {
+		log.Logger.Fatalf("repository.NewSearchBackend() got error %v", err)
+	}
+
+	productService := service.NewProductService(*productRepository, searchBackend, cfg.Cache, cfg.Search, cfg.StockStream, cfg.Webhook)
 	productUsecase := usecase.NewProductUsecase(*productService)
-	productHandler := handler.NewProductHandler(*productUsecase)
+	productHandler := handler.NewProductHandler(*productUsecase, cfg.Search, cfg.StockStream)
+	graphQLHandler, err := handler.NewGraphQLHandler(*productUsecase, cfg.Search, cfg.GraphQL)
+	if err != nil {
+		log.Logger.Fatalf("handler.NewGraphQLHandler() got error %v", err)
+	}
+
+	// command mode: go run main.go warmup|rebuild-autocomplete|reindex
+	if len(os.Args) > 1 {
+		runCommand(os.Args[1], productService)
+		return
+	}
+
+	grpcServer, grpcHealthServer := routes.SetupGRPCServer(handler.NewProductGRPCServer(*productUsecase, cfg.Search))
+	setReady := func() {
+		health.SetReady(true)
+		grpcHealthServer.SetServingStatus("", grpc_health_v1.HealthCheckResponse_SERVING)
+	}
+
+	if cfg.Cache.WarmUp.Enabled {
+		go func() {
+			err := productService.WarmUpCache(context.Background())
+			if err != nil {
+				log.Logger.Errorf("productService.WarmUpCache() got error %v", err)
+			}
+
+			setReady()
+		}()
+	} else {
+		setReady()
+	}
 
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// stock streams stop first, their open responses would hold the HTTP shutdown otherwise,
+	// the sinks last, so what in-flight requests record is still written
+	var background sync.WaitGroup
+	streamCtx, stopStreams := context.WithCancel(context.Background())
+	sinkCtx, stopSinks := context.WithCancel(context.Background())
+	runInBackground(streamCtx, &background, productService.StockStream.Run)
+	runInBackground(sinkCtx, &background, productService.SearchAnalytics.Run)
+	runInBackground(sinkCtx, &background, productService.Webhooks.Run)
+	runInBackground(sinkCtx, &background, productService.SearchIndexer.Run)
+
+	// consumers stop reading on the signal, what they've read is still written before the sinks stop
+	var consumers sync.WaitGroup
 	kafkaProductUpdateStockConsumer := consumer.NewProductUpdateStockConsumer(
 		[]string{"localhost:9093"},
 		"stock.update",
 		*productService,
 	)
 
-	kafkaProductUpdateStockConsumer.Start(context.Background())
+	runInBackground(ctx, &consumers, kafkaProductUpdateStockConsumer.Start)
 
 	kafkaProductRollbackStockConsumer := consumer.NewProductRollbackStockConsumer(
 		[]string{"localhost:9093"},
@@ -44,12 +107,97 @@ func main() {
 		*productService,
 	)
 
-	kafkaProductRollbackStockConsumer.Start(context.Background())
+	runInBackground(ctx, &consumers, kafkaProductRollbackStockConsumer.Start)
+
+	grpcListener, err := net.Listen("tcp", ":"+cfg.GRPC.Port)
+	if err != nil {
+		log.Logger.Fatalf("net.Listen() got error %v", err)
+	}
+
+	go func() {
+		log.Logger.Printf("gRPC server running on port: %s", cfg.GRPC.Port)
+		err := grpcServer.Serve(grpcListener)
+		if err != nil {
+			log.Logger.Fatalf("grpcServer.Serve() got error %v", err)
+		}
+	}()
 
 	port := cfg.App.Port
 	router := gin.Default()
-	routes.SetupRoutes(router, *productHandler)
-	router.Run(":" + port)
+	routes.SetupRoutes(router, *productHandler, graphQLHandler, middleware.Idempotency(redis, cfg.Idempotency))
+
+	server := &http.Server{
+		Addr:    ":" + port,
+		Handler: router,
+	}
+
+	go func() {
+		log.Logger.Printf("Server running on port: %s", port)
+		err := server.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Logger.Fatalf("server.ListenAndServe() got error %v", err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Logger.Printf("Shutting down")
+
+	stopStreams()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	err = server.Shutdown(shutdownCtx)
+	if err != nil {
+		log.Logger.Errorf("server.Shutdown() got error %v", err)
+	}
+
+	// streaming calls could hold a graceful stop forever
+	grpcStopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(grpcStopped)
+	}()
+
+	select {
+	case <-grpcStopped:
+	case <-shutdownCtx.Done():
+		grpcServer.Stop()
+	}
+
+	consumers.Wait()
+	stopSinks()
+	background.Wait()
+}
+
+// runInBackground run in background by given ctx, wg pointer of sync.WaitGroup, and run, wg is done once run returns.
+func runInBackground(ctx context.Context, wg *sync.WaitGroup, run func(ctx context.Context)) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		run(ctx)
+	}()
+}
 
-	log.Logger.Printf("Server running on port: %s", port)
+// runCommand run command by given name, and productService pointer of service.ProductService.
+func runCommand(name string, productService *service.ProductService) {
+	switch name {
+	case "warmup":
+		err := productService.WarmUpCache(context.Background())
+		if err != nil {
+			log.Logger.Fatalf("productService.WarmUpCache() got error %v", err)
+		}
+	case "rebuild-autocomplete":
+		err := productService.RebuildAutocompleteIndex(context.Background())
+		if err != nil {
+			log.Logger.Fatalf("productService.RebuildAutocompleteIndex() got error %v", err)
+		}
+	case "reindex":
+		err := productService.ReindexSearch(context.Background())
+		if err != nil {
+			log.Logger.Fatalf("productService.ReindexSearch() got error %v", err)
+		}
+	default:
+		log.Logger.Fatalf("unknown command: %s", name)
+	}
 }
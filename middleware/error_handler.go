@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	// golang package
+	"errors"
+	"net/http"
+	"productfc/cmd/product/service"
+	"productfc/infrastructure/log"
+
+	// external package
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const problemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details body.
+type Problem struct {
+	Type      string               `json:"type"`
+	Title     string               `json:"title"`
+	Status    int                  `json:"status"`
+	Detail    string               `json:"detail,omitempty"`
+	Instance  string               `json:"instance"`
+	Code      string               `json:"code"`
+	RequestID string               `json:"request_id,omitempty"`
+	Errors    []service.FieldError `json:"errors,omitempty"`
+}
+
+// HTTPError is a protocol level error, for failures that aren't about the domain, e.g. an unsupported media type.
+type HTTPError struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+// Error error.
+//
+// It returns string.
+func (e *HTTPError) Error() string {
+	return e.Message
+}
+
+// NewHTTPError new http error by given status, code, and message.
+//
+// It returns pointer of HTTPError.
+func NewHTTPError(status int, code string, message string) *HTTPError {
+	return &HTTPError{
+		Status:  status,
+		Code:    code,
+		Message: message,
+	}
+}
+
+// ErrorHandler error handler.
+//
+// Handlers report failures with c.Error(err) and return, the last error is rendered as problem+json.
+//
+// It returns gin.HandlerFunc.
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		err := c.Errors.Last().Err
+		problem := newProblem(err)
+		problem.Instance = c.Request.URL.Path
+		if requestID, ok := c.Request.Context().Value("request_id").(string); ok {
+			problem.RequestID = requestID
+		}
+
+		if problem.Status >= http.StatusInternalServerError {
+			log.Logger.WithFields(logrus.Fields{
+				"request_id": problem.RequestID,
+				"path":       problem.Instance,
+			}).Errorf("unhandled error %v", err)
+		}
+
+		c.Header("Content-Type", problemContentType)
+		c.JSON(problem.Status, problem)
+	}
+}
+
+// newProblem new problem by given err.
+//
+// Errors that aren't domain or http errors are internal, their message is not exposed.
+//
+// It returns Problem.
+func newProblem(err error) Problem {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return Problem{
+			Type:   "/problems/" + httpErr.Code,
+			Title:  http.StatusText(httpErr.Status),
+			Status: httpErr.Status,
+			Detail: httpErr.Message,
+			Code:   httpErr.Code,
+		}
+	}
+
+	var domainErr *service.Error
+	if errors.As(err, &domainErr) {
+		status := statusOfKind(domainErr.Kind)
+		return Problem{
+			Type:   "/problems/" + domainErr.Code,
+			Title:  http.StatusText(status),
+			Status: status,
+			Detail: domainErr.Message,
+			Code:   domainErr.Code,
+			Errors: domainErr.Fields,
+		}
+	}
+
+	return Problem{
+		Type:   "/problems/internal_error",
+		Title:  http.StatusText(http.StatusInternalServerError),
+		Status: http.StatusInternalServerError,
+		Detail: "an unexpected error occurred",
+		Code:   "internal_error",
+	}
+}
+
+// statusOfKind status of kind by given kind.
+//
+// It returns int of http status.
+func statusOfKind(kind error) int {
+	switch kind {
+	case service.ErrNotFound:
+		return http.StatusNotFound
+	case service.ErrConflict, service.ErrInsufficientStock:
+		return http.StatusConflict
+	case service.ErrValidation:
+		return http.StatusBadRequest
+	case service.ErrUnavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
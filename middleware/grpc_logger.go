@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	// golang package
+	"context"
+	"productfc/infrastructure/log"
+	"time"
+
+	// external package
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// GRPCRequestLogger grpc request logger.
+//
+// It's the RequestLogger of the gRPC server, every call gets a request_id and the same timeout in its context.
+//
+// It returns grpc.UnaryServerInterceptor.
+func GRPCRequestLogger() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, next grpc.UnaryHandler) (interface{}, error) {
+		requestID := uuid.New().String()
+
+		timeoutCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		defer cancel()
+
+		ctx = context.WithValue(timeoutCtx, "request_id", requestID)
+
+		startTime := time.Now()
+		resp, err := next(ctx, req)
+		latency := time.Since(startTime)
+
+		requestLog := logrus.Fields{
+			"request_id": requestID,
+			"method":     info.FullMethod,
+			"code":       status.Code(err).String(),
+			"latency":    latency,
+		}
+
+		if err == nil {
+			log.Logger.WithFields(requestLog).Info("Request Success")
+		} else {
+			log.Logger.WithFields(requestLog).Error("Request Error")
+		}
+
+		return resp, err
+	}
+}
@@ -0,0 +1,230 @@
+package middleware
+
+import (
+	// golang package
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"productfc/config"
+	"productfc/infrastructure/log"
+
+	// external package
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	idempotencyKeyHeader      = "Idempotency-Key"
+	idempotencyReplayedHeader = "Idempotent-Replayed"
+	idempotencyKeyMaxLength   = 255
+
+	idempotencyStateProcessing = "processing"
+	idempotencyStateCompleted  = "completed"
+)
+
+// idempotencyReplayedHeaders are the response headers stored and replayed along with the body.
+var idempotencyReplayedHeaders = []string{"Content-Type", "Location"}
+
+// idempotencyRecord is what's stored in redis for an Idempotency-Key.
+type idempotencyRecord struct {
+	State       string            `json:"state"`
+	RequestHash string            `json:"requestHash"`
+	Status      int               `json:"status,omitempty"`
+	Header      map[string]string `json:"header,omitempty"`
+	Body        []byte            `json:"body,omitempty"`
+}
+
+// idempotencyWriter keeps a copy of the response body, so it can be stored.
+type idempotencyWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+// Write write by given data.
+//
+// It returns int of bytes written, and nil error when successful.
+// Otherwise, error will be returned.
+func (w *idempotencyWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString write string by given s.
+//
+// It returns int of bytes written, and nil error when successful.
+// Otherwise, error will be returned.
+func (w *idempotencyWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency idempotency by given redisClient pointer of redis.Client, and idempotencyConfig.
+//
+// A request with an Idempotency-Key header runs once within the configured window. Repeating it replays the
+// stored response, repeating the key with another request is rejected with 422, and repeating it while the
+// first one is still running is rejected with 409. Requests without the header are untouched.
+// Failed responses (5xx, or errors left to ErrorHandler) aren't stored, so the request can be retried.
+// When redis is unavailable the request runs as if it had no key.
+//
+// It returns gin.HandlerFunc.
+func Idempotency(redisClient *redis.Client, idempotencyConfig config.IdempotencyConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(idempotencyKeyHeader)
+		if !idempotencyConfig.Enabled || key == "" {
+			c.Next()
+			return
+		}
+
+		if len(key) > idempotencyKeyMaxLength {
+			c.Error(NewHTTPError(http.StatusBadRequest, "invalid_idempotency_key", "Idempotency-Key must be at most 255 characters"))
+			c.Abort()
+
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.Error(NewHTTPError(http.StatusBadRequest, "invalid_request_body", "request body can't be read"))
+			c.Abort()
+
+			return
+		}
+
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := c.Request.Context()
+		redisKey := "idempotency:" + key
+		requestHash := idempotencyRequestHash(c.Request.Method, c.Request.URL.Path, body)
+
+		// claim the key, only one request gets to run it
+		processing, _ := json.Marshal(idempotencyRecord{
+			State:       idempotencyStateProcessing,
+			RequestHash: requestHash,
+		})
+		claimed, err := redisClient.SetNX(ctx, redisKey, processing, idempotencyConfig.LockTimeout).Result()
+		if err != nil {
+			log.Logger.WithFields(logrus.Fields{
+				"key": key,
+			}).Errorf("redisClient.SetNX() got error %v", err)
+			c.Next()
+
+			return
+		}
+
+		if !claimed {
+			replayIdempotentResponse(c, redisClient, redisKey, requestHash)
+			return
+		}
+
+		writer := &idempotencyWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		c.Next()
+
+		// the request context may be over by now, the key must still be stored or released
+		ctx = context.WithoutCancel(ctx)
+
+		// errors are rendered by ErrorHandler once this returns, so they're never stored
+		failed := len(c.Errors) > 0 && !c.Writer.Written()
+		if failed || c.Writer.Status() >= http.StatusInternalServerError {
+			err = redisClient.Del(ctx, redisKey).Err()
+			if err != nil {
+				log.Logger.WithFields(logrus.Fields{
+					"key": key,
+				}).Errorf("redisClient.Del() got error %v", err)
+			}
+
+			return
+		}
+
+		record := idempotencyRecord{
+			State:       idempotencyStateCompleted,
+			RequestHash: requestHash,
+			Status:      c.Writer.Status(),
+			Header:      make(map[string]string, len(idempotencyReplayedHeaders)),
+			Body:        writer.body.Bytes(),
+		}
+		for _, name := range idempotencyReplayedHeaders {
+			if value := c.Writer.Header().Get(name); value != "" {
+				record.Header[name] = value
+			}
+		}
+
+		completed, _ := json.Marshal(record)
+		err = redisClient.Set(ctx, redisKey, completed, idempotencyConfig.TTL).Err()
+		if err != nil {
+			log.Logger.WithFields(logrus.Fields{
+				"key": key,
+			}).Errorf("redisClient.Set() got error %v", err)
+		}
+	}
+}
+
+// replayIdempotentResponse replay idempotent response by given c pointer of gin.Context, redisClient pointer of redis.Client, redisKey, and requestHash.
+//
+// The stored response is written as is when the request matches the one stored under the key.
+func replayIdempotentResponse(c *gin.Context, redisClient *redis.Client, redisKey string, requestHash string) {
+	defer c.Abort()
+
+	rawRecord, err := redisClient.Get(c.Request.Context(), redisKey).Bytes()
+	if errors.Is(err, redis.Nil) {
+		// released by a failed first request in between, let the client retry
+		c.Error(NewHTTPError(http.StatusConflict, "idempotency_key_in_use", "a request with this Idempotency-Key is in progress, retry later"))
+		return
+	}
+
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"key": redisKey,
+		}).Errorf("redisClient.Get() got error %v", err)
+		c.Error(err)
+
+		return
+	}
+
+	var record idempotencyRecord
+	err = json.Unmarshal(rawRecord, &record)
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"key": redisKey,
+		}).Errorf("json.Unmarshal() got error %v", err)
+		c.Error(err)
+
+		return
+	}
+
+	if record.RequestHash != requestHash {
+		c.Error(NewHTTPError(http.StatusUnprocessableEntity, "idempotency_key_reused", "Idempotency-Key was already used for a different request"))
+		return
+	}
+
+	if record.State != idempotencyStateCompleted {
+		c.Error(NewHTTPError(http.StatusConflict, "idempotency_key_in_use", "a request with this Idempotency-Key is in progress, retry later"))
+		return
+	}
+
+	for name, value := range record.Header {
+		c.Header(name, value)
+	}
+
+	c.Header(idempotencyReplayedHeader, "true")
+	c.Status(record.Status)
+	c.Writer.Write(record.Body)
+}
+
+// idempotencyRequestHash idempotency request hash by given method, path, and body.
+//
+// It returns string of hex encoded sha256.
+func idempotencyRequestHash(method string, path string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method + " " + path + "\n"))
+	hash.Write(body)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
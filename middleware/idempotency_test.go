@@ -0,0 +1,309 @@
+package middleware
+
+import (
+	// golang package
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"productfc/config"
+	"productfc/infrastructure/log"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	// external package
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	log.SetupLogger()
+	log.Logger.SetOutput(io.Discard)
+
+	os.Exit(m.Run())
+}
+
+// startFakeRedis start fake redis, a RESP2 server keeping strings in memory, enough for GET, SET and DEL.
+//
+// It returns string of the address it listens on.
+func startFakeRedis(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() got error %v", err)
+	}
+
+	t.Cleanup(func() {
+		listener.Close()
+	})
+
+	var mu sync.Mutex
+	data := map[string]string{}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+
+				reader := bufio.NewReader(conn)
+				for {
+					args, err := readRESPCommand(reader)
+					if err != nil {
+						return
+					}
+
+					mu.Lock()
+					reply := fakeRedisReply(data, args)
+					mu.Unlock()
+
+					conn.Write([]byte(reply))
+				}
+			}()
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+// readRESPCommand read resp command by given reader pointer of bufio.Reader.
+func readRESPCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, count)
+	for i := range args {
+		_, err = reader.ReadString('\n') // $length
+		if err != nil {
+			return nil, err
+		}
+
+		arg, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+
+		args[i] = strings.TrimSuffix(arg, "\r\n")
+	}
+
+	return args, nil
+}
+
+// fakeRedisReply fake redis reply by given data, and args of the command.
+func fakeRedisReply(data map[string]string, args []string) string {
+	switch strings.ToUpper(args[0]) {
+	case "GET":
+		value, ok := data[args[1]]
+		if !ok {
+			return "$-1\r\n"
+		}
+
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+	case "SET":
+		for _, option := range args[3:] {
+			if _, exists := data[args[1]]; strings.ToUpper(option) == "NX" && exists {
+				return "$-1\r\n"
+			}
+		}
+
+		data[args[1]] = args[2]
+		return "+OK\r\n"
+	case "DEL":
+		delete(data, args[1])
+		return ":1\r\n"
+	default:
+		return "-ERR unknown command '" + args[0] + "'\r\n"
+	}
+}
+
+// idempotentServer is a router with Idempotency in front of POST /products, which fails with status while it isn't 0.
+type idempotentServer struct {
+	router  *gin.Engine
+	calls   atomic.Int32
+	status  atomic.Int32
+	release chan struct{} // when set, requests wait for it
+}
+
+// newIdempotentServer new idempotent server by given addr of redis.
+func newIdempotentServer(t *testing.T, addr string) *idempotentServer {
+	t.Helper()
+
+	client := redis.NewClient(&redis.Options{Addr: addr, MaxRetries: -1, DialTimeout: 100 * time.Millisecond})
+	t.Cleanup(func() {
+		client.Close()
+	})
+
+	server := &idempotentServer{router: gin.New()}
+	server.router.Use(ErrorHandler())
+	server.router.POST("/products", Idempotency(client, config.IdempotencyConfig{
+		Enabled:     true,
+		TTL:         time.Minute,
+		LockTimeout: time.Minute,
+	}), func(c *gin.Context) {
+		n := server.calls.Add(1)
+		if server.release != nil {
+			<-server.release
+		}
+
+		if status := server.status.Load(); status != 0 {
+			c.Error(NewHTTPError(int(status), "failed", "failed"))
+			return
+		}
+
+		c.Header("Location", fmt.Sprintf("/products/%d", n))
+		c.JSON(http.StatusCreated, gin.H{"id": n})
+	})
+
+	return server
+}
+
+// post post by given key, and body.
+//
+// It returns pointer of httptest.ResponseRecorder.
+func (s *idempotentServer) post(key string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/products", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(idempotencyKeyHeader, key)
+	}
+
+	recorder := httptest.NewRecorder()
+	s.router.ServeHTTP(recorder, req)
+
+	return recorder
+}
+
+func TestIdempotencyReplays(t *testing.T) {
+	server := newIdempotentServer(t, startFakeRedis(t))
+
+	first := server.post("key-1", `{"name":"Phone"}`)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("first request = %d, want %d", first.Code, http.StatusCreated)
+	}
+
+	again := server.post("key-1", `{"name":"Phone"}`)
+	if again.Code != http.StatusCreated || again.Body.String() != first.Body.String() {
+		t.Fatalf("repeated request = %d %s, want %d %s", again.Code, again.Body, first.Code, first.Body)
+	}
+
+	if again.Header().Get("Location") != "/products/1" || again.Header().Get(idempotencyReplayedHeader) != "true" {
+		t.Fatalf("repeated request headers = %v, want the stored Location, replayed", again.Header())
+	}
+
+	if calls := server.calls.Load(); calls != 1 {
+		t.Fatalf("handler ran %d times, want once", calls)
+	}
+
+	// another key, or none, runs again
+	if code := server.post("key-2", `{"name":"Phone"}`).Code; code != http.StatusCreated {
+		t.Fatalf("request with another key = %d, want %d", code, http.StatusCreated)
+	}
+
+	if code := server.post("", `{"name":"Phone"}`).Code; code != http.StatusCreated {
+		t.Fatalf("request without a key = %d, want %d", code, http.StatusCreated)
+	}
+
+	if calls := server.calls.Load(); calls != 3 {
+		t.Fatalf("handler ran %d times, want 3", calls)
+	}
+}
+
+func TestIdempotencyRejectsAnotherRequest(t *testing.T) {
+	server := newIdempotentServer(t, startFakeRedis(t))
+
+	server.post("key-1", `{"name":"Phone"}`)
+
+	if code := server.post("key-1", `{"name":"Tablet"}`).Code; code != http.StatusUnprocessableEntity {
+		t.Fatalf("same key with another body = %d, want %d", code, http.StatusUnprocessableEntity)
+	}
+
+	if calls := server.calls.Load(); calls != 1 {
+		t.Fatalf("handler ran %d times, want once", calls)
+	}
+}
+
+func TestIdempotencyLocksWhileInProgress(t *testing.T) {
+	server := newIdempotentServer(t, startFakeRedis(t))
+	server.release = make(chan struct{})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- server.post("key-1", `{"name":"Phone"}`)
+	}()
+
+	for server.calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if code := server.post("key-1", `{"name":"Phone"}`).Code; code != http.StatusConflict {
+		t.Fatalf("repeated request while the first runs = %d, want %d", code, http.StatusConflict)
+	}
+
+	close(server.release)
+
+	if code := (<-done).Code; code != http.StatusCreated {
+		t.Fatalf("first request = %d, want %d", code, http.StatusCreated)
+	}
+
+	if calls := server.calls.Load(); calls != 1 {
+		t.Fatalf("handler ran %d times, want once", calls)
+	}
+}
+
+func TestIdempotencyReleasesFailures(t *testing.T) {
+	server := newIdempotentServer(t, startFakeRedis(t))
+	server.status.Store(http.StatusServiceUnavailable)
+
+	if code := server.post("key-1", `{"name":"Phone"}`).Code; code != http.StatusServiceUnavailable {
+		t.Fatalf("failed request = %d, want %d", code, http.StatusServiceUnavailable)
+	}
+
+	// the failure isn't stored, the retry runs
+	server.status.Store(0)
+	if code := server.post("key-1", `{"name":"Phone"}`).Code; code != http.StatusCreated {
+		t.Fatalf("retried request = %d, want %d", code, http.StatusCreated)
+	}
+
+	if calls := server.calls.Load(); calls != 2 {
+		t.Fatalf("handler ran %d times, want twice", calls)
+	}
+}
+
+func TestIdempotencyWithoutRedis(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() got error %v", err)
+	}
+
+	addr := listener.Addr().String()
+	listener.Close()
+
+	// the key can't be claimed, the requests run as if they had none
+	server := newIdempotentServer(t, addr)
+	server.post("key-1", `{"name":"Phone"}`)
+	server.post("key-1", `{"name":"Phone"}`)
+
+	if calls := server.calls.Load(); calls != 2 {
+		t.Fatalf("handler ran %d times, want twice", calls)
+	}
+}
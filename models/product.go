@@ -1,12 +1,18 @@
 package models
 
+import "time"
+
 type Product struct {
-	ID          int64   `json:"id"`
-	Name        string  `json:"name"`
-	Description string  `json:"description"`
-	Price       float64 `json:"price"`
-	Stock       int     `json:"stock"`
-	CategoryID  int     `json:"category_id"`
+	ID          int64     `json:"id"`
+	Name        string    `json:"name" validate:"required,max=255"`
+	Description string    `json:"description" validate:"max=5000"`
+	Price       float64   `json:"price" validate:"gte=0"`
+	Stock       int       `json:"stock" validate:"gte=0"`
+	CategoryID  int       `json:"category_id" validate:"required,gt=0"`
+	Popularity  int64     `json:"popularity" gorm:"->"` // maintained by stock changes only
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Version     int64     `json:"-" gorm:"->"` // bumped by every write to the row
 }
 
 type ProductManagementParameter struct {
@@ -14,9 +20,32 @@ type ProductManagementParameter struct {
 	Product
 }
 
+type BatchProductParameter struct {
+	IDs []int64 `json:"ids"`
+}
+
+type BatchProductResult struct {
+	ID      int64    `json:"id"`
+	Found   bool     `json:"found"`
+	Product *Product `json:"product,omitempty"`
+}
+
+// ProductStockEvent is the stock and price of a product after a change, pushed to GET /v1/product/stream.
+type ProductStockEvent struct {
+	ProductID int64     `json:"productId"`
+	Stock     int       `json:"stock"`
+	InStock   bool      `json:"inStock"`
+	Price     float64   `json:"price"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	Version   int64     `json:"version"` // row version of the product, orders the events of a product
+}
+
 type ProductCategory struct {
-	ID   int    `json:"id"`
-	Name string `json:"name"`
+	ID        int       `json:"id"`
+	Name      string    `json:"name" validate:"required,max=255"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Version   int64     `json:"-" gorm:"->"` // bumped by every write to the row
 }
 
 type ProductCategoryManagementParameter struct {
@@ -25,14 +54,78 @@ type ProductCategoryManagementParameter struct {
 }
 
 type SearchProductParameter struct {
+	Query    string  `json:"q"`
 	Name     string  `json:"name"`
+	Mode     string  `json:"mode"` // "fuzzy" matches name by trigram similarity
 	Category string  `json:"category"`
 	MinPrice float64 `json:"minPrice"`
 	MaxPrice float64 `json:"maxPrice"`
 	Page     int     `json:"page"`
 	PageSize int     `json:"pageSize"`
-	OrderBy  string  `json:"orderBy"`
-	Sort     string  `json:"sort"`
+	Cursor   string  `json:"cursor"` // when set, pages by cursor instead of page
+
+	CategoryIDs        []int64   `json:"categoryIds"`
+	ExcludeCategoryIDs []int64   `json:"excludeCategoryIds"`
+	IDs                []int64   `json:"ids"`
+	ExcludeIDs         []int64   `json:"excludeIds"`
+	InStock            bool      `json:"inStock"`
+	CreatedAfter       time.Time `json:"createdAfter"` // zero value means unbounded, and so on
+	CreatedBefore      time.Time `json:"createdBefore"`
+	UpdatedAfter       time.Time `json:"updatedAfter"`
+	UpdatedBefore      time.Time `json:"updatedBefore"`
+
+	SortFields []SortField `json:"sortFields"` // empty means best match (when searching) then name
+
+	SimilarityThreshold float64 `json:"-"` // set by the service from config
+	SearchID            string  `json:"-"` // correlates the search with its clicks in analytics
+}
+
+const (
+	AutocompleteTypeProduct         = "product"
+	AutocompleteTypeProductCategory = "product_category"
+)
+
+type AutocompleteSuggestion struct {
+	Type       string  `json:"type"`
+	ID         int64   `json:"id"`
+	Name       string  `json:"name"`
+	Popularity float64 `json:"popularity,omitempty"`
+}
+
+const SearchModeFuzzy = "fuzzy"
+
+const (
+	SortFieldName       = "name"
+	SortFieldPrice      = "price"
+	SortFieldStock      = "stock"
+	SortFieldCreatedAt  = "created_at"
+	SortFieldUpdatedAt  = "updated_at"
+	SortFieldRelevance  = "relevance" // ts_rank for q, trigram similarity for fuzzy name
+	SortFieldPopularity = "popularity"
+)
+
+// SearchProductSortFields are the fields a search can be sorted by.
+var SearchProductSortFields = []string{
+	SortFieldName,
+	SortFieldPrice,
+	SortFieldStock,
+	SortFieldCreatedAt,
+	SortFieldUpdatedAt,
+	SortFieldRelevance,
+	SortFieldPopularity,
+}
+
+type SortField struct {
+	Field string `json:"field"`
+	Desc  bool   `json:"desc"`
+}
+
+type SearchProductResult struct {
+	Products   []Product `json:"products"`
+	TotalCount int       `json:"totalCount"`
+	NextCursor string    `json:"nextCursor,omitempty"`
+	PrevCursor string    `json:"prevCursor,omitempty"`
+	SearchID   string    `json:"-"` // of this search, set after the cache so a cached result is never shared
 }
 
 type SearchProductResponse struct {
@@ -41,5 +134,51 @@ type SearchProductResponse struct {
 	PageSize    int       `json:"pageSize"`
 	TotalCount  int       `json:"totalCount"`
 	TotalPages  int       `json:"totalPages"`
-	NextPageUrl *string   `json:"nextPageUrl"`
+	NextPageUrl *string   `json:"nextPageUrl"` // deprecated, use Links.Next
+	NextCursor  *string   `json:"nextCursor"`
+	PrevCursor  *string   `json:"prevCursor"`
+	Suggestions []string  `json:"suggestions,omitempty"`
+
+	Links    SearchProductLinks `json:"links"`
+	SearchID string             `json:"searchId"` // send it back with POST /v1/search/click
+
+	Facets *SearchProductFacets `json:"facets,omitempty"`
+}
+
+// SearchProductLinks are absolute URLs of the current search, each keeping every filter of the request.
+type SearchProductLinks struct {
+	Self  string  `json:"self"`
+	First string  `json:"first"`
+	Last  string  `json:"last"`
+	Next  *string `json:"next"`
+	Prev  *string `json:"prev"`
+}
+
+const (
+	SearchFacetCategory = "category"
+	SearchFacetPrice    = "price"
+	SearchFacetStock    = "stock"
+)
+
+type SearchProductFacets struct {
+	Categories  []CategoryFacet   `json:"categories,omitempty"`
+	PriceRanges []PriceRangeFacet `json:"priceRanges,omitempty"`
+	Stock       *StockFacet       `json:"stock,omitempty"`
+}
+
+type CategoryFacet struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+type PriceRangeFacet struct {
+	Min   *float64 `json:"min"` // nil means unbounded
+	Max   *float64 `json:"max"` // exclusive, nil means unbounded
+	Count int64    `json:"count"`
+}
+
+type StockFacet struct {
+	InStock    int64 `json:"inStock"`
+	OutOfStock int64 `json:"outOfStock"`
 }
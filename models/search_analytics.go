@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+type SearchEvent struct {
+	ID          int64     `json:"id"`
+	SearchID    string    `json:"search_id"`
+	Term        string    `json:"term"`
+	Filters     string    `json:"filters"` // json of the filters that were set
+	ResultCount int       `json:"result_count"`
+	LatencyMs   float64   `json:"latency_ms"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type SearchClick struct {
+	ID        int64     `json:"id"`
+	SearchID  string    `json:"search_id"`
+	ProductID int64     `json:"product_id"`
+	Position  int       `json:"position"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type SearchClickParameter struct {
+	SearchID  string `json:"searchId"`
+	ProductID int64  `json:"productId"`
+	Position  int    `json:"position"` // 1-based rank of the product on the result page
+}
+
+type SearchReportParameter struct {
+	From  time.Time
+	To    time.Time
+	Limit int
+}
+
+type SearchQueryStat struct {
+	Term           string  `json:"term"`
+	Searches       int64   `json:"searches"`
+	AvgResultCount float64 `json:"avgResultCount"`
+	AvgLatencyMs   float64 `json:"avgLatencyMs"`
+}
+
+type SearchClickThroughStat struct {
+	Term            string  `json:"term"`
+	Searches        int64   `json:"searches"`
+	ClickedSearches int64   `json:"clickedSearches"`
+	Clicks          int64   `json:"clicks"`
+	CTR             float64 `json:"ctr" gorm:"column:ctr"` // clickedSearches / searches
+}
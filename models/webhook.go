@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+const (
+	WebhookEventProductCreated  = "product.created"
+	WebhookEventProductUpdated  = "product.updated"
+	WebhookEventProductDeleted  = "product.deleted"
+	WebhookEventCategoryCreated = "category.created"
+	WebhookEventCategoryUpdated = "category.updated"
+	WebhookEventCategoryDeleted = "category.deleted"
+	WebhookEventStockChanged    = "stock.changed"
+)
+
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+type WebhookSubscription struct {
+	ID                  int64      `json:"id"`
+	URL                 string     `json:"url" validate:"required,http_url,max=2048"`
+	EventTypes          []string   `json:"event_types" gorm:"serializer:json" validate:"required,min=1,dive,oneof=product.created product.updated product.deleted category.created category.updated category.deleted stock.changed"`
+	Secret              string     `json:"secret,omitempty" validate:"omitempty,min=16,max=255"` // generated when empty, only returned when created
+	Active              bool       `json:"active"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	DisabledAt          *time.Time `json:"disabled_at"` // set when it was disabled for failing
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+type WebhookDelivery struct {
+	ID             int64      `json:"id"`
+	SubscriptionID int64      `json:"subscription_id"`
+	EventID        string     `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	Payload        string     `json:"-"` // the WebhookEvent sent, as json
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	LastStatusCode int        `json:"last_status_code"`
+	LastError      string     `json:"last_error"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// WebhookEvent is the body POSTed to a webhook.
+type WebhookEvent struct {
+	ID         string      `json:"id"` // the same on every attempt, receivers dedupe by it
+	Type       string      `json:"type"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Data       interface{} `json:"data"`
+}
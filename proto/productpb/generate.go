@@ -0,0 +1,4 @@
+// Package productpb is the generated code of proto/product.proto.
+package productpb
+
+//go:generate protoc -I .. --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative ../product.proto
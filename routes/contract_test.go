@@ -0,0 +1,304 @@
+package routes
+
+import (
+	// golang package
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"productfc/cmd/product/handler"
+	"productfc/cmd/product/repository"
+	"productfc/cmd/product/service"
+	"productfc/cmd/product/usecase"
+	"productfc/config"
+	"productfc/infrastructure/log"
+	"productfc/middleware"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	// external package
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// contractCase is a request to a real handler, whose response must match the spec.
+type contractCase struct {
+	name        string
+	method      string
+	path        string
+	contentType string
+	header      map[string]string
+	body        string
+	wantStatus  int
+}
+
+var contractCases = []contractCase{
+	{name: "liveness", method: http.MethodGet, path: "/health/live", wantStatus: http.StatusOK},
+	{name: "readiness before warm-up", method: http.MethodGet, path: "/health/ready", wantStatus: http.StatusServiceUnavailable},
+	{name: "spec", method: http.MethodGet, path: "/openapi.json", wantStatus: http.StatusOK},
+	{name: "swagger ui", method: http.MethodGet, path: "/docs", wantStatus: http.StatusOK},
+
+	{name: "v1 add product", method: http.MethodPost, path: "/v1/product", body: `{"action":"add","name":"Phone 2","price":10,"stock":1,"category_id":1}`, wantStatus: http.StatusOK},
+	{name: "v1 add invalid product", method: http.MethodPost, path: "/v1/product", body: `{"action":"add","price":-1,"stock":-1}`, wantStatus: http.StatusBadRequest},
+	{name: "v1 edit product", method: http.MethodPost, path: "/v1/product", body: `{"action":"edit","id":1,"name":"Phone","price":10,"stock":1,"category_id":1}`, wantStatus: http.StatusOK},
+	{name: "v1 edit missing product", method: http.MethodPost, path: "/v1/product", body: `{"action":"edit","id":404,"name":"Phone","price":10,"stock":1,"category_id":1}`, wantStatus: http.StatusNotFound},
+	{name: "v1 delete product", method: http.MethodPost, path: "/v1/product", body: `{"action":"delete","id":1}`, wantStatus: http.StatusOK},
+	{name: "v1 unknown product action", method: http.MethodPost, path: "/v1/product", body: `{"action":"archive","id":1}`, wantStatus: http.StatusBadRequest},
+	{name: "v1 add product category", method: http.MethodPost, path: "/v1/product_category", body: `{"action":"add","name":"Gadgets 2"}`, wantStatus: http.StatusOK},
+	{name: "v1 edit product category", method: http.MethodPost, path: "/v1/product_category", body: `{"action":"edit","id":1,"name":"Gadgets"}`, wantStatus: http.StatusOK},
+	{name: "v1 delete product category", method: http.MethodPost, path: "/v1/product_category", body: `{"action":"delete","id":1}`, wantStatus: http.StatusOK},
+	{name: "v1 product category without name", method: http.MethodPost, path: "/v1/product_category", body: `{"action":"add"}`, wantStatus: http.StatusBadRequest},
+	{name: "batch products", method: http.MethodPost, path: "/v1/product/batch", body: `{"ids":[1,404]}`, wantStatus: http.StatusOK},
+	{name: "batch without ids", method: http.MethodPost, path: "/v1/product/batch", body: `{"ids":[]}`, wantStatus: http.StatusBadRequest},
+	{name: "v1 get product", method: http.MethodGet, path: "/v1/product/1", wantStatus: http.StatusOK},
+	{name: "v1 get missing product", method: http.MethodGet, path: "/v1/product/404", wantStatus: http.StatusNotFound},
+	{name: "v1 get product by invalid id", method: http.MethodGet, path: "/v1/product/abc", wantStatus: http.StatusBadRequest},
+	{name: "v1 get product category", method: http.MethodGet, path: "/v1/product_category/1", wantStatus: http.StatusOK},
+	{name: "v1 get missing product category", method: http.MethodGet, path: "/v1/product_category/404", wantStatus: http.StatusNotFound},
+
+	{name: "search", method: http.MethodGet, path: "/v1/product/search?q=phone&facets=category,price,stock&category_id=1&in_stock=true", wantStatus: http.StatusOK},
+	{name: "search sorted by price", method: http.MethodGet, path: "/v1/product/search?name=pho&sort=price:desc&pageSize=1", wantStatus: http.StatusOK},
+	{name: "search with unknown sort", method: http.MethodGet, path: "/v1/product/search?sort=color:asc", wantStatus: http.StatusBadRequest},
+	{name: "autocomplete", method: http.MethodGet, path: "/v1/product/suggest?prefix=pho&weighted=true", wantStatus: http.StatusOK},
+	{name: "autocomplete without prefix", method: http.MethodGet, path: "/v1/product/suggest", wantStatus: http.StatusBadRequest},
+
+	{name: "create product", method: http.MethodPost, path: "/v2/products", body: `{"name":"Phone 2","price":10,"stock":1,"category_id":1}`, wantStatus: http.StatusCreated},
+	{name: "create product with id", method: http.MethodPost, path: "/v2/products", body: `{"id":7,"name":"Phone 2","price":10,"stock":1,"category_id":1}`, wantStatus: http.StatusBadRequest},
+	{name: "create product in missing category", method: http.MethodPost, path: "/v2/products", body: `{"name":"Phone 2","category_id":404}`, wantStatus: http.StatusBadRequest},
+	{name: "get product", method: http.MethodGet, path: "/v2/products/1", wantStatus: http.StatusOK},
+	{name: "get missing product", method: http.MethodGet, path: "/v2/products/404", wantStatus: http.StatusNotFound},
+	{name: "replace product", method: http.MethodPut, path: "/v2/products/1", body: `{"name":"Phone","price":10,"stock":1,"category_id":1}`, wantStatus: http.StatusOK},
+	{name: "patch product", method: http.MethodPatch, path: "/v2/products/1", contentType: "application/merge-patch+json", body: `{"price":12.5,"description":null}`, wantStatus: http.StatusOK},
+	{name: "patch immutable field", method: http.MethodPatch, path: "/v2/products/1", contentType: "application/merge-patch+json", body: `{"id":2}`, wantStatus: http.StatusBadRequest},
+	{name: "patch as text", method: http.MethodPatch, path: "/v2/products/1", contentType: "text/plain", body: `price=1`, wantStatus: http.StatusUnsupportedMediaType},
+	{name: "delete product", method: http.MethodDelete, path: "/v2/products/1", wantStatus: http.StatusNoContent},
+	{name: "delete missing product", method: http.MethodDelete, path: "/v2/products/404", wantStatus: http.StatusNotFound},
+
+	{name: "create product category", method: http.MethodPost, path: "/v2/categories", body: `{"name":"Gadgets 2"}`, wantStatus: http.StatusCreated},
+	{name: "get product category", method: http.MethodGet, path: "/v2/categories/1", wantStatus: http.StatusOK},
+	{name: "get product category by invalid id", method: http.MethodGet, path: "/v2/categories/0", wantStatus: http.StatusBadRequest},
+	{name: "replace product category", method: http.MethodPut, path: "/v2/categories/1", body: `{"name":"Gadgets"}`, wantStatus: http.StatusOK},
+	{name: "patch product category", method: http.MethodPatch, path: "/v2/categories/1", contentType: "application/merge-patch+json", body: `{"name":"Gadgets"}`, wantStatus: http.StatusOK},
+	{name: "patch product category as json", method: http.MethodPatch, path: "/v2/categories/1", body: `{}`, wantStatus: http.StatusOK},
+	{name: "patch product category immutable field", method: http.MethodPatch, path: "/v2/categories/1", contentType: "application/merge-patch+json", body: `{"created_at":"2024-01-01T00:00:00Z"}`, wantStatus: http.StatusBadRequest},
+	{name: "patch product category name to null", method: http.MethodPatch, path: "/v2/categories/1", contentType: "application/merge-patch+json", body: `{"name":null}`, wantStatus: http.StatusBadRequest},
+	{name: "patch product category as text", method: http.MethodPatch, path: "/v2/categories/1", contentType: "text/plain", body: `name=x`, wantStatus: http.StatusUnsupportedMediaType},
+	{name: "delete product category", method: http.MethodDelete, path: "/v2/categories/1", wantStatus: http.StatusNoContent},
+	{name: "delete missing product category", method: http.MethodDelete, path: "/v2/categories/404", wantStatus: http.StatusNotFound},
+
+	{name: "idempotent create", method: http.MethodPost, path: "/v2/products", header: map[string]string{"Idempotency-Key": "contract"}, body: `{"name":"Phone 3","category_id":1}`, wantStatus: http.StatusCreated},
+	{name: "idempotent create replayed", method: http.MethodPost, path: "/v2/products", header: map[string]string{"Idempotency-Key": "contract"}, body: `{"name":"Phone 3","category_id":1}`, wantStatus: http.StatusCreated},
+	{name: "idempotency key reused", method: http.MethodPost, path: "/v2/products", header: map[string]string{"Idempotency-Key": "contract"}, body: `{"name":"Phone 4","category_id":1}`, wantStatus: http.StatusUnprocessableEntity},
+
+	{name: "create webhook", method: http.MethodPost, path: "/v2/webhooks", body: `{"url":"https://partner.example.com/hooks","event_types":["product.created","stock.changed"]}`, wantStatus: http.StatusCreated},
+	{name: "create webhook with unknown event", method: http.MethodPost, path: "/v2/webhooks", body: `{"url":"ftp://partner.example.com","event_types":["product.archived"]}`, wantStatus: http.StatusBadRequest},
+	{name: "create webhook with http url", method: http.MethodPost, path: "/v2/webhooks", body: `{"url":"http://partner.example.com/hooks","event_types":["product.created"]}`, wantStatus: http.StatusBadRequest},
+	{name: "create webhook with private address", method: http.MethodPost, path: "/v2/webhooks", body: `{"url":"https://10.0.0.1/hooks","event_types":["product.created"]}`, wantStatus: http.StatusBadRequest},
+	{name: "list webhooks", method: http.MethodGet, path: "/v2/webhooks", wantStatus: http.StatusOK},
+	{name: "get webhook", method: http.MethodGet, path: "/v2/webhooks/1", wantStatus: http.StatusOK},
+	{name: "get missing webhook", method: http.MethodGet, path: "/v2/webhooks/404", wantStatus: http.StatusNotFound},
+	{name: "replace webhook", method: http.MethodPut, path: "/v2/webhooks/1", body: `{"url":"https://partner.example.com/hooks","event_types":["stock.changed"],"active":true}`, wantStatus: http.StatusOK},
+	{name: "delete webhook", method: http.MethodDelete, path: "/v2/webhooks/1", wantStatus: http.StatusNoContent},
+	{name: "delete missing webhook", method: http.MethodDelete, path: "/v2/webhooks/404", wantStatus: http.StatusNotFound},
+	{name: "webhook deliveries", method: http.MethodGet, path: "/v2/webhooks/1/deliveries?limit=10", wantStatus: http.StatusOK},
+	{name: "webhook deliveries with invalid limit", method: http.MethodGet, path: "/v2/webhooks/1/deliveries?limit=0", wantStatus: http.StatusBadRequest},
+
+	{name: "search click", method: http.MethodPost, path: "/v1/search/click", body: `{"searchId":"0b6f3c1e-4c5f-4c55-9d83-5d1b0d0b5a11","productId":1,"position":1}`, wantStatus: http.StatusNoContent},
+	{name: "search click without search", method: http.MethodPost, path: "/v1/search/click", body: `{"productId":1}`, wantStatus: http.StatusBadRequest},
+	{name: "top search queries", method: http.MethodGet, path: "/v1/search/analytics/top-queries?from=2024-01-01&to=2024-02-01", wantStatus: http.StatusOK},
+	{name: "top search queries with reversed range", method: http.MethodGet, path: "/v1/search/analytics/top-queries?from=2024-02-01&to=2024-01-01", wantStatus: http.StatusBadRequest},
+	{name: "zero result search queries", method: http.MethodGet, path: "/v1/search/analytics/zero-results?limit=5", wantStatus: http.StatusOK},
+	{name: "search click through rates", method: http.MethodGet, path: "/v1/search/analytics/ctr", wantStatus: http.StatusOK},
+
+	{name: "stream product stock", method: http.MethodGet, path: "/v1/product/stream?ids=1,2", wantStatus: http.StatusOK},
+	{name: "stream product stock without ids", method: http.MethodGet, path: "/v1/product/stream", wantStatus: http.StatusBadRequest},
+	{name: "stream product stock with too many ids", method: http.MethodGet, path: "/v1/product/stream?ids=1,2,3,4,5,6", wantStatus: http.StatusBadRequest},
+
+	{name: "graphql product with category", method: http.MethodPost, path: "/graphql", body: `{"query":"{ product(id: \"1\") { id name inStock category { id name } } }"}`, wantStatus: http.StatusOK},
+	{name: "graphql category products", method: http.MethodPost, path: "/graphql", body: `{"query":"query($page: PageInput) { category(id: \"1\") { name products(page: $page) { totalCount items { id } } } }","variables":{"page":{"pageSize":5}}}`, wantStatus: http.StatusOK},
+	{name: "graphql field error", method: http.MethodPost, path: "/graphql", body: `{"query":"{ products(page: {pageSize: 0}) { totalCount } }"}`, wantStatus: http.StatusOK},
+	{name: "graphql query too complex", method: http.MethodPost, path: "/graphql", body: `{"query":"{ products(page: {pageSize: 100}) { items { category { products(page: {pageSize: 100}) { totalCount } } } } }"}`, wantStatus: http.StatusBadRequest},
+	{name: "graphql invalid query", method: http.MethodPost, path: "/graphql", body: `{"query":"{ product(id: \"1\") { nope } }"}`, wantStatus: http.StatusBadRequest},
+	{name: "graphql without query", method: http.MethodPost, path: "/graphql", body: `{}`, wantStatus: http.StatusBadRequest},
+}
+
+// newContractRouter new contract router, every route of SetupRoutes on real handlers, backed by a fake database and redis.
+func newContractRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+
+	gin.SetMode(gin.TestMode)
+	log.SetupLogger()
+	log.Logger.SetOutput(io.Discard)
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: openFakeDB()}), &gorm.Config{
+		Logger:         logger.Discard,
+		TranslateError: true,
+	})
+	if err != nil {
+		t.Fatalf("gorm.Open() got error %v", err)
+	}
+
+	redisClient := redis.NewClient(&redis.Options{
+		Addr:     startFakeRedis(t),
+		Protocol: 2,
+	})
+
+	cacheConfig := config.CacheConfig{
+		StaleReadTimeout:  100 * time.Millisecond,
+		RevalidateTimeout: time.Second,
+		RedisBreaker: config.CircuitBreakerConfig{
+			FailureThreshold: 5,
+			OpenTimeout:      time.Second,
+		},
+	}
+	stockStreamConfig := config.StockStreamConfig{
+		MaxConnections:    10,
+		MaxProductIDs:     5,
+		BufferSize:        1,
+		HeartbeatInterval: time.Second,
+		MaxDuration:       10 * time.Millisecond,
+	}
+	searchConfig := config.SearchConfig{
+		SimilarityThreshold: 0.3,
+		SuggestionLimit:     5,
+		AutocompleteLimit:   10,
+		PriceBuckets:        []float64{100, 1000},
+		DefaultPageSize:     20,
+		MaxPageSize:         100,
+		MaxBatchProductIDs:  100,
+		Backend:             "sql",
+	}
+
+	productRepository := repository.NewProductRepository(db, redisClient)
+	searchBackend, err := repository.NewSearchBackend(searchConfig, *productRepository)
+	if err != nil {
+		t.Fatalf("repository.NewSearchBackend() got error %v", err)
+	}
+
+	productService := service.NewProductService(*productRepository, searchBackend, cacheConfig, searchConfig, stockStreamConfig, config.WebhookConfig{})
+	productUsecase := usecase.NewProductUsecase(*productService)
+	productHandler := handler.NewProductHandler(*productUsecase, searchConfig, stockStreamConfig)
+	graphQLHandler, err := handler.NewGraphQLHandler(*productUsecase, searchConfig, config.GraphQLConfig{
+		MaxDepth:      8,
+		MaxComplexity: 2000,
+	})
+	if err != nil {
+		t.Fatalf("handler.NewGraphQLHandler() got error %v", err)
+	}
+
+	router := gin.New()
+	SetupRoutes(router, *productHandler, graphQLHandler, middleware.Idempotency(redisClient, config.IdempotencyConfig{
+		Enabled:     true,
+		TTL:         time.Hour,
+		LockTimeout: time.Minute,
+	}))
+
+	return router
+}
+
+// routeParam matches gin path parameters, e.g. :id.
+var routeParam = regexp.MustCompile(`:(\w+)`)
+
+func TestSpecDocumentsEveryRoute(t *testing.T) {
+	router := newContractRouter(t)
+	operations := loadOpenAPISpec(t).operations(t)
+
+	routes := make(map[string]bool)
+	for _, route := range router.Routes() {
+		key := route.Method + " " + routeParam.ReplaceAllString(route.Path, "{$1}")
+		routes[key] = true
+
+		if _, ok := operations[key]; !ok {
+			t.Errorf("route %s isn't documented in api/openapi.json", key)
+		}
+	}
+
+	for key := range operations {
+		if !routes[key] {
+			t.Errorf("operation %s is documented, but not routed", key)
+		}
+	}
+}
+
+func TestResponsesMatchSpec(t *testing.T) {
+	router := newContractRouter(t)
+	spec := loadOpenAPISpec(t)
+
+	covered := make(map[string]bool)
+	for _, tc := range contractCases {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			if tc.body != "" {
+				contentType := tc.contentType
+				if contentType == "" {
+					contentType = "application/json"
+				}
+
+				request.Header.Set("Content-Type", contentType)
+			}
+
+			for name, value := range tc.header {
+				request.Header.Set(name, value)
+			}
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+
+			if recorder.Code != tc.wantStatus {
+				t.Fatalf("got status %d, want %d, body %s", recorder.Code, tc.wantStatus, recorder.Body)
+			}
+
+			key, op, ok := spec.findOperation(t, tc.method, request.URL.Path)
+			if !ok {
+				t.Fatalf("%s %s isn't documented", tc.method, request.URL.Path)
+			}
+
+			covered[key] = true
+
+			responseSchema, err := spec.responseSchema(op, recorder.Code, recorder.Header().Get("Content-Type"))
+			if err != nil {
+				t.Fatalf("%s: %v", key, err)
+			}
+
+			if responseSchema == nil {
+				if recorder.Body.Len() > 0 {
+					t.Fatalf("%s: status %d is documented without a body, got %s", key, recorder.Code, recorder.Body)
+				}
+
+				return
+			}
+
+			if !strings.Contains(recorder.Header().Get("Content-Type"), "json") {
+				return
+			}
+
+			var body interface{}
+			err = json.Unmarshal(recorder.Body.Bytes(), &body)
+			if err != nil {
+				t.Fatalf("%s: body isn't JSON: %v", key, err)
+			}
+
+			for _, violation := range spec.validate(responseSchema, body, "body") {
+				t.Errorf("%s %d: %s", key, recorder.Code, violation)
+			}
+		})
+	}
+
+	var uncovered []string
+	for key := range spec.operations(t) {
+		if !covered[key] {
+			uncovered = append(uncovered, key)
+		}
+	}
+
+	sort.Strings(uncovered)
+	for _, key := range uncovered {
+		t.Errorf("operation %s has no contract case", key)
+	}
+}
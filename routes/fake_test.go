@@ -0,0 +1,320 @@
+package routes
+
+import (
+	// golang package
+	"bufio"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// missingID is an id the fake database has no row for.
+const missingID = 404
+
+var fakeTime = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// fakeRow has a value for every column the repository selects, the columns a query doesn't ask for are ignored by gorm.
+var fakeRow = []struct {
+	column string
+	value  driver.Value
+}{
+	{"id", int64(1)},
+	{"name", "Phone"},
+	{"description", "A phone"},
+	{"price", 100.5},
+	{"stock", int64(5)},
+	{"category_id", int64(1)},
+	{"popularity", int64(3)},
+	{"created_at", fakeTime},
+	{"updated_at", fakeTime},
+	{"version", int64(1)},
+	{"relevance", 0.5},
+	{"category", "Gadgets"},
+	{"type", "product"},
+	{"count", int64(1)},
+	{"bucket", int64(1)},
+	{"in_stock", int64(1)},
+	{"out_of_stock", int64(0)},
+	{"term", "phone"},
+	{"searches", int64(2)},
+	{"avg_result_count", 1.5},
+	{"avg_latency_ms", 12.5},
+	{"clicked_searches", int64(1)},
+	{"clicks", int64(1)},
+	{"ctr", 0.5},
+	{"url", "https://partner.example.com/hooks"},
+	{"event_types", `["product.created","stock.changed"]`},
+	{"secret", "0123456789abcdef0123456789abcdef"},
+	{"active", true},
+	{"consecutive_failures", int64(0)},
+	{"disabled_at", nil},
+	{"subscription_id", int64(1)},
+	{"event_id", "0b6f3c1e-4c5f-4c55-9d83-5d1b0d0b5a11"},
+	{"event_type", "product.created"},
+	{"payload", `{}`},
+	{"status", "succeeded"},
+	{"attempts", int64(1)},
+	{"next_attempt_at", fakeTime},
+	{"last_status_code", int64(200)},
+	{"last_error", ""},
+	{"delivered_at", fakeTime},
+}
+
+// singleColumnQuery matches the queries scanned into a single value, e.g. Count and Pluck.
+var singleColumnQuery = regexp.MustCompile(`(?i)^SELECT (count\(\*\)|"?name"?) FROM`)
+
+// openFakeDB open fake db.
+//
+// Every query answers one fakeRow, or nothing when an argument is missingID, every statement affects one row.
+func openFakeDB() *sql.DB {
+	return sql.OpenDB(fakeConnector{})
+}
+
+type fakeConnector struct{}
+
+func (fakeConnector) Connect(context.Context) (driver.Conn, error) {
+	return fakeConn{}, nil
+}
+
+func (fakeConnector) Driver() driver.Driver {
+	return fakeDriver{}
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) {
+	return fakeConn{}, nil
+}
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("fake db doesn't prepare %q", query)
+}
+
+func (fakeConn) Close() error {
+	return nil
+}
+
+func (fakeConn) Begin() (driver.Tx, error) {
+	return fakeTx{}, nil
+}
+
+func (fakeConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	return fakeTx{}, nil
+}
+
+func (fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	rows := &fakeRows{}
+	for _, column := range fakeRow {
+		rows.columns = append(rows.columns, column.column)
+		rows.values = append(rows.values, column.value)
+	}
+
+	if match := singleColumnQuery.FindStringSubmatch(query); match != nil {
+		rows.columns = []string{strings.Trim(match[1], `"`)}
+		rows.values = []driver.Value{int64(1)}
+		if strings.Contains(query, "LOWER(name)") {
+			// no product name is taken
+			rows.values = []driver.Value{int64(0)}
+		}
+
+		if rows.columns[0] == "name" {
+			rows.values = []driver.Value{"Phone"}
+		}
+	}
+
+	rows.remaining = 1
+	if hasMissingID(args) {
+		rows.remaining = 0
+	}
+
+	return rows, nil
+}
+
+func (fakeConn) ExecContext(_ context.Context, _ string, args []driver.NamedValue) (driver.Result, error) {
+	if hasMissingID(args) {
+		return driver.RowsAffected(0), nil
+	}
+
+	return driver.RowsAffected(1), nil
+}
+
+// hasMissingID has missing id by given args.
+func hasMissingID(args []driver.NamedValue) bool {
+	for _, arg := range args {
+		if id, ok := arg.Value.(int64); ok && id == missingID {
+			return true
+		}
+	}
+
+	return false
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error {
+	return nil
+}
+
+func (fakeTx) Rollback() error {
+	return nil
+}
+
+type fakeRows struct {
+	columns   []string
+	values    []driver.Value
+	remaining int
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.remaining == 0 {
+		return io.EOF
+	}
+
+	r.remaining--
+	copy(dest, r.values)
+
+	return nil
+}
+
+// startFakeRedis start fake redis, a RESP2 server keeping strings in memory, enough for the commands the service sends.
+//
+// It returns string of the address it listens on.
+func startFakeRedis(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() got error %v", err)
+	}
+
+	t.Cleanup(func() {
+		listener.Close()
+	})
+
+	var mu sync.Mutex
+	data := map[string]string{}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+
+				reader := bufio.NewReader(conn)
+				for {
+					args, err := readRESPCommand(reader)
+					if err != nil {
+						return
+					}
+
+					mu.Lock()
+					reply := fakeRedisReply(data, args)
+					mu.Unlock()
+
+					conn.Write([]byte(reply))
+				}
+			}()
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+// readRESPCommand read resp command by given reader pointer of bufio.Reader.
+func readRESPCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, count)
+	for i := range args {
+		_, err = reader.ReadString('\n') // $length
+		if err != nil {
+			return nil, err
+		}
+
+		arg, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+
+		args[i] = strings.TrimSuffix(arg, "\r\n")
+	}
+
+	return args, nil
+}
+
+// fakeRedisReply fake redis reply by given data, and args of the command.
+func fakeRedisReply(data map[string]string, args []string) string {
+	bulk := func(value string) string {
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+	}
+
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		return "+PONG\r\n"
+	case "GET":
+		value, ok := data[args[1]]
+		if !ok {
+			return "$-1\r\n"
+		}
+
+		return bulk(value)
+	case "SET":
+		for _, option := range args[3:] {
+			if _, exists := data[args[1]]; strings.ToUpper(option) == "NX" && exists {
+				return "$-1\r\n"
+			}
+		}
+
+		data[args[1]] = args[2]
+		return "+OK\r\n"
+	case "SETEX":
+		data[args[1]] = args[3]
+		return "+OK\r\n"
+	case "DEL":
+		delete(data, args[1])
+		return ":1\r\n"
+	case "INCR", "ZADD", "ZREM", "EXPIRE", "PUBLISH":
+		return ":1\r\n"
+	case "ZINCRBY":
+		return bulk("1")
+	case "MGET":
+		return fmt.Sprintf("*%d\r\n%s", len(args)-1, strings.Repeat("$-1\r\n", len(args)-1))
+	case "ZRANGEBYLEX":
+		return "*1\r\n" + bulk("phone\x00product\x001\x00Phone")
+	case "ZMSCORE":
+		return fmt.Sprintf("*%d\r\n%s", len(args)-2, strings.Repeat(bulk("3"), len(args)-2))
+	default:
+		return "-ERR unknown command '" + args[0] + "'\r\n"
+	}
+}
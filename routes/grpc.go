@@ -0,0 +1,35 @@
+package routes
+
+import (
+	// golang package
+	"productfc/cmd/product/handler"
+	"productfc/middleware"
+	"productfc/proto/productpb"
+
+	// external package
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// SetupGRPCServer setup grpc server by given productServer pointer of handler.ProductGRPCServer.
+//
+// The health service starts NOT_SERVING, set it to SERVING along with health.SetReady.
+//
+// It returns pointer of grpc.Server, and pointer of health.Server.
+func SetupGRPCServer(productServer *handler.ProductGRPCServer) (*grpc.Server, *health.Server) {
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(middleware.GRPCRequestLogger()),
+	)
+
+	productpb.RegisterProductServiceServer(server, productServer)
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	grpc_health_v1.RegisterHealthServer(server, healthServer)
+
+	reflection.Register(server)
+
+	return server, healthServer
+}
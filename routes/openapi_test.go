@@ -0,0 +1,297 @@
+package routes
+
+import (
+	// golang package
+	"encoding/json"
+	"fmt"
+	"math"
+	"productfc/api"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	// external package
+	"github.com/google/uuid"
+)
+
+// openAPISpec is api.Spec decoded, with just enough of OpenAPI 3 and JSON schema to check responses against it.
+type openAPISpec struct {
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas map[string]*schema `json:"schemas"`
+	} `json:"components"`
+
+	responses map[string]json.RawMessage
+}
+
+type operation struct {
+	OperationID string                `json:"operationId"`
+	Responses   map[string]*specReply `json:"responses"`
+}
+
+type specReply struct {
+	Ref     string `json:"$ref"`
+	Content map[string]struct {
+		Schema *schema `json:"schema"`
+	} `json:"content"`
+}
+
+type schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Format               string             `json:"format"`
+	Nullable             bool               `json:"nullable"`
+	Enum                 []interface{}      `json:"enum"`
+	Required             []string           `json:"required"`
+	Properties           map[string]*schema `json:"properties"`
+	AdditionalProperties *bool              `json:"additionalProperties"`
+	Items                *schema            `json:"items"`
+	AllOf                []*schema          `json:"allOf"`
+}
+
+// loadOpenAPISpec load open api spec.
+func loadOpenAPISpec(t *testing.T) *openAPISpec {
+	t.Helper()
+
+	var spec openAPISpec
+	err := json.Unmarshal(api.Spec, &spec)
+	if err != nil {
+		t.Fatalf("api.Spec isn't valid JSON: %v", err)
+	}
+
+	var raw struct {
+		Components struct {
+			Responses map[string]json.RawMessage `json:"responses"`
+		} `json:"components"`
+	}
+	err = json.Unmarshal(api.Spec, &raw)
+	if err != nil {
+		t.Fatalf("api.Spec isn't valid JSON: %v", err)
+	}
+
+	spec.responses = raw.Components.Responses
+
+	return &spec
+}
+
+// operations operations of the spec.
+//
+// It returns map of "METHOD /path/{param}" to operation.
+func (s *openAPISpec) operations(t *testing.T) map[string]operation {
+	t.Helper()
+
+	operations := make(map[string]operation)
+	for path, item := range s.Paths {
+		for method, rawOperation := range item {
+			if method == "parameters" {
+				continue
+			}
+
+			var op operation
+			err := json.Unmarshal(rawOperation, &op)
+			if err != nil {
+				t.Fatalf("%s %s isn't a valid operation: %v", method, path, err)
+			}
+
+			operations[strings.ToUpper(method)+" "+path] = op
+		}
+	}
+
+	return operations
+}
+
+// findOperation find operation by given method, and request path.
+//
+// Literal segments win over parameters, so /v1/product/search isn't taken for /v1/product/{id}.
+//
+// It returns string of "METHOD /path/{param}", and operation, and true when documented.
+func (s *openAPISpec) findOperation(t *testing.T, method string, path string) (string, operation, bool) {
+	t.Helper()
+
+	operations := s.operations(t)
+	segments := strings.Split(path, "/")
+
+	best, bestParams := "", math.MaxInt
+	for key := range operations {
+		keyMethod, template, _ := strings.Cut(key, " ")
+		templateSegments := strings.Split(template, "/")
+		if keyMethod != method || len(templateSegments) != len(segments) {
+			continue
+		}
+
+		params, matched := 0, true
+		for i, segment := range templateSegments {
+			if strings.HasPrefix(segment, "{") {
+				params++
+				continue
+			}
+
+			if segment != segments[i] {
+				matched = false
+				break
+			}
+		}
+
+		if matched && params < bestParams {
+			best, bestParams = key, params
+		}
+	}
+
+	op, ok := operations[best]
+	return best, op, ok
+}
+
+// responseSchema response schema by given op, status, and contentType.
+//
+// It returns pointer of schema, nil when the response has no body, and error when the response isn't documented.
+func (s *openAPISpec) responseSchema(op operation, status int, contentType string) (*schema, error) {
+	reply, ok := op.Responses[fmt.Sprint(status)]
+	if !ok {
+		return nil, fmt.Errorf("status %d isn't documented", status)
+	}
+
+	if reply.Ref != "" {
+		name := strings.TrimPrefix(reply.Ref, "#/components/responses/")
+		reply = &specReply{}
+		err := json.Unmarshal(s.responses[name], reply)
+		if err != nil {
+			return nil, fmt.Errorf("response %s isn't valid: %v", name, err)
+		}
+	}
+
+	if len(reply.Content) == 0 {
+		return nil, nil
+	}
+
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	content, ok := reply.Content[mediaType]
+	if !ok {
+		return nil, fmt.Errorf("content type %q of status %d isn't documented", mediaType, status)
+	}
+
+	return content.Schema, nil
+}
+
+// validate validate by given schema pointer of schema, value decoded from JSON, and path to it.
+//
+// It returns slice of string of every violation, empty when value is valid.
+func (s *openAPISpec) validate(sch *schema, value interface{}, path string) []string {
+	if sch.Ref != "" {
+		name := strings.TrimPrefix(sch.Ref, "#/components/schemas/")
+		resolved, ok := s.Components.Schemas[name]
+		if !ok {
+			return []string{fmt.Sprintf("%s: unknown schema %s", path, sch.Ref)}
+		}
+
+		return s.validate(resolved, value, path)
+	}
+
+	var violations []string
+	for _, part := range sch.AllOf {
+		violations = append(violations, s.validate(part, value, path)...)
+	}
+
+	if value == nil {
+		if sch.Type != "" && !sch.Nullable {
+			violations = append(violations, fmt.Sprintf("%s: is null, expected %s", path, sch.Type))
+		}
+
+		return violations
+	}
+
+	if len(sch.Enum) > 0 && !containsValue(sch.Enum, value) {
+		violations = append(violations, fmt.Sprintf("%s: %v isn't one of %v", path, value, sch.Enum))
+	}
+
+	switch sch.Type {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return append(violations, fmt.Sprintf("%s: is %T, expected object", path, value))
+		}
+
+		for _, name := range sch.Required {
+			if _, ok := object[name]; !ok {
+				violations = append(violations, fmt.Sprintf("%s: missing required property %s", path, name))
+			}
+		}
+
+		names := make([]string, 0, len(object))
+		for name := range object {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+		for _, name := range names {
+			property, ok := sch.Properties[name]
+			if !ok {
+				if sch.AdditionalProperties != nil && !*sch.AdditionalProperties {
+					violations = append(violations, fmt.Sprintf("%s: unexpected property %s", path, name))
+				}
+
+				continue
+			}
+
+			violations = append(violations, s.validate(property, object[name], path+"."+name)...)
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return append(violations, fmt.Sprintf("%s: is %T, expected array", path, value))
+		}
+
+		for i, item := range items {
+			violations = append(violations, s.validate(sch.Items, item, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	case "string":
+		text, ok := value.(string)
+		if !ok {
+			return append(violations, fmt.Sprintf("%s: is %T, expected string", path, value))
+		}
+
+		violations = append(violations, validateFormat(sch.Format, text, path)...)
+	case "integer":
+		number, ok := value.(float64)
+		if !ok || number != math.Trunc(number) {
+			violations = append(violations, fmt.Sprintf("%s: is %v, expected integer", path, value))
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			violations = append(violations, fmt.Sprintf("%s: is %T, expected number", path, value))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			violations = append(violations, fmt.Sprintf("%s: is %T, expected boolean", path, value))
+		}
+	}
+
+	return violations
+}
+
+// validateFormat validate format by given format, text, and path to it.
+func validateFormat(format string, text string, path string) []string {
+	switch format {
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339Nano, text); err != nil {
+			return []string{fmt.Sprintf("%s: %q isn't a date-time", path, text)}
+		}
+	case "uuid":
+		if err := uuid.Validate(text); err != nil {
+			return []string{fmt.Sprintf("%s: %q isn't a uuid", path, text)}
+		}
+	}
+
+	return nil
+}
+
+// containsValue contains value by given values, and value.
+func containsValue(values []interface{}, value interface{}) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+
+	return false
+}
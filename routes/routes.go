@@ -2,21 +2,65 @@ package routes
 
 import (
 	// golang package
+	"productfc/api"
 	"productfc/cmd/product/handler"
+	"productfc/infrastructure/health"
 	"productfc/middleware"
 
 	// external package
 	"github.com/gin-gonic/gin"
 )
 
-// SetupRoutes setup routes by given router pointer of gin.Engine, and ProductHandler.
-func SetupRoutes(router *gin.Engine, orderHandler handler.ProductHandler) {
+// SetupRoutes setup routes by given router pointer of gin.Engine, ProductHandler, graphQLHandler pointer of handler.GraphQLHandler, and idempotency middleware.
+//
+// idempotency guards the create and mutation endpoints, so a retried request isn't applied twice.
+func SetupRoutes(router *gin.Engine, orderHandler handler.ProductHandler, graphQLHandler *handler.GraphQLHandler, idempotency gin.HandlerFunc) {
+	router.GET("/health/live", health.Liveness)
+	router.GET("/health/ready", health.Readiness)
+
+	router.GET("/openapi.json", api.OpenAPI)
+	router.GET("/docs", api.SwaggerUI)
+
+	// streams outlive the request timeout of RequestLogger, so they're routed before it
+	router.GET("/v1/product/stream", middleware.ErrorHandler(), orderHandler.StreamProductStock)
+
 	router.Use(middleware.RequestLogger())
-	router.POST("/v1/product", orderHandler.ProductManagement)
-	router.POST("/v1/product_category", orderHandler.ProductCategoryManagement)
+	router.Use(middleware.ErrorHandler())
+
+	// v1 action endpoints, kept for existing clients, prefer the v2 resources below
+	router.POST("/v1/product", idempotency, orderHandler.ProductManagement)
+	router.POST("/v1/product_category", idempotency, orderHandler.ProductCategoryManagement)
+	router.POST("/v1/product/batch", orderHandler.GetProductBatchInfo)
 
 	router.GET("/v1/product/:id", orderHandler.GetProductInfo)
 	router.GET("/v1/product_category/:id", orderHandler.GetProductCategoryInfo)
 
 	router.GET("/v1/product/search", orderHandler.SearchProduct)
+	router.GET("/v1/product/suggest", orderHandler.AutocompleteProduct)
+
+	router.POST("/v2/products", idempotency, orderHandler.CreateProduct)
+	router.GET("/v2/products/:id", orderHandler.GetProduct)
+	router.PUT("/v2/products/:id", idempotency, orderHandler.ReplaceProduct)
+	router.PATCH("/v2/products/:id", idempotency, orderHandler.UpdateProduct)
+	router.DELETE("/v2/products/:id", idempotency, orderHandler.DeleteProduct)
+
+	router.POST("/v2/categories", idempotency, orderHandler.CreateProductCategory)
+	router.GET("/v2/categories/:id", orderHandler.GetProductCategory)
+	router.PUT("/v2/categories/:id", idempotency, orderHandler.ReplaceProductCategory)
+	router.PATCH("/v2/categories/:id", idempotency, orderHandler.UpdateProductCategory)
+	router.DELETE("/v2/categories/:id", idempotency, orderHandler.DeleteProductCategory)
+
+	router.POST("/v2/webhooks", idempotency, orderHandler.CreateWebhook)
+	router.GET("/v2/webhooks", orderHandler.ListWebhooks)
+	router.GET("/v2/webhooks/:id", orderHandler.GetWebhook)
+	router.PUT("/v2/webhooks/:id", idempotency, orderHandler.ReplaceWebhook)
+	router.DELETE("/v2/webhooks/:id", idempotency, orderHandler.DeleteWebhook)
+	router.GET("/v2/webhooks/:id/deliveries", orderHandler.ListWebhookDeliveries)
+
+	router.POST("/v1/search/click", orderHandler.RecordSearchClick)
+	router.GET("/v1/search/analytics/top-queries", orderHandler.GetTopSearchQueries)
+	router.GET("/v1/search/analytics/zero-results", orderHandler.GetZeroResultSearchQueries)
+	router.GET("/v1/search/analytics/ctr", orderHandler.GetSearchClickThroughRates)
+
+	router.POST("/graphql", graphQLHandler.ServeGraphQL)
 }